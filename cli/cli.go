@@ -18,20 +18,30 @@ const usage = `Usage:
 	The myLG tool, developed to troubleshoot networking situations.
 	The vi/emacs mode, almost all basic features are supported. Press tab to see which options are available.
 
-	connect <provider name>     connects to external looking glass, press tab to see the menu
-	node <city/country name>    connects to specific node at current looking glass, press tab to see the available nodes
+	connect <provider name>     connects to external looking glass, press tab to see the menu (connect auto <host> selects the provider of the host origin AS)
+	node <city/country name>    connects to specific node at current looking glass, press tab or run node w/o name to see the available nodes (-json w/ the codes and capabilities)
 	local                       back to local
 	lg                          change mode to external looking glass
 	ns                          change mode to name server looking up
 	ping                        ping ip address or domain name
 	trace                       trace ip address or domain name (real-time w/ -r option)
+	pmtu                        discover the path MTU to ip address or domain name
+	check                       quick connectivity report: ping, trace, reverse DNS and origin AS (-json)
+	batch                       run ping/trace/bgp through the looking glass against the targets of a file (-file)
+	preset                      save the current looking glass query (preset save name ping|trace|bgp [host]), list and run them (preset run name [host])
 	dig                         nameserver look up
 	nms                         quick NMS - monitor device/server ports real-time
 	whois                       resolve AS number/IP/CIDR to holder (provided by ripe ncc)
+	asn                         resolve AS number to name, country and announced prefixes
 	hping                       ping through HTTP/HTTPS w/ GET/POST/HEAD methods
 	scan                        scan tcp ports (you can provide range >scan host minport maxport)
+	banner                      grab the tcp service banner w/ the TLS certificate details (-hex for binary)
+	cert                        inspect the TLS certificate chain, flags the expired certificates (-json)
 	dump                        prints out a description of the contents of packets on a network interface
+	sniff                       prints out a summary per packet or the conversations (-conv) on a network interface
 	disc                        discover all the devices on a LAN
+	dhcp                        discover the DHCP servers on a LAN
+	ifaces                      local interfaces, addresses and default gateways (-json)
 	peering                     peering information (provided by peeringdb.com)
 	web                         web dashboard - opens dashboard at your default browser
 
@@ -50,6 +60,10 @@ var (
 	cmds = []string{
 		"ping",
 		"trace",
+		"pmtu",
+		"check",
+		"batch",
+		"preset",
 		"bgp",
 		"hping",
 		"connect",
@@ -60,9 +74,15 @@ var (
 		"dig",
 		"nms",
 		"whois",
+		"asn",
 		"scan",
+		"banner",
+		"cert",
 		"dump",
+		"sniff",
 		"disc",
+		"dhcp",
+		"ifaces",
 		"peering",
 		"speedtest",
 		"help",
@@ -147,6 +167,46 @@ func (r *Readline) UpdateCompleter(pcItem string, pcSubItems []string) {
 	r.completer.Children = child
 }
 
+// UpdateDynamicCompleter replaces subitem(s) from a specific main item with
+// the candidates which are returned by f for the typed argument on demand
+func (r *Readline) UpdateDynamicCompleter(pcItem string, f func(string) []string) {
+	callback := func(line string) []string {
+		arg := strings.TrimLeft(line, " ")
+		arg = strings.TrimLeft(strings.TrimPrefix(arg, pcItem), " ")
+		return completeSuffix(arg, f(arg))
+	}
+	for _, p := range r.completer.Children {
+		if pc, ok := p.(*readline.PrefixCompleter); ok && strings.TrimSpace(string(pc.Name)) == pcItem {
+			pc.Children = []readline.PrefixCompleterInterface{readline.PcItemDynamic(callback)}
+		}
+	}
+}
+
+// completeSuffix rewrites the candidates as the typed argument plus
+// the rest of the candidate after the (case-insensitive) matched word
+// since readline only appends the completion to the typed argument
+func completeSuffix(arg string, candidates []string) []string {
+	var r []string
+	a := strings.ToLower(arg)
+	for _, c := range candidates {
+		lc := strings.ToLower(c)
+		if len(lc) != len(c) {
+			continue
+		}
+		for i := 0; i <= len(lc)-len(a); i++ {
+			if (i == 0 || !isAlnum(lc[i-1])) && strings.HasPrefix(lc[i:], a) {
+				r = append(r, arg+c[i+len(a):])
+				break
+			}
+		}
+	}
+	return r
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
+
 // SetPrompt set readline prompt and store it
 func (r *Readline) SetPrompt(p string) {
 	p = strings.ToLower(p)
@@ -0,0 +1,304 @@
+// Package dhcp discovers the DHCP servers on the local network
+package dhcp
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/mehrdadrad/mylg/cli"
+)
+
+// Offer represents a DHCPOFFER
+type Offer struct {
+	Server  net.IP
+	Address net.IP
+	Mask    net.IP
+	Lease   time.Duration
+	Router  []net.IP
+	DNS     []net.IP
+	Domain  string
+}
+
+// Discovery represents a DHCP servers discovery
+type Discovery struct {
+	// Interface is the broadcast interface, the first up
+	// and non-loopback interface is used if it's empty
+	Interface string
+	// Timeout is the offers wait time of each round
+	Timeout time.Duration
+	// Rounds is the repeated discoveries, the
+	// rogue servers may not reply every time
+	Rounds int
+	// Offers holds the unique offers of all rounds
+	Offers []Offer
+}
+
+const (
+	serverPort = 67
+	clientPort = 68
+
+	// minMessageSize is the bootp minimum message size
+	minMessageSize = 300
+)
+
+// ErrPermission is returned once the dhcp client port can't be opened
+var ErrPermission = errors.New("dhcp discovery requires root privileges (the client port 68 is privileged)")
+
+// New parses the dhcp arguments, nil is returned if the help is requested
+func New(args string) (*Discovery, error) {
+	_, flag := cli.Flag(args)
+	if _, ok := flag["help"]; ok {
+		help()
+		return nil, nil
+	}
+	timeout := cli.SetFlag(flag, "t", 5).(int)
+	rounds := cli.SetFlag(flag, "r", 1).(int)
+	if timeout < 1 || rounds < 1 {
+		return nil, errors.New("the timeout and the rounds should be positive")
+	}
+	return &Discovery{
+		Interface: cli.SetFlag(flag, "i", "").(string),
+		Timeout:   time.Duration(timeout) * time.Second,
+		Rounds:    rounds,
+	}, nil
+}
+
+// Run broadcasts a DHCPDISCOVER per round and collects the offers
+func (d *Discovery) Run() error {
+	iface, err := d.iface()
+	if err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for i := 0; i < d.Rounds; i++ {
+		offers, err := discover(iface, d.Timeout)
+		if err != nil {
+			return err
+		}
+		for _, o := range offers {
+			key := o.Server.String() + "/" + o.Address.String()
+			if !seen[key] {
+				seen[key] = true
+				d.Offers = append(d.Offers, o)
+			}
+		}
+	}
+	return nil
+}
+
+// Servers returns the distinct servers which have replied
+func (d *Discovery) Servers() []string {
+	var (
+		servers []string
+		seen    = map[string]bool{}
+	)
+	for _, o := range d.Offers {
+		if s := o.Server.String(); !seen[s] {
+			seen[s] = true
+			servers = append(servers, s)
+		}
+	}
+	return servers
+}
+
+// PrintPretty prints the offers
+func (d *Discovery) PrintPretty() {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Server", "Offered", "Mask", "Lease", "Router", "DNS", "Domain"})
+	for _, o := range d.Offers {
+		table.Append([]string{o.Server.String(), o.Address.String(), o.Mask.String(), o.Lease.String(),
+			joinIPs(o.Router), joinIPs(o.DNS), o.Domain})
+	}
+	table.Render()
+	n := len(d.Servers())
+	println(n, "DHCP server(s) has been found")
+	if n > 1 {
+		println("warning: multiple DHCP servers have replied, one of them could be a rogue server")
+	}
+}
+
+// iface returns the requested or the first up and non-loopback interface
+func (d *Discovery) iface() (*net.Interface, error) {
+	if d.Interface != "" {
+		return net.InterfaceByName(d.Interface)
+	}
+	ifs, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range ifs {
+		if i.Flags&net.FlagUp != 0 && i.Flags&net.FlagLoopback == 0 && len(i.HardwareAddr) > 0 {
+			return &i, nil
+		}
+	}
+	return nil, errors.New("there isn't any up interface, please specify one w/ -i")
+}
+
+// discover broadcasts a DHCPDISCOVER through the interface
+// and returns the offers which are received until the timeout
+func discover(iface *net.Interface, timeout time.Duration) ([]Offer, error) {
+	conn, err := listen(iface.Name, clientPort)
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return nil, ErrPermission
+		}
+		return nil, err
+	}
+	defer conn.Close()
+
+	xid := rand.Uint32()
+	b, err := DiscoverPacket(iface.HardwareAddr, xid)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteTo(b, &net.UDPAddr{IP: net.IPv4bcast, Port: serverPort}); err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return nil, ErrPermission
+		}
+		return nil, err
+	}
+
+	return ReadOffers(conn, xid, timeout)
+}
+
+// ReadOffers returns the offers of the transaction which are received
+// until the timeout
+func ReadOffers(conn net.PacketConn, xid uint32, timeout time.Duration) ([]Offer, error) {
+	var (
+		offers []Offer
+		buf    = make([]byte, 1500)
+	)
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if e, ok := err.(net.Error); ok && e.Timeout() {
+				return offers, nil
+			}
+			return offers, err
+		}
+		// the offer ips and options are the slices of the packet,
+		// so the buffer which is reused by the next read is copied
+		pkt := append([]byte(nil), buf[:n]...)
+		if o, ok := ParseOffer(pkt, xid); ok {
+			offers = append(offers, o)
+		}
+	}
+}
+
+// DiscoverPacket returns a broadcast DHCPDISCOVER of the transaction
+func DiscoverPacket(mac net.HardwareAddr, xid uint32) ([]byte, error) {
+	req := &layers.DHCPv4{
+		Operation:    layers.DHCPOpRequest,
+		HardwareType: layers.LinkTypeEthernet,
+		HardwareLen:  uint8(len(mac)),
+		Xid:          xid,
+		Flags:        0x8000, // broadcast reply
+		ClientHWAddr: mac,
+		Options: layers.DHCPOptions{
+			layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(layers.DHCPMsgTypeDiscover)}),
+			layers.NewDHCPOption(layers.DHCPOptParamsRequest, []byte{
+				byte(layers.DHCPOptSubnetMask),
+				byte(layers.DHCPOptRouter),
+				byte(layers.DHCPOptDNS),
+				byte(layers.DHCPOptDomainName),
+				byte(layers.DHCPOptLeaseTime),
+			}),
+		},
+	}
+	buf := gopacket.NewSerializeBuffer()
+	if err := req.SerializeTo(buf, gopacket.SerializeOptions{FixLengths: true}); err != nil {
+		return nil, err
+	}
+	b := buf.Bytes()
+	// some servers ignore the messages shorter than the bootp minimum
+	if len(b) < minMessageSize {
+		b = append(b, make([]byte, minMessageSize-len(b))...)
+	}
+	return b, nil
+}
+
+// ParseOffer decodes the DHCPOFFER of the transaction, false is
+// returned if it's not an offer or it belongs to another transaction
+func ParseOffer(b []byte, xid uint32) (Offer, bool) {
+	var (
+		o     Offer
+		reply layers.DHCPv4
+	)
+	if err := reply.DecodeFromBytes(b, gopacket.NilDecodeFeedback); err != nil {
+		return o, false
+	}
+	if reply.Operation != layers.DHCPOpReply || reply.Xid != xid {
+		return o, false
+	}
+	isOffer := false
+	o.Address = reply.YourClientIP
+	o.Server = reply.NextServerIP
+	for _, opt := range reply.Options {
+		switch opt.Type {
+		case layers.DHCPOptMessageType:
+			isOffer = len(opt.Data) == 1 && layers.DHCPMsgType(opt.Data[0]) == layers.DHCPMsgTypeOffer
+		case layers.DHCPOptServerID:
+			if len(opt.Data) == 4 {
+				o.Server = net.IP(opt.Data)
+			}
+		case layers.DHCPOptSubnetMask:
+			if len(opt.Data) == 4 {
+				o.Mask = net.IP(opt.Data)
+			}
+		case layers.DHCPOptLeaseTime:
+			if len(opt.Data) == 4 {
+				secs := uint32(opt.Data[0])<<24 | uint32(opt.Data[1])<<16 | uint32(opt.Data[2])<<8 | uint32(opt.Data[3])
+				o.Lease = time.Duration(secs) * time.Second
+			}
+		case layers.DHCPOptRouter:
+			o.Router = ips(opt.Data)
+		case layers.DHCPOptDNS:
+			o.DNS = ips(opt.Data)
+		case layers.DHCPOptDomainName:
+			o.Domain = strings.TrimRight(string(opt.Data), "\x00")
+		}
+	}
+	return o, isOffer
+}
+
+func ips(b []byte) []net.IP {
+	var r []net.IP
+	for i := 0; i+4 <= len(b); i += 4 {
+		r = append(r, net.IP(b[i:i+4]))
+	}
+	return r
+}
+
+func joinIPs(ips []net.IP) string {
+	var s []string
+	for _, ip := range ips {
+		s = append(s, ip.String())
+	}
+	return strings.Join(s, ", ")
+}
+
+// help shows dhcp help
+func help() {
+	fmt.Println(`
+    DHCP servers discovery
+    usage:
+          dhcp [options]
+    options:
+          -i interface   Broadcast on specified interface (default: first non-loopback)
+          -t timeout     Wait for the offers in seconds (default: 5)
+          -r rounds      Repeat the discovery to catch the rogue servers (default: 1)
+    Example:
+          dhcp
+          dhcp -i eth0 -t 10 -r 3
+	`)
+}
@@ -0,0 +1,120 @@
+package dhcp_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/mehrdadrad/mylg/dhcp"
+)
+
+func TestParseOffer(t *testing.T) {
+	mac := net.HardwareAddr{0, 1, 2, 3, 4, 5}
+	b, err := dhcp.DiscoverPacket(mac, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) < 300 {
+		t.Error("expected the bootp minimum size but got", len(b))
+	}
+	if _, ok := dhcp.ParseOffer(b, 42); ok {
+		t.Error("expected the discover isn't an offer")
+	}
+
+	offer := &layers.DHCPv4{
+		Operation:    layers.DHCPOpReply,
+		HardwareType: layers.LinkTypeEthernet,
+		Xid:          42,
+		YourClientIP: net.IPv4(192, 168, 1, 100),
+		ClientHWAddr: mac,
+		Options: layers.DHCPOptions{
+			layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(layers.DHCPMsgTypeOffer)}),
+			layers.NewDHCPOption(layers.DHCPOptServerID, []byte{192, 168, 1, 1}),
+			layers.NewDHCPOption(layers.DHCPOptSubnetMask, []byte{255, 255, 255, 0}),
+			layers.NewDHCPOption(layers.DHCPOptLeaseTime, []byte{0, 0, 0x0e, 0x10}),
+			layers.NewDHCPOption(layers.DHCPOptRouter, []byte{192, 168, 1, 1}),
+			layers.NewDHCPOption(layers.DHCPOptDNS, []byte{8, 8, 8, 8, 1, 1, 1, 1}),
+			layers.NewDHCPOption(layers.DHCPOptDomainName, []byte("lan")),
+		},
+	}
+	buf := gopacket.NewSerializeBuffer()
+	if err := offer.SerializeTo(buf, gopacket.SerializeOptions{FixLengths: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := dhcp.ParseOffer(buf.Bytes(), 43); ok {
+		t.Error("expected the other transaction is ignored")
+	}
+	o, ok := dhcp.ParseOffer(buf.Bytes(), 42)
+	if !ok {
+		t.Fatal("expected an offer")
+	}
+	if o.Server.String() != "192.168.1.1" || o.Address.String() != "192.168.1.100" ||
+		o.Mask.String() != "255.255.255.0" || o.Lease != time.Hour || o.Domain != "lan" {
+		t.Errorf("unexpected offer %+v", o)
+	}
+	if len(o.Router) != 1 || len(o.DNS) != 2 || o.DNS[1].String() != "1.1.1.1" {
+		t.Errorf("unexpected router/dns options %v %v", o.Router, o.DNS)
+	}
+}
+
+// offerPacket returns a DHCPOFFER of the address from the server
+func offerPacket(t *testing.T, xid uint32, addr, server net.IP, domain string) []byte {
+	offer := &layers.DHCPv4{
+		Operation:    layers.DHCPOpReply,
+		HardwareType: layers.LinkTypeEthernet,
+		Xid:          xid,
+		YourClientIP: addr,
+		ClientHWAddr: net.HardwareAddr{0, 1, 2, 3, 4, 5},
+		Options: layers.DHCPOptions{
+			layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(layers.DHCPMsgTypeOffer)}),
+			layers.NewDHCPOption(layers.DHCPOptServerID, server.To4()),
+			layers.NewDHCPOption(layers.DHCPOptRouter, server.To4()),
+			layers.NewDHCPOption(layers.DHCPOptDomainName, []byte(domain)),
+		},
+	}
+	buf := gopacket.NewSerializeBuffer()
+	if err := offer.SerializeTo(buf, gopacket.SerializeOptions{FixLengths: true}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadOffers(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	server, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	for _, b := range [][]byte{
+		offerPacket(t, 42, net.IPv4(192, 168, 1, 100), net.IPv4(192, 168, 1, 1), "lan"),
+		offerPacket(t, 43, net.IPv4(172, 16, 0, 5), net.IPv4(172, 16, 0, 1), "other"),
+		offerPacket(t, 42, net.IPv4(10, 0, 0, 100), net.IPv4(10, 0, 0, 1), "rogue"),
+	} {
+		if _, err := server.WriteTo(b, conn.LocalAddr()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	offers, err := dhcp.ReadOffers(conn, 42, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offers) != 2 {
+		t.Fatal("expected 2 offers but got", offers)
+	}
+	if o := offers[0]; o.Address.String() != "192.168.1.100" || o.Server.String() != "192.168.1.1" ||
+		o.Router[0].String() != "192.168.1.1" || o.Domain != "lan" {
+		t.Errorf("expected the first offer isn't overwritten but got %+v", o)
+	}
+	if o := offers[1]; o.Address.String() != "10.0.0.100" || o.Server.String() != "10.0.0.1" || o.Domain != "rogue" {
+		t.Errorf("unexpected second offer %+v", o)
+	}
+}
@@ -0,0 +1,32 @@
+//go:build darwin || freebsd || netbsd || openbsd
+// +build darwin freebsd netbsd openbsd
+
+package dhcp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// listen opens the udp port on all addresses w/ broadcast enabled,
+// the broadcast goes through the default route interface
+func listen(iface string, port int) (net.PacketConn, error) {
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			var err error
+			cErr := c.Control(func(fd uintptr) {
+				if err = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1); err != nil {
+					return
+				}
+				err = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+			})
+			if cErr != nil {
+				return cErr
+			}
+			return err
+		},
+	}
+	return lc.ListenPacket(context.Background(), "udp4", fmt.Sprintf(":%d", port))
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package dhcp
+
+import (
+	"errors"
+	"net"
+)
+
+// listen isn't supported on this platform
+func listen(iface string, port int) (net.PacketConn, error) {
+	return nil, errors.New("dhcp discovery isn't supported on this platform")
+}
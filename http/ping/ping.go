@@ -42,6 +42,7 @@ type Ping struct {
 	kAlive        bool
 	TLSSkipVerify bool
 	tracerEnabled bool
+	follow        bool
 	fmtJSON       bool
 	ipv4          bool
 	ipv6          bool
@@ -56,11 +57,15 @@ type Result struct {
 	Server     string
 	Status     string
 	Trace      Trace
+	Seq        int
+	Err        error
 }
 
-// Trace holds trace results
+// Trace holds trace results (ms)
 type Trace struct {
+	DNSLookup       float64
 	ConnectionTime  float64
+	TLSHandshake    float64
 	TimeToFirstByte float64
 }
 
@@ -80,7 +85,7 @@ func (r Result) PrintPingResult(p *Ping, seq int, err error) {
 	pStrPrefix := "HTTP Response seq=%d, "
 	pStrSuffix := "proto=%s, status=%d, size=%d Bytes, time=%.3f ms"
 	pStrSuffixHead := "proto=%s, status=%d, time=%.3f ms"
-	pStrTrace := ", connection=%.3f ms, first byte read=%.3f ms\n"
+	pStrTrace := ", dns=%.3f ms, connection=%.3f ms, tls=%.3f ms, first byte read=%.3f ms\n"
 
 	if p.quiet {
 		if err != nil {
@@ -99,14 +104,16 @@ func (r Result) PrintPingResult(p *Ping, seq int, err error) {
 
 	if p.method == "HEAD" {
 		if p.tracerEnabled {
-			fmt.Printf(pStrPrefix+pStrSuffixHead+pStrTrace, seq, r.Proto, r.StatusCode, r.TotalTime*1e3, r.Trace.ConnectionTime, r.Trace.TimeToFirstByte)
+			fmt.Printf(pStrPrefix+pStrSuffixHead+pStrTrace, seq, r.Proto, r.StatusCode, r.TotalTime*1e3,
+				r.Trace.DNSLookup, r.Trace.ConnectionTime, r.Trace.TLSHandshake, r.Trace.TimeToFirstByte)
 			return
 		}
 		fmt.Printf(pStrPrefix+pStrSuffixHead+"\n", seq, r.Proto, r.StatusCode, r.TotalTime*1e3)
 		return
 	}
 	if p.tracerEnabled {
-		fmt.Printf(pStrPrefix+pStrSuffix+pStrTrace, seq, r.Proto, r.StatusCode, r.Size, r.TotalTime*1e3, r.Trace.ConnectionTime, r.Trace.TimeToFirstByte)
+		fmt.Printf(pStrPrefix+pStrSuffix+pStrTrace, seq, r.Proto, r.StatusCode, r.Size, r.TotalTime*1e3,
+			r.Trace.DNSLookup, r.Trace.ConnectionTime, r.Trace.TLSHandshake, r.Trace.TimeToFirstByte)
 		return
 	}
 	fmt.Printf(pStrPrefix+pStrSuffix+"\n", seq, r.Proto, r.StatusCode, r.Size, r.TotalTime*1e3)
@@ -140,6 +147,7 @@ func NewPing(args string, cfg cli.Config) (*Ping, error) {
 		buf:           cli.SetFlag(flag, "d", "mylg").(string),
 		count:         cli.SetFlag(flag, "c", cfg.Hping.Count).(int),
 		tracerEnabled: cli.SetFlag(flag, "trace", false).(bool),
+		follow:        cli.SetFlag(flag, "f", false).(bool),
 		fmtJSON:       cli.SetFlag(flag, "json", false).(bool),
 		uAgent:        cli.SetFlag(flag, "u", "myLG (http://mylg.io)").(string),
 		dCompress:     cli.SetFlag(flag, "dc", false).(bool),
@@ -199,32 +207,35 @@ func (p *Ping) Run() {
 		return
 	}
 	var (
-		sigCh = make(chan os.Signal, 1)
-		c     = make(map[int]float64, 10)
-		s     []float64
+		sigCh       = make(chan os.Signal, 1)
+		c           = make(map[int]float64, 10)
+		s           []float64
+		traces      []Trace
+		ctx, cancel = context.WithCancel(context.Background())
 	)
+	defer cancel()
 	// capture interrupt w/ s channel
 	signal.Notify(sigCh, os.Interrupt)
 	defer signal.Stop(sigCh)
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
 
 	fmt.Printf("HPING %s (%s), Method: %s, DNSLookup: %.4f ms\n", p.host, p.rAddr, p.method, p.nsTime.Seconds()*1e3)
 
-LOOP:
-	for i := 0; i < p.count; i++ {
-		if r, err := p.Ping(); err == nil {
-			r.PrintPingResult(p, i, err)
-			c[r.StatusCode]++
-			s = append(s, r.TotalTime*1e3)
-		} else {
+	for r := range p.Stream(ctx) {
+		r.PrintPingResult(p, r.Seq, r.Err)
+		if r.Err != nil {
 			c[-1]++
-			r.PrintPingResult(p, i, err)
-		}
-		select {
-		case <-sigCh:
-			break LOOP
-		default:
+			continue
 		}
-		time.Sleep(p.interval)
+		c[r.StatusCode]++
+		s = append(s, r.TotalTime*1e3)
+		traces = append(traces, r.Trace)
 	}
 
 	// print statistics
@@ -233,9 +244,56 @@ LOOP:
 		p.printStatsJSON(c, s)
 	} else {
 		p.printStats(c, s)
+		if p.tracerEnabled && !p.quiet {
+			printTraceStats(traces)
+		}
 	}
 }
 
+// Stream pings count times and streams each result, the
+// channel is closed once it's done or ctx is canceled
+func (p *Ping) Stream(ctx context.Context) chan Result {
+	var c = make(chan Result, 1)
+	go func() {
+		defer close(c)
+		for i := 0; i < p.count; i++ {
+			r, err := p.Ping()
+			r.Seq, r.Err = i, err
+			select {
+			case c <- r:
+			case <-ctx.Done():
+				return
+			}
+			if i == p.count-1 {
+				break
+			}
+			select {
+			case <-time.After(p.interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// printTraceStats prints out the average of the request phases
+func printTraceStats(traces []Trace) {
+	var avg Trace
+	if len(traces) == 0 {
+		return
+	}
+	for _, t := range traces {
+		avg.DNSLookup += t.DNSLookup
+		avg.ConnectionTime += t.ConnectionTime
+		avg.TLSHandshake += t.TLSHandshake
+		avg.TimeToFirstByte += t.TimeToFirstByte
+	}
+	n := float64(len(traces))
+	fmt.Printf("HTTP phases avg dns/connection/tls/first byte = %.2f/%.2f/%.2f/%.2f ms\n",
+		avg.DNSLookup/n, avg.ConnectionTime/n, avg.TLSHandshake/n, avg.TimeToFirstByte/n)
+}
+
 // printStats prints out the footer
 func (p *Ping) printStats(c map[int]float64, s []float64) {
 
@@ -314,7 +372,8 @@ func (p *Ping) setTransport() {
 		},
 		Proxy: http.ProxyFromEnvironment,
 		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			return net.Dial(p.IPVersion("tcp"), addr)
+			// the context passes the dns and connect events to the tracer
+			return (&net.Dialer{}).DialContext(ctx, p.IPVersion("tcp"), addr)
 		},
 	}
 }
@@ -337,6 +396,9 @@ func (p *Ping) Ping() (Result, error) {
 		Timeout:   p.timeout,
 		Transport: p.transport,
 	}
+	if p.follow {
+		client.CheckRedirect = nil
+	}
 
 	sTime = time.Now()
 
@@ -384,20 +446,32 @@ func (p *Ping) Ping() (Result, error) {
 
 func tracer(r *Result) *httptrace.ClientTrace {
 	var (
-		begin   = time.Now()
-		elapsed time.Duration
+		begin              = time.Now()
+		dnsStart, tcpStart time.Time
+		tlsStart           time.Time
 	)
 
 	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			r.Trace.DNSLookup = time.Since(dnsStart).Seconds() * 1e3
+		},
+		ConnectStart: func(network, addr string) {
+			tcpStart = time.Now()
+		},
 		ConnectDone: func(network, addr string, err error) {
-			elapsed = time.Since(begin)
-			begin = time.Now()
-			r.Trace.ConnectionTime = elapsed.Seconds() * 1e3
+			r.Trace.ConnectionTime = time.Since(tcpStart).Seconds() * 1e3
+		},
+		TLSHandshakeStart: func() {
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			r.Trace.TLSHandshake = time.Since(tlsStart).Seconds() * 1e3
 		},
 		GotFirstResponseByte: func() {
-			elapsed = time.Since(begin)
-			begin = time.Now()
-			r.Trace.TimeToFirstByte = elapsed.Seconds() * 1e3
+			r.Trace.TimeToFirstByte = time.Since(begin).Seconds() * 1e3
 		},
 	}
 }
@@ -461,7 +535,8 @@ func help(cfg cli.Config) {
           -k                Enable keep alive
           -dc               Disable compression
           -nc               Don’t check the server certificate
-          -trace            Provides the events within client requests
+          -f                Follow redirects
+          -trace            Provides the dns, connection, tls and first byte timings
           -json             Export statistics as json format
 
     Proxy:
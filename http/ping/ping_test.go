@@ -1,6 +1,7 @@
 package ping_test
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -58,3 +59,53 @@ func TestNormalize(t *testing.T) {
 		t.Error("Normalize retured unexpected value")
 	}
 }
+
+func TestPingStream(t *testing.T) {
+	var hits int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/ok", http.StatusFound)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	}))
+	defer ts.Close()
+
+	cfg, _ := cli.ReadDefaultConfig()
+	tests := []struct {
+		args string
+		want int
+		hits int
+	}{
+		{ts.URL + " -c 2 -i 1ms -trace", http.StatusFound, 2},
+		{ts.URL + " -c 2 -i 1ms -trace -f", http.StatusOK, 4},
+	}
+	for _, tt := range tests {
+		hits = 0
+		p, err := ping.NewPing(tt.args, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var seq []int
+		for r := range p.Stream(context.Background()) {
+			if r.Err != nil {
+				t.Fatal(r.Err)
+			}
+			if r.StatusCode != tt.want {
+				t.Errorf("%s: expected %d but got %d", tt.args, tt.want, r.StatusCode)
+			}
+			if r.Trace.ConnectionTime == 0 || r.Trace.TimeToFirstByte == 0 {
+				t.Errorf("%s: expected the connection and the first byte timings %+v", tt.args, r.Trace)
+			}
+			seq = append(seq, r.Seq)
+		}
+		if len(seq) != 2 || seq[1] != 1 {
+			t.Error("unexpected sequences", seq)
+		}
+		// the redirects are followed w/ -f
+		if hits != tt.hits {
+			t.Errorf("%s: expected %d requests but got %d", tt.args, tt.hits, hits)
+		}
+	}
+}
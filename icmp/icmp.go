@@ -88,6 +88,9 @@ type Ping struct {
 	timeout   time.Duration
 	interval  time.Duration
 	MaxRTT    time.Duration
+	// unprivileged is true once the raw socket isn't
+	// permitted and ping falls back to the udp socket
+	unprivileged bool
 }
 
 // HopResp represents hop's response
@@ -0,0 +1,160 @@
+package icmp
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+)
+
+// the live ping window sizes, the window is bounded
+// so the long running pings don't grow the memory
+const (
+	DefaultLiveWindow = 60
+	MaxLiveWindow     = 1024
+)
+
+// sparkBlocks are the sparkline levels from the lowest rtt
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sample represents a ping of the live window, RTT is zero if it's lost
+type Sample struct {
+	RTT  float64
+	Lost bool
+}
+
+// RTTWindow keeps the last samples in a ring buffer and the running jitter
+// in ms, it's the mean deviation of the consecutive replies like Jitter
+// but over all of the replies not only the window
+type RTTWindow struct {
+	samples  []Sample
+	next     int
+	full     bool
+	Sent     int
+	Received int
+	Jitter   float64
+
+	last      float64
+	hasLast   bool
+	deviation float64
+}
+
+// LiveUpdate represents a live ping result, Samples are the
+// last samples of the window (oldest first) w/ the latest one
+type LiveUpdate struct {
+	Latest   Response
+	Samples  []Sample
+	Jitter   float64
+	Sent     int
+	Received int
+}
+
+// NewRTTWindow returns a window of the size, the default size is used
+// if it's not positive and it's capped by MaxLiveWindow
+func NewRTTWindow(size int) *RTTWindow {
+	if size < 1 {
+		size = DefaultLiveWindow
+	}
+	if size > MaxLiveWindow {
+		size = MaxLiveWindow
+	}
+	return &RTTWindow{samples: make([]Sample, size)}
+}
+
+// Add adds the response to the window, the oldest sample is dropped once it's full
+func (w *RTTWindow) Add(resp Response) {
+	s := Sample{RTT: resp.RTT}
+	if resp.Error != nil || resp.Timeout {
+		s = Sample{Lost: true}
+	}
+	w.Sent++
+	if !s.Lost {
+		w.Received++
+		if w.hasLast {
+			w.deviation += math.Abs(s.RTT - w.last)
+			w.Jitter = w.deviation / float64(w.Received-1)
+		}
+		w.last, w.hasLast = s.RTT, true
+	}
+	w.samples[w.next] = s
+	w.next = (w.next + 1) % len(w.samples)
+	if w.next == 0 {
+		w.full = true
+	}
+}
+
+// Samples returns a copy of the samples, oldest first
+func (w *RTTWindow) Samples() []Sample {
+	if !w.full {
+		return append([]Sample(nil), w.samples[:w.next]...)
+	}
+	s := make([]Sample, 0, len(w.samples))
+	s = append(s, w.samples[w.next:]...)
+	return append(s, w.samples[:w.next]...)
+}
+
+// Update returns the live update of the latest response
+func (w *RTTWindow) Update(latest Response) LiveUpdate {
+	return LiveUpdate{
+		Latest:   latest,
+		Samples:  w.Samples(),
+		Jitter:   w.Jitter,
+		Sent:     w.Sent,
+		Received: w.Received,
+	}
+}
+
+// Live pings continuously at the interval until ctx is canceled, each
+// response is sent w/ the window of the last size samples
+func (p *Ping) Live(ctx context.Context, size int) chan LiveUpdate {
+	var (
+		c    = make(chan LiveUpdate, 1)
+		w    = NewRTTWindow(size)
+		resp = make(chan Response, 1)
+	)
+	go func() {
+		defer close(c)
+		for {
+			p.Ping(resp)
+			r := <-resp
+			w.Add(r)
+			select {
+			case c <- w.Update(r):
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case <-time.After(p.interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// Sparkline returns the samples as a line of the block characters which
+// are scaled between the min and max rtts, the lost samples are spaces
+func (u LiveUpdate) Sparkline() string {
+	var (
+		min, max = math.MaxFloat64, 0.0
+		b        strings.Builder
+	)
+	for _, s := range u.Samples {
+		if !s.Lost {
+			min, max = math.Min(min, s.RTT), math.Max(max, s.RTT)
+		}
+	}
+	for _, s := range u.Samples {
+		switch {
+		case s.Lost:
+			b.WriteRune(' ')
+		case max == min:
+			b.WriteRune(sparkBlocks[0])
+		default:
+			i := int(math.Round((s.RTT - min) / (max - min) * float64(len(sparkBlocks)-1)))
+			b.WriteRune(sparkBlocks[i])
+		}
+	}
+	return b.String()
+}
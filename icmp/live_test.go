@@ -0,0 +1,78 @@
+package icmp_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/icmp"
+)
+
+func TestRTTWindow(t *testing.T) {
+	w := icmp.NewRTTWindow(3)
+	for _, r := range []icmp.Response{
+		{RTT: 10}, {RTT: 26}, {Error: errors.New("Request timeout")}, {RTT: 10}, {RTT: 17},
+	} {
+		w.Add(r)
+	}
+	want := []icmp.Sample{{Lost: true}, {RTT: 10}, {RTT: 17}}
+	if got := w.Samples(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v but got %+v", want, got)
+	}
+	if w.Sent != 5 || w.Received != 4 {
+		t.Error("unexpected counters", w.Sent, w.Received)
+	}
+	// the lost samples are skipped
+	if j := icmp.Jitter([]float64{10, 26, 10, 17}); math.Abs(w.Jitter-j) > 1e-9 {
+		t.Error("expected jitter", j, "but got", w.Jitter)
+	}
+
+	u := w.Update(icmp.Response{RTT: 17})
+	if s := u.Sparkline(); s != " ▁█" {
+		t.Errorf("unexpected sparkline %q", s)
+	}
+	if n := len(icmp.NewRTTWindow(0).Update(icmp.Response{}).Samples); n != 0 {
+		t.Error("expected no samples but got", n)
+	}
+}
+
+func TestRTTWindowBounded(t *testing.T) {
+	w := icmp.NewRTTWindow(icmp.MaxLiveWindow * 2)
+	for i := 0; i < icmp.MaxLiveWindow*3; i++ {
+		w.Add(icmp.Response{RTT: float64(i)})
+	}
+	s := w.Samples()
+	if len(s) != icmp.MaxLiveWindow || s[0].RTT != float64(icmp.MaxLiveWindow*2) {
+		t.Error("expected the window to be capped but got", len(s), s[0])
+	}
+}
+
+func TestLive(t *testing.T) {
+	cfg, _ := cli.ReadDefaultConfig()
+	p, err := icmp.NewPing("127.0.0.1 -i 10ms -t 200ms", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := p.Live(ctx, 5)
+	u := <-c
+	if u.Sent != 1 || len(u.Samples) != 1 {
+		t.Errorf("unexpected first update %+v", u)
+	}
+	cancel()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-c:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("expected the live ping to stop once it's canceled")
+		}
+	}
+}
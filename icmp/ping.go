@@ -7,6 +7,7 @@ import (
 	"golang.org/x/net/icmp"
 	"golang.org/x/net/ipv4"
 	"golang.org/x/net/ipv6"
+	"math"
 	"math/rand"
 	"net"
 	"os"
@@ -18,6 +19,7 @@ import (
 	"time"
 
 	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/stats"
 )
 
 // packet represents ping packet
@@ -225,15 +227,43 @@ func (p *Ping) PacketSize(s int) {
 	p.pSize = s
 }
 
-// listen starts to listen incoming icmp
+// listen starts to listen incoming icmp, it falls back to
+// the unprivileged udp ping if the raw socket isn't permitted
 func (p *Ping) listen(network string) (*icmp.PacketConn, error) {
 	c, err := icmp.ListenPacket(network, p.source)
+	if err != nil && isPermissionErr(err) {
+		udp := map[string]string{"ip4:icmp": "udp4", "ip6:ipv6-icmp": "udp6"}[network]
+		if c, err = icmp.ListenPacket(udp, p.source); err == nil {
+			p.unprivileged = true
+		}
+	}
 	if err != nil {
 		return c, err
 	}
 	return c, nil
 }
 
+// isPermissionErr returns true if the socket isn't permitted
+func isPermissionErr(err error) bool {
+	return os.IsPermission(err) || errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES)
+}
+
+// hostAddr returns the address w/o the udp port
+func hostAddr(addr net.Addr) string {
+	if a, ok := addr.(*net.UDPAddr); ok {
+		return (&net.IPAddr{IP: a.IP, Zone: a.Zone}).String()
+	}
+	return addr.String()
+}
+
+// dest returns the ping destination address based on the socket
+func (p *Ping) dest() net.Addr {
+	if p.unprivileged {
+		return &net.UDPAddr{IP: p.addr.IP, Zone: p.addr.Zone}
+	}
+	return p.addr
+}
+
 // recv reads icmp message
 func (p *Ping) recv(conn *icmp.PacketConn, rcvdChan chan<- *packet) {
 	var (
@@ -261,7 +291,8 @@ func (p *Ping) recv(conn *icmp.PacketConn, rcvdChan chan<- *packet) {
 		if n > 0 {
 			respID := int(bytes[4])<<8 | int(bytes[5])
 			respSq := int(bytes[6])<<8 | int(bytes[7])
-			if respID == p.id && respSq == p.seq {
+			// the kernel replaces the id w/ the udp port at unprivileged mode
+			if (respID == p.id || p.unprivileged) && respSq == p.seq {
 				rcvdChan <- &packet{bytes: bytes, addr: dest, err: err}
 				break
 			} else if time.Since(ts) < p.timeout {
@@ -327,7 +358,7 @@ func (p *Ping) send(conn *icmp.PacketConn) {
 			}
 			break
 		}
-	}(conn, p.addr, bytes)
+	}(conn, p.dest(), bytes)
 
 	wg.Wait()
 }
@@ -382,7 +413,7 @@ func (p *Ping) Ping(out chan Response) {
 		rtt := float64(time.Now().UnixNano()-getTimeStamp(rm.bytes)) / 1000000
 		out <- Response{
 			Size:     len(rm.bytes),
-			Addr:     rm.addr.String(),
+			Addr:     hostAddr(rm.addr),
 			RTT:      rtt,
 			Sequence: p.seq,
 			Error:    nil,
@@ -402,6 +433,7 @@ func (p *Ping) PrintPretty(resp chan Response) {
 		sFmt          = "%d packets transmitted,  %d packets received, %d%% packet loss\n"
 		msg           string
 		min, max, avg float64
+		rtts          []float64
 		c             = map[string]int{"tx": 0, "err": 0, "pl": 0}
 	)
 
@@ -427,7 +459,7 @@ func (p *Ping) PrintPretty(resp chan Response) {
 
 			min = Min(r.RTT, min)
 			max = Max(r.RTT, max)
-			avg = Avg(r.RTT, avg)
+			rtts = append(rtts, r.RTT)
 
 			msg = fmt.Sprintf(pFmt, r.Size, r.Addr, r.Sequence, r.RTT)
 			println(msg)
@@ -449,7 +481,10 @@ func (p *Ping) PrintPretty(resp chan Response) {
 		return
 	}
 
-	fmt.Printf("round-trip min/avg/max = %.3f/%.3f/%.3f ms\n", min, avg, max)
+	avg = Mean(rtts)
+	fmt.Printf("round-trip min/avg/max/stddev = %.3f/%.3f/%.3f/%.3f ms\n", min, avg, max, StdDev(rtts))
+	fmt.Printf("percentile p50/p90/p99 = %.3f/%.3f/%.3f ms, jitter = %.3f ms\n",
+		Percentile(rtts, 50), Percentile(rtts, 90), Percentile(rtts, 99), Jitter(rtts))
 }
 
 // IsCIDR returns true if target is CIDR
@@ -481,6 +516,42 @@ func Avg(x, y float64) float64 {
 	return (x + y) / 2
 }
 
+// Mean returns the average of the rtts
+func Mean(rtts []float64) float64 {
+	var sum float64
+	if len(rtts) == 0 {
+		return 0
+	}
+	for _, rtt := range rtts {
+		sum += rtt
+	}
+	return sum / float64(len(rtts))
+}
+
+// StdDev returns the population standard deviation of the rtts
+func StdDev(rtts []float64) float64 {
+	var sum float64
+	if len(rtts) == 0 {
+		return 0
+	}
+	mean := Mean(rtts)
+	for _, rtt := range rtts {
+		sum += (rtt - mean) * (rtt - mean)
+	}
+	return math.Sqrt(sum / float64(len(rtts)))
+}
+
+// Percentile returns the nearest-rank percentile (0-100) of the rtts,
+// see stats.Percentile
+func Percentile(rtts []float64, p float64) float64 {
+	return stats.Percentile(rtts, p)
+}
+
+// Jitter returns the mean deviation of the consecutive rtts, see stats.Jitter
+func Jitter(rtts []float64) float64 {
+	return stats.Jitter(rtts)
+}
+
 // getTimeStamp
 func getTimeStamp(m []byte) int64 {
 	var ts int64
@@ -555,12 +626,17 @@ func help(cfg cli.Config) {
           -i interval    Wait interval between sending each packet (default: %s)
           -4             Forces the ping command to use IPv4 (target should be hostname)
           -6             Forces the ping command to use IPv6 (target should be hostname)
+          -up n          Pings until n consecutive replies (exit status 0)
+          -down n        Pings until n consecutive failures (exit status 1)
+          -above rtt     Pings until the rtt crosses the threshold in ms (exit status 3)
+          -max duration  Stops the -up/-down/-above pings after the duration (exit status 2)
     Example:
           ping 8.8.8.8
           ping 31.13.74.0/24
           ping 8.8.8.8 -c 10
           ping google.com -6
           ping mylg.io -i 5s
+          ping 10.0.0.1 -up 3 -max 10m
 	`,
 		cfg.Ping.Count,
 		cfg.Ping.Timeout,
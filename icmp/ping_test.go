@@ -21,3 +21,34 @@ func TestIsIPvx(t *testing.T) {
 		t.Error("IsIPv4 is false but expected true")
 	}
 }
+
+func TestStdDev(t *testing.T) {
+	rtts := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+	if m := icmp.Mean(rtts); m != 5 {
+		t.Error("expected mean 5 but got", m)
+	}
+	if s := icmp.StdDev(rtts); s != 2 {
+		t.Error("expected stddev 2 but got", s)
+	}
+	if s := icmp.StdDev(nil); s != 0 {
+		t.Error("expected stddev 0 w/o rtts but got", s)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	rtts := []float64{7, 1, 10, 3, 5, 2, 9, 4, 8, 6}
+	for p, want := range map[float64]float64{50: 5, 90: 9, 99: 10, 0: 1} {
+		if got := icmp.Percentile(rtts, p); got != want {
+			t.Errorf("expected p%v %v but got %v", p, want, got)
+		}
+	}
+	if rtts[0] != 7 {
+		t.Error("expected the rtts not to be sorted in place")
+	}
+	if j := icmp.Jitter([]float64{2, 4, 4, 4, 5, 5, 7, 9}); j != 1 {
+		t.Error("expected jitter 1 but got", j)
+	}
+	if j := icmp.Jitter([]float64{2}); j != 0 {
+		t.Error("expected jitter 0 w/ one rtt but got", j)
+	}
+}
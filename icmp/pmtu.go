@@ -0,0 +1,295 @@
+package icmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"github.com/mehrdadrad/mylg/cli"
+)
+
+// the path mtu search range, the minimum is the
+// smallest mtu which every link has to support
+const (
+	minIPv4MTU     = 68
+	minIPv6MTU     = 1280
+	defaultMaxMTU  = 1500
+	pmtuProbeTries = 2
+)
+
+// ErrRawSocket is returned if the raw icmp socket isn't permitted, the
+// don't fragment probes and the frag needed messages need the raw socket
+var ErrRawSocket = errors.New("path mtu discovery needs the raw icmp socket, run it as root or w/ CAP_NET_RAW")
+
+// PMTU represents a path mtu discovery request
+type PMTU struct {
+	target  string
+	ip      net.IP
+	max     int
+	timeout time.Duration
+	id      int
+	seq     int
+}
+
+// PMTUResult represents the discovered path mtu, Hop is the router
+// which sent the frag needed (packet too big) message w/ its HopMTU
+type PMTUResult struct {
+	Target string
+	IP     net.IP
+	MTU    int
+	Hop    string
+	HopMTU int
+	Probes int
+}
+
+// pmtuReply represents a probe result
+type pmtuReply struct {
+	ok     bool
+	tooBig bool
+	hop    string
+	mtu    int
+}
+
+// NewPMTU parses the pmtu arguments, nil is returned if the help is requested
+func NewPMTU(args string) (*PMTU, error) {
+	target, flag := cli.Flag(args)
+	if _, ok := flag["help"]; ok || target == "" {
+		pmtuHelp()
+		return nil, nil
+	}
+	p := &PMTU{
+		target: target,
+		max:    cli.SetFlag(flag, "max", defaultMaxMTU).(int),
+		id:     rand.Intn(0xffff),
+	}
+	t, err := time.ParseDuration(NormalizeDuration(fmt.Sprint(cli.SetFlag(flag, "t", "1s"))))
+	if err != nil {
+		return nil, fmt.Errorf("timeout options is not valid")
+	}
+	p.timeout = t
+
+	ips, err := net.LookupIP(target)
+	if err != nil {
+		return nil, err
+	}
+	forceV4, forceV6 := cli.SetFlag(flag, "4", false).(bool), cli.SetFlag(flag, "6", false).(bool)
+	for _, ip := range ips {
+		if (IsIPv4(ip) && !forceV6) || (IsIPv6(ip) && !forceV4) {
+			p.ip = ip
+			break
+		}
+	}
+	if p.ip == nil {
+		return nil, fmt.Errorf("%s has no address of the requested family", target)
+	}
+	if p.max < p.min() {
+		return nil, fmt.Errorf("max mtu should be at least %d", p.min())
+	}
+	return p, nil
+}
+
+// min returns the minimum mtu of the address family
+func (p *PMTU) min() int {
+	if IsIPv4(p.ip) {
+		return minIPv4MTU
+	}
+	return minIPv6MTU
+}
+
+// Run sends the don't fragment echo requests and binary searches the
+// largest packet size which gets the echo reply
+func (p *PMTU) Run() (PMTUResult, error) {
+	r := PMTUResult{Target: p.target, IP: p.ip}
+	conn, err := p.listen()
+	if err != nil {
+		return r, err
+	}
+	defer conn.Close()
+
+	lo, hi := p.min(), p.max
+	probe := func(size int) (pmtuReply, error) {
+		var (
+			reply pmtuReply
+			err   error
+		)
+		for i := 0; i < pmtuProbeTries; i++ {
+			r.Probes++
+			if reply, err = p.probe(conn, size); err != nil || reply.ok || reply.tooBig {
+				break
+			}
+		}
+		return reply, err
+	}
+	if reply, err := probe(lo); err != nil || !reply.ok {
+		if err == nil {
+			err = fmt.Errorf("no echo reply from %s", p.ip)
+		}
+		return r, err
+	}
+	for lo < hi {
+		size := (lo + hi + 1) / 2
+		reply, err := probe(size)
+		if err != nil {
+			return r, err
+		}
+		switch {
+		case reply.ok:
+			lo = size
+		case reply.tooBig:
+			r.Hop, r.HopMTU = reply.hop, reply.mtu
+			hi = size - 1
+			if reply.mtu >= lo && reply.mtu < hi {
+				hi = reply.mtu
+			}
+		default:
+			// dropped silently (a pmtu black hole)
+			hi = size - 1
+		}
+	}
+	r.MTU = lo
+	return r, nil
+}
+
+// listen opens the raw icmp socket w/ the don't fragment bit set
+func (p *PMTU) listen() (*net.IPConn, error) {
+	network, addr := "ip4:icmp", "0.0.0.0"
+	if IsIPv6(p.ip) {
+		network, addr = "ip6:ipv6-icmp", "::"
+	}
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			var err error
+			if cErr := c.Control(func(fd uintptr) {
+				err = setDontFragment(int(fd), IsIPv6(p.ip))
+			}); cErr != nil {
+				return cErr
+			}
+			return err
+		},
+	}
+	c, err := lc.ListenPacket(context.Background(), network, addr)
+	if err != nil {
+		if isPermissionErr(err) {
+			return nil, ErrRawSocket
+		}
+		return nil, err
+	}
+	return c.(*net.IPConn), nil
+}
+
+// probe sends an echo request of the packet size and waits for the echo
+// reply or the frag needed message, it's neither once it's timed out
+func (p *PMTU) probe(conn *net.IPConn, size int) (pmtuReply, error) {
+	var (
+		typ    icmp.Type = ipv4.ICMPTypeEcho
+		header           = 20
+		proto            = ProtocolIPv4ICMP
+	)
+	if IsIPv6(p.ip) {
+		typ, header, proto = ipv6.ICMPTypeEchoRequest, 40, ProtocolIPv6ICMP
+	}
+	p.seq = (p.seq + 1) & 0xffff
+	b, err := (&icmp.Message{
+		Type: typ,
+		Body: &icmp.Echo{ID: p.id, Seq: p.seq, Data: make([]byte, size-header-8)},
+	}).Marshal(nil)
+	if err != nil {
+		return pmtuReply{}, err
+	}
+	if _, err := conn.WriteTo(b, &net.IPAddr{IP: p.ip}); err != nil {
+		// the packet is larger than the local interface mtu
+		if errors.Is(err, syscall.EMSGSIZE) {
+			return pmtuReply{tooBig: true, hop: "local"}, nil
+		}
+		return pmtuReply{}, err
+	}
+
+	buf := make([]byte, 1<<16)
+	deadline := time.Now().Add(p.timeout)
+	conn.SetReadDeadline(deadline)
+	for time.Now().Before(deadline) {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				break
+			}
+			return pmtuReply{}, err
+		}
+		if r, ok := p.parse(buf[:n], proto); ok {
+			r.hop = from.String()
+			return r, nil
+		}
+	}
+	return pmtuReply{}, nil
+}
+
+// parse returns the reply if the message is the echo reply or the frag
+// needed / packet too big message of the current probe
+func (p *PMTU) parse(b []byte, proto int) (pmtuReply, bool) {
+	if len(b) < 8 {
+		return pmtuReply{}, false
+	}
+	echo := func(b []byte) bool {
+		return len(b) >= 8 && int(b[4])<<8|int(b[5]) == p.id && int(b[6])<<8|int(b[7]) == p.seq
+	}
+	switch {
+	case proto == ProtocolIPv4ICMP && b[0] == IPv4ICMPTypeEchoReply,
+		proto == ProtocolIPv6ICMP && b[0] == IPv6ICMPTypeEchoReply:
+		return pmtuReply{ok: true}, echo(b)
+	case proto == ProtocolIPv4ICMP && b[0] == IPv4ICMPTypeDestinationUnreachable && b[1] == 4:
+		// the original ip header and the first 8 bytes of the echo request
+		if len(b) < 28 {
+			return pmtuReply{}, false
+		}
+		ihl := int(b[8]&0x0f) * 4
+		if len(b) < 8+ihl+8 {
+			return pmtuReply{}, false
+		}
+		return pmtuReply{tooBig: true, mtu: int(b[6])<<8 | int(b[7])}, echo(b[8+ihl:])
+	case proto == ProtocolIPv6ICMP && b[0] == 2:
+		// packet too big, the original ipv6 header follows the mtu
+		if len(b) < 8+40+8 {
+			return pmtuReply{}, false
+		}
+		mtu := int(b[4])<<24 | int(b[5])<<16 | int(b[6])<<8 | int(b[7])
+		return pmtuReply{tooBig: true, mtu: mtu}, echo(b[48:])
+	}
+	return pmtuReply{}, false
+}
+
+// PrintPretty prints the discovered path mtu
+func (r PMTUResult) PrintPretty() {
+	fmt.Printf("path mtu to %s (%s): %d bytes (%d probes)\n", r.Target, r.IP, r.MTU, r.Probes)
+	switch {
+	case r.Hop == "local":
+		fmt.Println("the local interface mtu is smaller than the probe")
+	case r.Hop != "" && r.HopMTU > 0:
+		fmt.Printf("fragmentation needed at %s (next-hop mtu %d)\n", r.Hop, r.HopMTU)
+	case r.Hop != "":
+		fmt.Printf("fragmentation needed at %s\n", r.Hop)
+	}
+}
+
+func pmtuHelp() {
+	fmt.Println(`
+    usage:
+          pmtu IP address / domain name [options]
+    options:
+          -max size      The largest probed packet size in bytes (default: 1500)
+          -t timeout     Wait timeout per probe e.g. 500ms (default: 1s)
+          -4             Forces IPv4 (target should be hostname)
+          -6             Forces IPv6 (target should be hostname)
+    Example:
+          pmtu 8.8.8.8
+          pmtu google.com -6
+          pmtu 10.0.0.1 -max 9000
+	`)
+}
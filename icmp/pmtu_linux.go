@@ -0,0 +1,18 @@
+package icmp
+
+import (
+	"os"
+	"syscall"
+)
+
+// ipv6PMTUDiscProbe is IPV6_PMTUDISC_PROBE, it's not defined by syscall
+const ipv6PMTUDiscProbe = 3
+
+// setDontFragment sets the don't fragment bit and ignores the cached
+// path mtu, so the probes larger than the path mtu aren't fragmented
+func setDontFragment(fd int, v6 bool) error {
+	if v6 {
+		return os.NewSyscallError("setsockopt", syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_MTU_DISCOVER, ipv6PMTUDiscProbe))
+	}
+	return os.NewSyscallError("setsockopt", syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_MTU_DISCOVER, syscall.IP_PMTUDISC_PROBE))
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package icmp
+
+import (
+	"errors"
+	"runtime"
+)
+
+// setDontFragment isn't supported, the probes could be fragmented
+func setDontFragment(fd int, v6 bool) error {
+	return errors.New("path mtu discovery isn't supported on " + runtime.GOOS)
+}
@@ -0,0 +1,24 @@
+package icmp_test
+
+import (
+	"testing"
+
+	"github.com/mehrdadrad/mylg/icmp"
+)
+
+func TestPMTU(t *testing.T) {
+	p, err := icmp.NewPMTU("127.0.0.1 -max 1400 -t 200ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := p.Run()
+	if err == icmp.ErrRawSocket {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.MTU != 1400 || r.Hop != "" {
+		t.Errorf("expected 1400 bytes path mtu w/o fragmentation but got %+v", r)
+	}
+}
@@ -502,6 +502,21 @@ func (h *HopResp) Marshal() string {
 	)
 }
 
+// Num returns the hop number
+func (h HopResp) Num() int { return h.num }
+
+// Host returns the hop name, it's empty if it can't be resolved
+func (h HopResp) Host() string { return h.hop }
+
+// IP returns the hop address, it's empty once the hop is timed out
+func (h HopResp) IP() string { return h.ip }
+
+// Elapsed returns the hop rtt in milliseconds
+func (h HopResp) Elapsed() float64 { return h.elapsed }
+
+// Err returns the hop error
+func (h HopResp) Err() error { return h.err }
+
 // routerChange detects if the router changed
 // to another one
 func routerChange(router, b string) bool {
@@ -0,0 +1,217 @@
+package icmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/mehrdadrad/mylg/cli"
+)
+
+// UntilCondition represents the stop condition of the ping until mode,
+// the zero values are disabled. RTTAbove is the rtt threshold in ms
+type UntilCondition struct {
+	Successes   int
+	Failures    int
+	RTTAbove    float64
+	MaxDuration time.Duration
+}
+
+// UntilStatus represents why the ping until mode is stopped
+type UntilStatus int
+
+// the ping until mode statuses
+const (
+	UntilRunning UntilStatus = iota
+	UntilUp
+	UntilDown
+	UntilSlow
+	UntilTimeout
+	UntilInterrupted
+)
+
+// UntilResult represents the ping until mode statistics
+type UntilResult struct {
+	Condition UntilCondition
+	Status    UntilStatus
+	Sent      int
+	Received  int
+	Min       float64
+	Avg       float64
+	Max       float64
+	Elapsed   time.Duration
+
+	// the consecutive replies and failures
+	successes int
+	failures  int
+}
+
+// ParseUntil parses the ping until flags (-up, -down, -above and
+// -max), ok is false if none of the conditions is set
+func ParseUntil(args string) (c UntilCondition, ok bool, err error) {
+	_, flag := cli.Flag(args)
+	for name, v := range map[string]*int{"up": &c.Successes, "down": &c.Failures} {
+		if n, set := flag[name]; set {
+			if *v, ok = n.(int); !ok || *v < 1 {
+				return c, false, fmt.Errorf("-%s should be a positive number of pings", name)
+			}
+		}
+	}
+	if v, set := flag["above"]; set {
+		if c.RTTAbove, err = strconv.ParseFloat(fmt.Sprint(v), 64); err != nil || c.RTTAbove <= 0 {
+			return c, false, errors.New("-above should be a positive rtt in ms")
+		}
+	}
+	if v, set := flag["max"]; set {
+		if c.MaxDuration, err = time.ParseDuration(NormalizeDuration(fmt.Sprint(v))); err != nil {
+			return c, false, errors.New("-max should be a duration e.g. 10m")
+		}
+	}
+	if c.Successes == 0 && c.Failures == 0 && c.RTTAbove == 0 {
+		if c.MaxDuration > 0 {
+			return c, false, errors.New("-max needs any of -up, -down or -above")
+		}
+		return c, false, nil
+	}
+	return c, true, nil
+}
+
+// Add counts the response and returns true once the condition is met
+func (r *UntilResult) Add(resp Response) bool {
+	r.Sent++
+	if resp.Error != nil {
+		r.successes = 0
+		r.failures++
+		if c := r.Condition.Failures; c > 0 && r.failures >= c {
+			r.Status = UntilDown
+		}
+		return r.Status != UntilRunning
+	}
+	r.Min = Min(resp.RTT, r.Min)
+	r.Max = Max(resp.RTT, r.Max)
+	r.Avg = (r.Avg*float64(r.Received) + resp.RTT) / float64(r.Received+1)
+	r.Received++
+	r.failures = 0
+	r.successes++
+	switch c := r.Condition; {
+	case c.RTTAbove > 0 && resp.RTT > c.RTTAbove:
+		r.Status = UntilSlow
+	case c.Successes > 0 && r.successes >= c.Successes:
+		r.Status = UntilUp
+	}
+	return r.Status != UntilRunning
+}
+
+// Until repeats the pings at the interval until the condition is met,
+// the max duration is passed or ctx is canceled. the responses are
+// passed to the report func once they're received
+func (p *Ping) Until(ctx context.Context, c UntilCondition, report func(Response)) UntilResult {
+	var (
+		r     = UntilResult{Condition: c}
+		start = time.Now()
+		resp  = make(chan Response, 1)
+	)
+	if c.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.MaxDuration)
+		defer cancel()
+	}
+	for {
+		p.Ping(resp)
+		res := <-resp
+		report(res)
+		if r.Add(res) {
+			break
+		}
+		select {
+		case <-time.After(p.interval):
+			continue
+		case <-ctx.Done():
+		}
+		r.Status = UntilInterrupted
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			r.Status = UntilTimeout
+		}
+		break
+	}
+	r.Elapsed = time.Since(start)
+	return r
+}
+
+// PrintUntil prints the responses of the ping until mode and
+// the summary once it's stopped (or interrupted)
+func (p *Ping) PrintUntil(c UntilCondition) UntilResult {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	fmt.Printf("PING %s (%s): %d data bytes\n", p.target, p.addr, p.pSize-8)
+	r := p.Until(ctx, c, func(r Response) {
+		if r.Error != nil {
+			fmt.Printf("%s icmp_seq=%d\n", r.Error, r.Sequence)
+			return
+		}
+		fmt.Printf("%d bytes from %s icmp_seq=%d time=%.3f ms\n", r.Size, r.Addr, r.Sequence, r.RTT)
+	})
+	fmt.Printf("\n--- %s ping until statistics ---\n", p.target)
+	fmt.Println(r)
+	return r
+}
+
+// String returns the status and the statistics
+func (r UntilResult) String() string {
+	s := fmt.Sprintf("%s after %s, %d packets transmitted, %d packets received", r.Status, r.Elapsed.Round(time.Millisecond), r.Sent, r.Received)
+	if r.Received > 0 {
+		s += fmt.Sprintf("\nround-trip min/avg/max = %.3f/%.3f/%.3f ms", r.Min, r.Avg, r.Max)
+	}
+	return s
+}
+
+// String returns the status description
+func (s UntilStatus) String() string {
+	switch s {
+	case UntilRunning:
+		return "running"
+	case UntilUp:
+		return "up"
+	case UntilDown:
+		return "down"
+	case UntilSlow:
+		return "rtt threshold crossed"
+	case UntilTimeout:
+		return "max duration passed"
+	case UntilInterrupted:
+		return "interrupted"
+	}
+	return fmt.Sprintf("UntilStatus(%d)", int(s))
+}
+
+// ExitCode returns the process exit status, it's zero once the host
+// is up, 1 once it's down, 2 if the condition isn't met in the max
+// duration, 3 once the rtt threshold is crossed and 130 on interrupt
+func (s UntilStatus) ExitCode() int {
+	switch s {
+	case UntilUp:
+		return 0
+	case UntilDown:
+		return 1
+	case UntilSlow:
+		return 3
+	case UntilInterrupted:
+		return 130
+	}
+	return 2
+}
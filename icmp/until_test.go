@@ -0,0 +1,67 @@
+package icmp_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mehrdadrad/mylg/icmp"
+)
+
+func TestParseUntil(t *testing.T) {
+	c, ok, err := icmp.ParseUntil("8.8.8.8 -up 3 -above 150.5 -max 10m")
+	if err != nil || !ok {
+		t.Fatal("unexpected error", err)
+	}
+	if want := (icmp.UntilCondition{Successes: 3, RTTAbove: 150.5, MaxDuration: 10 * time.Minute}); c != want {
+		t.Errorf("expected %+v but got %+v", want, c)
+	}
+	if _, ok, err := icmp.ParseUntil("8.8.8.8 -c 3"); ok || err != nil {
+		t.Error("expected no condition but got", ok, err)
+	}
+	for _, args := range []string{"8.8.8.8 -down 0", "8.8.8.8 -above fast", "8.8.8.8 -max 10"} {
+		if _, _, err := icmp.ParseUntil(args); err == nil {
+			t.Error("expected error for", args)
+		}
+	}
+}
+
+func TestUntilResult(t *testing.T) {
+	var (
+		ok   = func(rtt float64) icmp.Response { return icmp.Response{RTT: rtt} }
+		fail = icmp.Response{Error: errors.New("Request timeout")}
+	)
+	tests := []struct {
+		cond   icmp.UntilCondition
+		resps  []icmp.Response
+		status icmp.UntilStatus
+		sent   int
+	}{
+		{icmp.UntilCondition{Successes: 2}, []icmp.Response{fail, ok(1), fail, ok(2), ok(3), ok(4)}, icmp.UntilUp, 5},
+		{icmp.UntilCondition{Failures: 2}, []icmp.Response{fail, ok(1), fail, fail, ok(1)}, icmp.UntilDown, 4},
+		{icmp.UntilCondition{Successes: 5, RTTAbove: 100}, []icmp.Response{ok(10), ok(150), ok(10)}, icmp.UntilSlow, 2},
+		{icmp.UntilCondition{Successes: 5}, []icmp.Response{ok(1), ok(3)}, icmp.UntilRunning, 2},
+	}
+	for i, tt := range tests {
+		r := icmp.UntilResult{Condition: tt.cond}
+		for _, resp := range tt.resps {
+			if r.Add(resp) {
+				break
+			}
+		}
+		if r.Status != tt.status || r.Sent != tt.sent {
+			t.Errorf("%d: expected %s after %d pings but got %s after %d", i, tt.status, tt.sent, r.Status, r.Sent)
+		}
+	}
+	r := icmp.UntilResult{}
+	r.Add(ok(1))
+	r.Add(ok(3))
+	if r.Min != 1 || r.Avg != 2 || r.Max != 3 || r.Received != 2 {
+		t.Errorf("unexpected statistics %+v", r)
+	}
+	for s, code := range map[icmp.UntilStatus]int{icmp.UntilUp: 0, icmp.UntilDown: 1, icmp.UntilTimeout: 2, icmp.UntilSlow: 3} {
+		if s.ExitCode() != code {
+			t.Errorf("expected %s exit status %d but got %d", s, code, s.ExitCode())
+		}
+	}
+}
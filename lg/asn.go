@@ -0,0 +1,194 @@
+package lg
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ASNResolver resolves an AS number to the AS name
+type ASNResolver interface {
+	Lookup(asn string) (string, error)
+}
+
+// CymruResolver resolves the AS names through Team Cymru DNS
+// service, the results are cached and it's safe for concurrent use
+type CymruResolver struct {
+	// Resolver is used for the TXT lookups, net.DefaultResolver
+	// is used if it's nil
+	Resolver *net.Resolver
+	// Timeout is the lookup timeout (default 2s)
+	Timeout time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cymruRecord
+}
+
+// cymruRecord represents the AS name and the registry country
+type cymruRecord struct {
+	name    string
+	country string
+}
+
+// asnResolver annotates the trace ASNs w/ the AS names if it's set
+var asnResolver ASNResolver
+
+var bareASNRe = regexp.MustCompile(`(?i)\[AS\s*(\d+)\]`)
+
+// SetASNResolver enables the AS name annotation of the traces,
+// it's disabled if the resolver is nil
+func SetASNResolver(r ASNResolver) {
+	asnResolver = r
+}
+
+// NewCymruResolver returns a Team Cymru AS name resolver
+func NewCymruResolver() *CymruResolver {
+	return &CymruResolver{cache: make(map[string]cymruRecord)}
+}
+
+// Lookup returns the AS name, e.g. COGENT-174 for 174
+func (r *CymruResolver) Lookup(asn string) (string, error) {
+	rec, err := r.lookup(asn)
+	return rec.name, err
+}
+
+// LookupRegistry returns the AS name and the registry country, e.g.
+// COGENT-174 and US for 174
+func (r *CymruResolver) LookupRegistry(asn string) (string, string, error) {
+	rec, err := r.lookup(asn)
+	return rec.name, rec.country, err
+}
+
+func (r *CymruResolver) lookup(asn string) (cymruRecord, error) {
+	asn = strings.TrimPrefix(strings.ToUpper(asn), "AS")
+	r.mu.Lock()
+	if r.cache == nil {
+		r.cache = make(map[string]cymruRecord)
+	}
+	rec, ok := r.cache[asn]
+	r.mu.Unlock()
+	if ok {
+		return rec, nil
+	}
+
+	txt, err := r.lookupTXT(context.Background(), "AS"+asn+".asn.cymru.com")
+	if err != nil {
+		return rec, err
+	}
+	if len(txt) == 0 {
+		return rec, fmt.Errorf("AS%s not found", asn)
+	}
+	rec, err = parseCymruTXT(txt[0])
+	if err != nil {
+		return rec, err
+	}
+	r.mu.Lock()
+	r.cache[asn] = rec
+	r.mu.Unlock()
+	return rec, nil
+}
+
+// LookupOrigins returns the origin ASNs of the ip, e.g. 174 for 38.0.0.1,
+// it's empty if the ip isn't announced
+func (r *CymruResolver) LookupOrigins(ctx context.Context, ip string) ([]int, error) {
+	name, err := cymruOriginName(ip)
+	if err != nil {
+		return nil, err
+	}
+	txt, err := r.lookupTXT(ctx, name)
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var (
+		asns []int
+		seen = map[int]bool{}
+	)
+	// e.g. 174 | 38.0.0.0/8 | US | arin | 2004-03-01, the
+	// multiple origins are separated by space
+	for _, t := range txt {
+		for _, a := range strings.Fields(strings.Split(t, "|")[0]) {
+			n, err := strconv.Atoi(a)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected cymru response: %s", t)
+			}
+			if !seen[n] {
+				seen[n] = true
+				asns = append(asns, n)
+			}
+		}
+	}
+	return asns, nil
+}
+
+// lookupTXT returns the TXT records of the name w/ the timeout
+func (r *CymruResolver) lookupTXT(ctx context.Context, name string) ([]string, error) {
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return resolver.LookupTXT(ctx, name)
+}
+
+// cymruOriginName returns the origin query name of the ip, e.g.
+// 1.0.0.38.origin.asn.cymru.com for 38.0.0.1 and the reversed
+// nibbles at origin6.asn.cymru.com for the IPv6 addresses
+func cymruOriginName(ip string) (string, error) {
+	a := net.ParseIP(ip)
+	if a == nil {
+		return "", fmt.Errorf("invalid ip address %q", ip)
+	}
+	if v4 := a.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.origin.asn.cymru.com", v4[3], v4[2], v4[1], v4[0]), nil
+	}
+	var b strings.Builder
+	for i := len(a) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "%x.%x.", a[i]&0xf, a[i]>>4)
+	}
+	return b.String() + "origin6.asn.cymru.com", nil
+}
+
+// parseCymruTXT returns the AS name and the country from the TXT record, e.g.
+// 174 | US | arin | 2001-01-01 | COGENT-174 - Cogent Communications, US
+func parseCymruTXT(txt string) (cymruRecord, error) {
+	f := strings.Split(txt, "|")
+	if len(f) < 5 {
+		return cymruRecord{}, fmt.Errorf("unexpected cymru response: %s", txt)
+	}
+	name := strings.TrimSpace(f[4])
+	if i := strings.Index(name, " - "); i > 0 {
+		name = name[:i]
+	}
+	return cymruRecord{name: name, country: strings.TrimSpace(f[1])}, nil
+}
+
+// annotateASN appends the AS names to the bare ASNs, e.g. [AS174]
+// becomes [AS174 COGENT-174], the ASN is kept if the lookup fails
+func annotateASN(l string) string {
+	r := asnResolver
+	if r == nil {
+		return l
+	}
+	return bareASNRe.ReplaceAllStringFunc(l, func(s string) string {
+		asn := bareASNRe.FindStringSubmatch(s)[1]
+		name, err := r.Lookup(asn)
+		if err != nil || name == "" {
+			getLogger().Debug("asn: lookup AS%s failed: %v", asn, err)
+			return s
+		}
+		return fmt.Sprintf("[AS%s %s]", asn, name)
+	})
+}
@@ -0,0 +1,215 @@
+package lg_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/lg"
+	"github.com/mehrdadrad/mylg/ripe"
+	"github.com/miekg/dns"
+)
+
+// fakeResolver resolves the ASNs from a map
+type fakeResolver struct {
+	mu    sync.Mutex
+	names map[string]string
+	calls int
+}
+
+func (r *fakeResolver) Lookup(asn string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if name, ok := r.names[asn]; ok {
+		return name, nil
+	}
+	return "", errors.New("not found")
+}
+
+func TestASNResolverTrace(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `traceroute to 8.8.8.8 (8.8.8.8), 30 hops max, 60 byte packets
+ 1  be2931.ccr21.lax01.atlas.cogentco.com (154.54.44.85) [AS174]  0.512 ms
+ 2  72.14.236.69 (72.14.236.69) [AS 15169]  1.5 ms
+`)
+	}))
+	defer ts.Close()
+
+	r := &fakeResolver{names: map[string]string{"174": "COGENT-174"}}
+	lg.SetASNResolver(r)
+	defer lg.SetASNResolver(nil)
+
+	c := &lg.Cogent{BaseURL: ts.URL}
+	c.Set("8.8.8.8", "ipv4")
+	lines, err := c.Trace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for l := range lines {
+		got = append(got, l)
+	}
+	if len(got) != 3 {
+		t.Fatal("unexpected trace", got)
+	}
+	if !strings.Contains(got[1], "[AS174 COGENT-174]") {
+		t.Error("expected AS name annotation but got", got[1])
+	}
+	// lookup failure keeps the number
+	if !strings.Contains(got[2], "[AS 15169]") {
+		t.Error("expected bare ASN on lookup failure but got", got[2])
+	}
+
+	// the structured hops are annotated once
+	r.calls = 0
+	hops, err := c.TraceStructured()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var asns []string
+	for hop := range hops {
+		asns = append(asns, hop.ASN)
+	}
+	if !reflect.DeepEqual(asns, []string{"174", "15169"}) {
+		t.Error("unexpected hop ASNs", asns)
+	}
+	if r.calls != 2 {
+		t.Error("expected 2 AS name lookups but got", r.calls)
+	}
+}
+
+// fakeRegistry resolves the AS names and countries
+type fakeRegistry struct {
+	calls int
+}
+
+func (r *fakeRegistry) LookupRegistry(asn string) (string, string, error) {
+	r.calls++
+	if asn != "174" {
+		return "", "", errors.New("not found")
+	}
+	return "COGENT-174", "US", nil
+}
+
+func TestASNLookup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	reg := &fakeRegistry{}
+	l := &lg.ASNLookup{
+		Registry: reg,
+		RIPE: func(asn string) (ripe.RIPEASN, error) {
+			return ripe.RIPEASN{Holder: "COGENT-174 - Cogent Communications",
+				Prefixes: []string{"38.0.0.0/8", "2001:550::/32"}}, nil
+		},
+		CacheFile: filepath.Join(dir, "asn.cache.json"),
+	}
+	expected := lg.ASNInfo{
+		ASN:     174,
+		Name:    "COGENT-174",
+		Holder:  "COGENT-174 - Cogent Communications",
+		Country: "US",
+		IPv4:    []string{"38.0.0.0/8"},
+		IPv6:    []string{"2001:550::/32"},
+	}
+	for _, asn := range []string{"174", "AS174", "as174"} {
+		info, err := l.Lookup(asn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(info, expected) {
+			t.Errorf("%s: expected %+v but got %+v", asn, expected, info)
+		}
+	}
+	if reg.calls != 1 {
+		t.Error("expected the disk cache to be used but registry calls", reg.calls)
+	}
+
+	if _, err := l.Lookup("ASX"); err == nil {
+		t.Error("expected invalid AS number error")
+	}
+}
+
+func TestCymruLookupOrigins(t *testing.T) {
+	origins := map[string]string{
+		"1.2.0.192.origin.asn.cymru.com.": "64500 174 | 192.0.2.0/24 | US | arin | 2004-03-01",
+		"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.origin6.asn.cymru.com.": "1299 | 2001:db8::/32 | SE | ripencc | 2005-01-01",
+	}
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, q *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(q)
+		if txt, ok := origins[q.Question[0].Name]; ok {
+			m.Answer = append(m.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: q.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+				Txt: []string{txt},
+			})
+		} else {
+			m.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(m)
+	})}
+	go s.ActivateAndServe()
+	defer s.Shutdown()
+
+	r := &lg.CymruResolver{Resolver: &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "udp", pc.LocalAddr().String())
+		},
+	}}
+	tests := []struct {
+		ip   string
+		asns []int
+	}{
+		{"192.0.2.1", []int{64500, 174}},
+		{"2001:db8::1", []int{1299}},
+		{"198.51.100.1", nil},
+	}
+	for _, tt := range tests {
+		asns, err := r.LookupOrigins(context.Background(), tt.ip)
+		if err != nil {
+			t.Errorf("%s: %v", tt.ip, err)
+			continue
+		}
+		if !reflect.DeepEqual(asns, tt.asns) {
+			t.Errorf("%s: expected %v but got %v", tt.ip, tt.asns, asns)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	l := &lg.ASNLookup{Registry: &fakeRegistry{}, Origin: r.LookupOrigins, CacheFile: filepath.Join(dir, "asn.cache.json")}
+	info, err := l.Origins(context.Background(), "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info) != 2 || info[1].ASN != 174 || info[1].Name != "COGENT-174" || info[1].Country != "US" {
+		t.Errorf("unexpected origins %+v", info)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.Origins(ctx, "192.0.2.1"); err == nil {
+		t.Error("expected the canceled lookup error")
+	}
+}
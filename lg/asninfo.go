@@ -0,0 +1,269 @@
+package lg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mehrdadrad/mylg/ripe"
+)
+
+// ASNInfo represents the AS name, holder, registry
+// country and the announced prefixes of an AS
+type ASNInfo struct {
+	ASN     int      `json:"asn"`
+	Name    string   `json:"name"`
+	Holder  string   `json:"holder"`
+	Country string   `json:"country"`
+	IPv4    []string `json:"ipv4_prefixes"`
+	IPv6    []string `json:"ipv6_prefixes"`
+}
+
+// ASNRegistry resolves the AS name and the registry country
+type ASNRegistry interface {
+	LookupRegistry(asn string) (name, country string, err error)
+}
+
+// ASNLookup resolves the AS information through Team Cymru DNS
+// and RIPEstat, the results are cached on disk
+type ASNLookup struct {
+	// Registry resolves the AS name and country, a
+	// CymruResolver is used if it's nil
+	Registry ASNRegistry
+	// RIPE returns the holder and the announced prefixes,
+	// ripe.LookupASN is used if it's nil
+	RIPE func(asn string) (ripe.RIPEASN, error)
+	// Origin returns the origin ASNs of an ip address, the
+	// CymruResolver origins are used if it's nil
+	Origin func(ctx context.Context, ip string) ([]int, error)
+	// CacheFile is asn.cache.json at the user config dir if it's empty
+	CacheFile string
+	// CacheTTL is the disk cache lifetime (default 24h)
+	CacheTTL time.Duration
+}
+
+// asnCacheEntry represents a cached AS information on disk
+type asnCacheEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Info      ASNInfo   `json:"info"`
+}
+
+var asnCacheMu sync.Mutex
+
+// NewASNLookup returns an AS information resolver
+func NewASNLookup() *ASNLookup {
+	return &ASNLookup{Registry: NewCymruResolver(), RIPE: ripe.LookupASN}
+}
+
+// ParseASN returns the AS number of 174, AS174 or as174
+func ParseASN(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	n, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(s), "AS"), 10, 32)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("invalid AS number: %s", s)
+	}
+	return int(n), nil
+}
+
+// Lookup returns the AS information, the partial result is returned
+// if one of the sources fails and it's not cached
+func (l *ASNLookup) Lookup(asn string) (ASNInfo, error) {
+	n, err := ParseASN(asn)
+	if err != nil {
+		return ASNInfo{}, err
+	}
+	key := strconv.Itoa(n)
+	if info, ok := l.cached(key); ok {
+		return info, nil
+	}
+
+	info := ASNInfo{ASN: n}
+	name, country, regErr := l.registry().LookupRegistry(key)
+	if regErr != nil {
+		getLogger().Debug("asn: registry lookup AS%d failed: %v", n, regErr)
+	}
+	info.Name, info.Country = name, country
+
+	r, ripeErr := l.ripe()(key)
+	if ripeErr != nil {
+		getLogger().Debug("asn: ripestat lookup AS%d failed: %v", n, ripeErr)
+	}
+	info.Holder = r.Holder
+	for _, p := range r.Prefixes {
+		if strings.Contains(p, ":") {
+			info.IPv6 = append(info.IPv6, p)
+		} else {
+			info.IPv4 = append(info.IPv4, p)
+		}
+	}
+
+	switch {
+	case regErr != nil && ripeErr != nil:
+		return info, fmt.Errorf("AS%d lookup failed: %v", n, ripeErr)
+	case regErr == nil && ripeErr == nil:
+		if err := l.cache(key, info); err != nil {
+			getLogger().Debug("asn: write cache failed: %s", err)
+		}
+	}
+	return info, nil
+}
+
+// Origins returns the AS information of the ip origin ASNs w/ the AS
+// names and countries of the registry, it's empty if the ip isn't
+// announced. the prefixes aren't looked up
+func (l *ASNLookup) Origins(ctx context.Context, ip string) ([]ASNInfo, error) {
+	asns, err := l.origin()(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+	var r []ASNInfo
+	for _, n := range asns {
+		if err := ctx.Err(); err != nil {
+			return r, err
+		}
+		info := ASNInfo{ASN: n}
+		if c, ok := l.cached(strconv.Itoa(n)); ok {
+			info.Name, info.Holder, info.Country = c.Name, c.Holder, c.Country
+		} else if name, country, err := l.registry().LookupRegistry(strconv.Itoa(n)); err == nil {
+			info.Name, info.Country = name, country
+		} else {
+			getLogger().Debug("asn: registry lookup AS%d failed: %v", n, err)
+		}
+		r = append(r, info)
+	}
+	return r, nil
+}
+
+// holder returns the holder or the AS name if it's unknown
+func (a ASNInfo) holder() string {
+	if a.Holder != "" {
+		return a.Holder
+	}
+	return a.Name
+}
+
+// String returns the AS information in human readable form
+func (a ASNInfo) String() string {
+	var lines []string
+	lines = append(lines, fmt.Sprintf("AS%d %s (%s)", a.ASN, a.Name, a.Country))
+	if a.Holder != "" {
+		lines = append(lines, "holder: "+a.Holder)
+	}
+	lines = append(lines, fmt.Sprintf("ipv4 prefixes (%d): %s", len(a.IPv4), strings.Join(a.IPv4, " ")))
+	lines = append(lines, fmt.Sprintf("ipv6 prefixes (%d): %s", len(a.IPv6), strings.Join(a.IPv6, " ")))
+	return strings.Join(lines, "\n")
+}
+
+// CSVHeader returns the ASNInfo CSV columns
+func (a ASNInfo) CSVHeader() []string {
+	return []string{"asn", "name", "holder", "country", "ipv4_prefixes", "ipv6_prefixes"}
+}
+
+// CSVRecord returns the ASNInfo CSV row, the prefixes are separated by space
+func (a ASNInfo) CSVRecord() []string {
+	return []string{
+		strconv.Itoa(a.ASN),
+		a.Name,
+		a.Holder,
+		a.Country,
+		strings.Join(a.IPv4, " "),
+		strings.Join(a.IPv6, " "),
+	}
+}
+
+func (l *ASNLookup) registry() ASNRegistry {
+	if l.Registry != nil {
+		return l.Registry
+	}
+	return NewCymruResolver()
+}
+
+func (l *ASNLookup) origin() func(context.Context, string) ([]int, error) {
+	if l.Origin != nil {
+		return l.Origin
+	}
+	return NewCymruResolver().LookupOrigins
+}
+
+func (l *ASNLookup) ripe() func(string) (ripe.RIPEASN, error) {
+	if l.RIPE != nil {
+		return l.RIPE
+	}
+	return ripe.LookupASN
+}
+
+func (l *ASNLookup) cacheFile() string {
+	if l.CacheFile != "" {
+		return l.CacheFile
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "mylg", "asn.cache.json")
+}
+
+func (l *ASNLookup) cacheTTL() time.Duration {
+	if l.CacheTTL > 0 {
+		return l.CacheTTL
+	}
+	return defaultCacheTTL
+}
+
+// cached returns the AS information from the disk cache if it's not expired
+func (l *ASNLookup) cached(asn string) (ASNInfo, bool) {
+	asnCacheMu.Lock()
+	defer asnCacheMu.Unlock()
+	entries, err := readASNCache(l.cacheFile())
+	if err != nil {
+		return ASNInfo{}, false
+	}
+	e, ok := entries[asn]
+	if !ok || time.Since(e.Timestamp) > l.cacheTTL() {
+		return ASNInfo{}, false
+	}
+	return e.Info, true
+}
+
+// cache saves the AS information to the disk cache
+func (l *ASNLookup) cache(asn string, info ASNInfo) error {
+	asnCacheMu.Lock()
+	defer asnCacheMu.Unlock()
+	file := l.cacheFile()
+	entries, err := readASNCache(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		getLogger().Debug("asn: read cache failed, it's rewritten: %s", err)
+	}
+	if entries == nil {
+		entries = map[string]asnCacheEntry{}
+	}
+	entries[asn] = asnCacheEntry{Timestamp: time.Now(), Info: info}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, b, 0600)
+}
+
+func readASNCache(file string) (map[string]asnCacheEntry, error) {
+	var entries map[string]asnCacheEntry
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,128 @@
+package lg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// BatchResult represents the command result of a batch target
+type BatchResult struct {
+	Target string
+	Result
+}
+
+// BatchSummary represents the succeeded and failed targets of a batch
+type BatchSummary struct {
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// ReadTargets reads the targets, one per line. the blank
+// lines and the comments (starting w/ #) are skipped
+func ReadTargets(r io.Reader) ([]string, error) {
+	var (
+		targets []string
+		s       = bufio.NewScanner(r)
+	)
+	for s.Scan() {
+		l := s.Text()
+		if i := strings.Index(l, "#"); i >= 0 {
+			l = l[:i]
+		}
+		if l = strings.TrimSpace(l); l != "" {
+			targets = append(targets, l)
+		}
+	}
+	return targets, s.Err()
+}
+
+// Batch runs the command against the targets w/ up to workers (default 5)
+// concurrent requests, each request is sent through a copy of the provider
+// so the node and the options are shared. the providers which can't be
+// copied run the targets one by one, and the Cogent requests are throttled
+// by the shared rate limiter anyway. the results are streamed once they're
+// done and the trace and bgp lines are collected into Text
+func Batch(ctx context.Context, p LookingGlass, cmd Command, targets []string, workers int) chan BatchResult {
+	var (
+		results = make(chan BatchResult)
+		jobs    = make(chan string)
+		wg      sync.WaitGroup
+	)
+	if workers < 1 {
+		workers = 5
+	}
+	if _, ok := copyProvider(p); !ok {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				q, _ := copyProvider(p)
+				results <- BatchResult{Target: target, Result: collect(ctx, q, cmd, target)}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, target := range targets {
+			select {
+			case jobs <- target:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// copyProvider returns a copy of the provider for the concurrent
+// requests, it returns the provider itself if it can't be copied
+func copyProvider(p LookingGlass) (LookingGlass, bool) {
+	c, ok := p.(*Cogent)
+	if !ok {
+		return p, false
+	}
+	return c.clone(), true
+}
+
+// collect runs the command and collects the trace and bgp lines into Text
+func collect(ctx context.Context, p LookingGlass, cmd Command, host string) Result {
+	r, err := RunContext(ctx, p, cmd, host)
+	if err == nil && r.Lines != nil {
+		var lines []string
+		for l := range r.Lines {
+			lines = append(lines, l)
+		}
+		r.Text, r.Lines = strings.Join(lines, "\n"), nil
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	r.Cancel()
+	r.Err = err
+	return r
+}
+
+// Add counts the result as succeeded or failed
+func (s *BatchSummary) Add(r BatchResult) {
+	if r.Err != nil {
+		s.Failed++
+	} else {
+		s.Succeeded++
+	}
+}
+
+// String returns the summary
+func (s BatchSummary) String() string {
+	return fmt.Sprintf("%d targets, %d succeeded, %d failed", s.Succeeded+s.Failed, s.Succeeded, s.Failed)
+}
@@ -0,0 +1,211 @@
+package lg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// BGPRoute represents a parsed looking glass bgp route
+type BGPRoute struct {
+	Prefix      string   `json:"prefix"`
+	NextHop     string   `json:"next_hop"`
+	ASPath      []string `json:"as_path"`
+	Communities []string `json:"communities"`
+	Origin      string   `json:"origin"`
+	Best        bool     `json:"best"`
+
+	// CommunityDescriptions is set by DescribeCommunities, the
+	// unknown communities are kept as they are
+	CommunityDescriptions []string `json:"community_descriptions,omitempty"`
+
+	// Prepends is the repeat count beyond one of the prepended
+	// asns (consecutive duplicates of the as-path)
+	Prepends map[string]int `json:"prepends,omitempty"`
+}
+
+var (
+	bgpEntryRe     = regexp.MustCompile(`(?i)BGP routing table entry for\s+(\S+?),?(\s|$)`)
+	bgpASPathRe    = regexp.MustCompile(`^\s+((\d+\s*)+|Local)(,.*)?$`)
+	bgpNextHopRe   = regexp.MustCompile(`^\s+([\da-fA-F.:]+)\s+(from|\()`)
+	bgpOriginRe    = regexp.MustCompile(`(?i)^\s+Origin\s+(IGP|EGP|incomplete)`)
+	bgpCommunityRe = regexp.MustCompile(`(?i)^\s+Community:\s+(.*)$`)
+	bgpOrigins     = map[string]string{"i": "IGP", "e": "EGP", "?": "incomplete"}
+)
+
+// String returns the route in one line
+func (r BGPRoute) String() string {
+	var s = "  "
+	if r.Best {
+		s = "*>"
+	}
+	s = fmt.Sprintf("%s %s via %s as-path [%s] origin %s", s, r.Prefix, r.NextHop,
+		strings.Join(r.ASPath, " "), r.Origin)
+	if len(r.Communities) > 0 {
+		s += " community " + strings.Join(r.Communities, " ")
+	}
+	if r.HasPrepend() {
+		var p []string
+		for asn, n := range prepends(r.ASPath) {
+			p = append(p, fmt.Sprintf("%sx%d", asn, n+1))
+		}
+		sort.Strings(p)
+		s += " prepend " + strings.Join(p, " ")
+	}
+	return s
+}
+
+// HasPrepend returns true if any of the asns is prepended
+func (r BGPRoute) HasPrepend() bool {
+	for i := 1; i < len(r.ASPath); i++ {
+		if r.ASPath[i] == r.ASPath[i-1] {
+			return true
+		}
+	}
+	return false
+}
+
+// prepends returns the repeat count beyond one of the
+// consecutive duplicate asns, it's nil w/o any prepend
+func prepends(path []string) map[string]int {
+	var m map[string]int
+	for i := 1; i < len(path); i++ {
+		if path[i] != path[i-1] {
+			continue
+		}
+		if m == nil {
+			m = map[string]int{}
+		}
+		m[path[i]]++
+	}
+	return m
+}
+
+// parseBGPRoutes parses the looking glass bgp dump, it supports the
+// table (show ip bgp) and the detail (show ip bgp prefix) formats,
+// the entry and the as-path lines of the detail format are matched
+// by entryRe and pathRe
+func parseBGPRoutes(rd io.Reader, entryRe, pathRe *regexp.Regexp) ([]BGPRoute, error) {
+	var (
+		routes []BGPRoute
+		route  *BGPRoute
+		prefix string
+		cols   []int
+		peers  bool
+	)
+
+	scanner := bufio.NewScanner(rd)
+	for scanner.Scan() {
+		l := cleanLine(scanner.Text())
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		// update-groups numbers look like an as-path
+		if peers {
+			peers = false
+			continue
+		}
+		if strings.Contains(strings.ToLower(l), "advertised to") {
+			peers = true
+			continue
+		}
+		// table format
+		if c := bgpTableColumns(l); c != nil {
+			cols = c
+			continue
+		}
+		if cols != nil {
+			if r, ok := parseBGPTableRow(l, cols, &prefix); ok {
+				routes = append(routes, r)
+			}
+			continue
+		}
+		// detail format
+		if m := entryRe.FindStringSubmatch(l); len(m) > 1 {
+			prefix = m[1]
+			continue
+		}
+		if prefix == "" {
+			continue
+		}
+		switch {
+		case bgpNextHopRe.MatchString(l) && route != nil && route.NextHop == "":
+			route.NextHop = bgpNextHopRe.FindStringSubmatch(l)[1]
+		case bgpOriginRe.MatchString(l) && route != nil:
+			route.Origin = bgpOriginRe.FindStringSubmatch(l)[1]
+			route.Best = strings.Contains(l, "best")
+		case bgpCommunityRe.MatchString(l) && route != nil:
+			route.Communities = strings.Fields(bgpCommunityRe.FindStringSubmatch(l)[1])
+		case pathRe.MatchString(l):
+			routes = append(routes, BGPRoute{Prefix: prefix})
+			route = &routes[len(routes)-1]
+			if p := pathRe.FindStringSubmatch(l)[1]; p != "Local" {
+				route.ASPath = strings.Fields(p)
+			}
+		}
+	}
+	for i := range routes {
+		routes[i].Prepends = prepends(routes[i].ASPath)
+	}
+	return routes, scanner.Err()
+}
+
+// bgpTableColumns returns the network, next hop and path
+// columns positions if the line is the table header
+func bgpTableColumns(l string) []int {
+	var cols []int
+	for _, h := range []string{"Network", "Next Hop", "Path"} {
+		i := strings.Index(l, h)
+		if i < 0 {
+			return nil
+		}
+		cols = append(cols, i)
+	}
+	return cols
+}
+
+// parseBGPTableRow parses a table row, the prefix is kept
+// for the next rows which don't repeat the network
+func parseBGPTableRow(l string, cols []int, prefix *string) (BGPRoute, bool) {
+	var r BGPRoute
+
+	if len(l) <= cols[0] || !strings.ContainsAny(l[:cols[0]], "*") {
+		return r, false
+	}
+	status := l[:cols[0]]
+	if n := strings.TrimSpace(column(l, cols[0], cols[1])); n != "" {
+		*prefix = strings.Fields(n)[0]
+	}
+	// the network is too long and the row continues at the next line
+	if len(l) <= cols[1] {
+		return r, false
+	}
+	if f := strings.Fields(column(l, cols[1], cols[2])); len(f) > 0 {
+		r.NextHop = f[0]
+	}
+	path := strings.Fields(column(l, cols[2], len(l)))
+	if n := len(path); n > 0 {
+		if o, ok := bgpOrigins[path[n-1]]; ok {
+			r.Origin = o
+			path = path[:n-1]
+		}
+	}
+	r.Prefix = *prefix
+	r.ASPath = path
+	r.Best = strings.Contains(status, ">")
+	return r, true
+}
+
+// column returns the l[i:j] safely
+func column(l string, i, j int) string {
+	if i >= len(l) {
+		return ""
+	}
+	if j > len(l) {
+		j = len(l)
+	}
+	return l[i:j]
+}
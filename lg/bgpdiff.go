@@ -0,0 +1,171 @@
+package lg
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// BGPAttrDiff represents a best path attribute which differs between the nodes
+type BGPAttrDiff struct {
+	Attr string `json:"attribute"`
+	A    string `json:"a"`
+	B    string `json:"b"`
+}
+
+// BGPComparison represents the routes of a prefix from two nodes, Added
+// and Removed are the paths (by next-hop and as-path) which are only seen
+// from B and A, Changed is the best paths attributes which differ
+type BGPComparison struct {
+	Prefix  string        `json:"prefix"`
+	NodeA   string        `json:"node_a"`
+	NodeB   string        `json:"node_b"`
+	BestA   *BGPRoute     `json:"best_a,omitempty"`
+	BestB   *BGPRoute     `json:"best_b,omitempty"`
+	Added   []BGPRoute    `json:"added,omitempty"`
+	Removed []BGPRoute    `json:"removed,omitempty"`
+	Changed []BGPAttrDiff `json:"changed,omitempty"`
+}
+
+// BGPDiff fetches the routes of the prefix from the both nodes concurrently
+// and compares them, the nodes can be any unique part of the bgp node name
+func (p *Cogent) BGPDiff(nodeA, nodeB, prefix string) (BGPComparison, error) {
+	return p.BGPDiffContext(context.Background(), nodeA, nodeB, prefix)
+}
+
+// BGPDiffContext is like BGPDiff but the requests are bound to ctx
+func (p *Cogent) BGPDiffContext(ctx context.Context, nodeA, nodeB, prefix string) (BGPComparison, error) {
+	var (
+		nodes  [2]string
+		routes [2][]BGPRoute
+		errs   [2]error
+		wg     sync.WaitGroup
+	)
+	for i, query := range []string{nodeA, nodeB} {
+		n, err := bgpNode(query)
+		if err != nil {
+			return BGPComparison{}, err
+		}
+		nodes[i] = n
+	}
+	for i := range nodes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			q := p.clone()
+			q.Host, q.Node = prefix, nodes[i]
+			routes[i], errs[i] = q.bgpRoutes(ctx)
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return BGPComparison{}, fmt.Errorf("%s: %w", nodes[i], err)
+		}
+	}
+	return compareBGPRoutes(prefix, nodes[0], nodes[1], routes[0], routes[1]), nil
+}
+
+// bgpNode returns the bgp node which is matched by the query
+func bgpNode(query string) (string, error) {
+	bgp := loadedNodes().BGPNodes
+	nodes := matchNodes(nodeNames(bgp), bgp, query)
+	switch len(nodes) {
+	case 0:
+		return "", fmt.Errorf("%w: bgp on %s", ErrUnsupportedCommand, query)
+	case 1:
+		return nodes[0], nil
+	}
+	return "", fmt.Errorf("the bgp node %q is ambiguous: %s", query, strings.Join(nodes, ", "))
+}
+
+// compareBGPRoutes returns the comparison of the routes from a and b
+func compareBGPRoutes(prefix, nodeA, nodeB string, a, b []BGPRoute) BGPComparison {
+	c := BGPComparison{Prefix: prefix, NodeA: nodeA, NodeB: nodeB, BestA: bestRoute(a), BestB: bestRoute(b)}
+	key := func(r BGPRoute) string {
+		return r.Prefix + " " + r.NextHop + " " + strings.Join(r.ASPath, " ")
+	}
+	seen := map[string]bool{}
+	for _, r := range a {
+		seen[key(r)] = true
+	}
+	for _, r := range b {
+		if !seen[key(r)] {
+			c.Added = append(c.Added, r)
+		}
+	}
+	seen = map[string]bool{}
+	for _, r := range b {
+		seen[key(r)] = true
+	}
+	for _, r := range a {
+		if !seen[key(r)] {
+			c.Removed = append(c.Removed, r)
+		}
+	}
+	if c.BestA == nil || c.BestB == nil {
+		return c
+	}
+	for _, d := range []BGPAttrDiff{
+		{"prefix", c.BestA.Prefix, c.BestB.Prefix},
+		{"next_hop", c.BestA.NextHop, c.BestB.NextHop},
+		{"as_path", strings.Join(c.BestA.ASPath, " "), strings.Join(c.BestB.ASPath, " ")},
+		{"as_path_length", strconv.Itoa(len(c.BestA.ASPath)), strconv.Itoa(len(c.BestB.ASPath))},
+		{"origin", c.BestA.Origin, c.BestB.Origin},
+		{"communities", strings.Join(c.BestA.Communities, " "), strings.Join(c.BestB.Communities, " ")},
+	} {
+		if d.A != d.B {
+			c.Changed = append(c.Changed, d)
+		}
+	}
+	return c
+}
+
+// bestRoute returns the best route or the first one if none is marked
+func bestRoute(routes []BGPRoute) *BGPRoute {
+	for i := range routes {
+		if routes[i].Best {
+			return &routes[i]
+		}
+	}
+	if len(routes) > 0 {
+		return &routes[0]
+	}
+	return nil
+}
+
+// Equal returns true if the best paths are the same
+func (c BGPComparison) Equal() bool {
+	return len(c.Changed) == 0 && (c.BestA == nil) == (c.BestB == nil)
+}
+
+// String returns the summary of where the paths diverge
+func (c BGPComparison) String() string {
+	var (
+		b    strings.Builder
+		best = func(r *BGPRoute) string {
+			if r == nil {
+				return "no route"
+			}
+			return strings.TrimSpace(r.String())
+		}
+	)
+	fmt.Fprintf(&b, "%s\n", c.Prefix)
+	fmt.Fprintf(&b, "  A %s: %s\n", c.NodeA, best(c.BestA))
+	fmt.Fprintf(&b, "  B %s: %s\n", c.NodeB, best(c.BestB))
+	if c.Equal() {
+		b.WriteString("the best paths are the same\n")
+	}
+	for _, d := range c.Changed {
+		fmt.Fprintf(&b, "~ %s: %s => %s\n", d.Attr, d.A, d.B)
+	}
+	for _, r := range c.Removed {
+		fmt.Fprintf(&b, "- %s\n", strings.TrimSpace(r.String()))
+	}
+	for _, r := range c.Added {
+		fmt.Fprintf(&b, "+ %s\n", strings.TrimSpace(r.String()))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
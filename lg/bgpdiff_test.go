@@ -0,0 +1,70 @@
+package lg_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestCogentBGPDiff(t *testing.T) {
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+
+	const (
+		atla = `<pre>BGP routing table entry for 8.8.8.0/24
+  15169
+    154.54.1.1 from 154.54.1.1 (66.28.1.1)
+      Origin IGP, metric 0, localpref 100, valid, external, best
+      Community: 174:21001
+</pre>`
+		amst = `<pre>BGP routing table entry for 8.8.8.0/24
+  3356 15169
+    130.117.1.1 from 130.117.1.1 (66.28.1.2)
+      Origin IGP, metric 0, localpref 100, valid, external, best
+      Community: 174:21001 174:22010
+  15169
+    154.54.1.1 from 154.54.1.1 (66.28.1.1)
+      Origin IGP, metric 0, localpref 100, valid, external
+      Community: 174:21001
+</pre>`
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("LOC") == "amst" {
+			fmt.Fprint(w, amst)
+			return
+		}
+		fmt.Fprint(w, atla)
+	}))
+	defer ts.Close()
+	c.BaseURL = ts.URL
+	c.DisableRetry = true
+
+	d, err := c.BGPDiff("atlanta", "amst", "8.8.8.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.NodeA != "US - Atlanta" || d.NodeB != "NL - Amsterdam" {
+		t.Error("unexpected nodes", d.NodeA, d.NodeB)
+	}
+	want := []lg.BGPAttrDiff{
+		{Attr: "next_hop", A: "154.54.1.1", B: "130.117.1.1"},
+		{Attr: "as_path", A: "15169", B: "3356 15169"},
+		{Attr: "as_path_length", A: "1", B: "2"},
+		{Attr: "communities", A: "174:21001", B: "174:21001 174:22010"},
+	}
+	if !reflect.DeepEqual(d.Changed, want) {
+		t.Errorf("expected %+v but got %+v", want, d.Changed)
+	}
+	if len(d.Added) != 1 || len(d.Removed) != 0 || d.Equal() {
+		t.Errorf("unexpected comparison %+v", d)
+	}
+
+	if _, err := c.BGPDiff("atlanta", "tokyo", "8.8.8.0/24"); !errors.Is(err, lg.ErrUnsupportedCommand) {
+		t.Error("expected unsupported bgp node error but got", err)
+	}
+}
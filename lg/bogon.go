@@ -0,0 +1,80 @@
+package lg
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Bogon represents a range which shouldn't be routed on the internet
+type Bogon struct {
+	Net  *net.IPNet
+	Name string
+}
+
+// Bogons is the bogon and martian table, private, loopback,
+// link-local, CGNAT, documentation and reserved ranges
+var Bogons = []Bogon{
+	bogon("0.0.0.0/8", "this network"),
+	bogon("10.0.0.0/8", "private (RFC1918)"),
+	bogon("100.64.0.0/10", "CGNAT (RFC6598)"),
+	bogon("127.0.0.0/8", "loopback"),
+	bogon("169.254.0.0/16", "link-local"),
+	bogon("172.16.0.0/12", "private (RFC1918)"),
+	bogon("192.0.0.0/24", "IETF protocol assignments"),
+	bogon("192.0.2.0/24", "documentation (TEST-NET-1)"),
+	bogon("192.168.0.0/16", "private (RFC1918)"),
+	bogon("198.18.0.0/15", "benchmarking"),
+	bogon("198.51.100.0/24", "documentation (TEST-NET-2)"),
+	bogon("203.0.113.0/24", "documentation (TEST-NET-3)"),
+	bogon("224.0.0.0/4", "multicast"),
+	bogon("240.0.0.0/4", "reserved"),
+	bogon("::/128", "unspecified"),
+	bogon("::1/128", "loopback"),
+	bogon("100::/64", "discard only"),
+	bogon("2001:db8::/32", "documentation"),
+	bogon("fc00::/7", "unique local"),
+	bogon("fe80::/10", "link-local"),
+	bogon("ff00::/8", "multicast"),
+}
+
+func bogon(cidr, name string) Bogon {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return Bogon{Net: n, Name: name}
+}
+
+// IsBogon returns true if the ip is in the bogon table
+func IsBogon(ip net.IP) bool {
+	_, ok := LookupBogon(ip)
+	return ok
+}
+
+// LookupBogon returns the bogon range of the ip
+func LookupBogon(ip net.IP) (Bogon, bool) {
+	if ip == nil {
+		return Bogon{}, false
+	}
+	for _, b := range Bogons {
+		if b.Net.Contains(ip) {
+			return b, true
+		}
+	}
+	return Bogon{}, false
+}
+
+// CheckBogon returns an error if the target ip or prefix is a bogon,
+// the host names aren't resolved
+func CheckBogon(target string) error {
+	s := strings.TrimSpace(target)
+	if ip, _, err := net.ParseCIDR(s); err == nil {
+		s = ip.String()
+	}
+	ip := net.ParseIP(s)
+	if b, ok := LookupBogon(ip); ok {
+		return fmt.Errorf("%s is a bogon address (%s %s), a public looking glass can't reach it", target, b.Net, b.Name)
+	}
+	return nil
+}
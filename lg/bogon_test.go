@@ -0,0 +1,51 @@
+package lg_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestIsBogon(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.1.2.3", true},
+		{"172.31.255.255", true},
+		{"172.32.0.1", false},
+		{"192.168.1.1", true},
+		{"127.0.0.1", true},
+		{"169.254.10.1", true},
+		{"100.64.0.1", true},
+		{"100.128.0.1", false},
+		{"192.0.2.10", true},
+		{"198.51.100.1", true},
+		{"203.0.113.7", true},
+		{"8.8.8.8", false},
+		{"::1", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"2001:db8::1", true},
+		{"2001:4860:4860::8888", false},
+	}
+	for _, tt := range tests {
+		if got := lg.IsBogon(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("%s: expected bogon %t but got %t", tt.ip, tt.want, got)
+		}
+	}
+}
+
+func TestCheckBogon(t *testing.T) {
+	for _, target := range []string{"10.0.0.1", "192.168.0.0/24", "::1"} {
+		if lg.CheckBogon(target) == nil {
+			t.Error("expected bogon error for", target)
+		}
+	}
+	for _, target := range []string{"8.8.8.8", "8.8.8.0/24", "google.com", ""} {
+		if err := lg.CheckBogon(target); err != nil {
+			t.Error("unexpected error:", err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package lg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// defaultCacheTTL is the nodes disk cache lifetime
+const defaultCacheTTL = 24 * time.Hour
+
+// nodesCache represents the looking glass nodes on disk
+type nodesCache struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Nodes     map[string]string `json:"nodes"`
+	BGPNodes  map[string]string `json:"bgp_nodes,omitempty"`
+	Sources   map[string]string `json:"sources,omitempty"`
+
+	// Fields is the scraped form field names
+	Fields *formFields `json:"form_fields,omitempty"`
+}
+
+// cacheFile returns the default cache file path for a provider
+func cacheFile(provider string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "mylg", provider+".nodes.json")
+}
+
+// readNodesCache loads the nodes from the cache file if it's not expired
+func readNodesCache(file string, ttl time.Duration) (nodesCache, error) {
+	var c nodesCache
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return c, err
+	}
+	if err = json.Unmarshal(b, &c); err != nil {
+		return c, err
+	}
+	if time.Since(c.Timestamp) > ttl {
+		return c, fmt.Errorf("nodes cache expired at %s", c.Timestamp.Add(ttl))
+	}
+	if len(c.Nodes) < 1 {
+		return c, fmt.Errorf("nodes cache is empty")
+	}
+	return c, nil
+}
+
+// writeNodesCache saves the nodes to the cache file
+func writeNodesCache(file string, c nodesCache) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	c.Timestamp = time.Now()
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, b, 0600)
+}
+
+// nodeNames returns the sorted node names
+func nodeNames(nodes map[string]string) []string {
+	var names []string
+	for node := range nodes {
+		names = append(names, node)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,40 @@
+package lg
+
+import "sort"
+
+// NodeCataloger is implemented by the providers which
+// can describe the nodes
+type NodeCataloger interface {
+	NodeCatalog() []NodeInfo
+}
+
+// NodeInfo represents a node of the catalog, Code is the
+// location code of the looking glass form
+type NodeInfo struct {
+	Name         string `json:"name"`
+	Code         string `json:"code"`
+	Region       string `json:"region"`
+	SupportsPing bool   `json:"supports_ping"`
+	SupportsBGP  bool   `json:"supports_bgp"`
+}
+
+// NodeCatalog returns the ping/trace and the bgp nodes sorted by the
+// name, the nodes are loaded by GetNodes. the nodes which offer the
+// both have the ping/trace location code
+func (p *Cogent) NodeCatalog() []NodeInfo {
+	var (
+		n       = loadedNodes()
+		catalog = make([]NodeInfo, 0, len(n.Nodes)+len(n.BGPNodes))
+	)
+	for name, code := range n.Nodes {
+		_, bgp := n.BGPNodes[name]
+		catalog = append(catalog, NodeInfo{Name: name, Code: code, Region: nodeRegion(name), SupportsPing: true, SupportsBGP: bgp})
+	}
+	for name, code := range n.BGPNodes {
+		if _, ok := n.Nodes[name]; !ok {
+			catalog = append(catalog, NodeInfo{Name: name, Code: code, Region: nodeRegion(name), SupportsBGP: true})
+		}
+	}
+	sort.Slice(catalog, func(i, j int) bool { return catalog[i].Name < catalog[j].Name })
+	return catalog
+}
@@ -4,13 +4,21 @@ package lg
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // A Cogent represents a telia looking glass request
@@ -19,178 +27,1134 @@ type Cogent struct {
 	IPv   string
 	Node  string
 	Nodes []string
+	// BaseURL is the looking glass address, cogentLGURL
+	// is used if it's empty
+	BaseURL string
+	// Client is used for the looking glass requests, the
+	// package http client (see SetHTTPClient) is used if it's nil
+	Client *http.Client
+	// ProxyURL routes the requests through the http, https or
+	// socks5 proxy regardless of the environment, the environment
+	// proxy (HTTP_PROXY, HTTPS_PROXY) is used if it's empty
+	ProxyURL string
+	// LocalAddr is the local source address of the looking glass
+	// requests on the multi-homed hosts, it should be assigned to a
+	// local interface. it's not the probe source (see Source)
+	LocalAddr string
+	// CacheFile keeps the fetched nodes on disk, it's
+	// cogent.nodes.json at the user config dir if it's empty
+	CacheFile string
+	// CacheTTL is the disk cache lifetime (default 24h)
+	CacheTTL time.Duration
+	// SnapshotFile replaces the bundled nodes snapshot which is
+	// used once the disk cache and the live fetch fail
+	SnapshotFile string
+	// Workers is the max concurrent requests of PingAll (default 5)
+	Workers int
+	// Retries is the max retries on 5xx and network errors (default 3)
+	Retries int
+	// DisableRetry turns off the retries
+	DisableRetry bool
+	// MaxRedirects is the max redirects of the form requests (default 3),
+	// the form is posted again to the new location on the same host w/o
+	// HTTPS to HTTP downgrade. the negative value disables them
+	MaxRedirects int
+	// Count is the ping packets count (1-10), the looking
+	// glass default (5) is used if it's zero
+	Count int
+	// Size is the ping payload size in bytes (1-1472), the
+	// looking glass default (56) is used if it's zero
+	Size int
+	// DryRun makes Ping, Trace and BGP return the description
+	// of the request instead of sending it
+	DryRun bool
+	// KeepRaw keeps the last raw response (up to 1MB)
+	// of Ping, Trace, BGP and FetchNodes, see LastRaw
+	KeepRaw bool
+	// Source is the probe source (name or value) of Ping and Trace,
+	// it's ignored w/ a warning if the looking glass doesn't offer it
+	Source string
+	// MaxHops stops the trace after the hops, and WaitTime cuts the
+	// trace off after the total duration. the looking glass form
+	// doesn't have them so they're enforced while the trace is streamed
+	MaxHops  int
+	WaitTime time.Duration
+	// Parser parses the responses, the package parser (see
+	// SetCogentParser and cogent_patterns config) is used if it's nil
+	Parser *CogentParser
+	// Failover makes Ping and Trace use the next healthy node of
+	// the same region if the node is unhealthy, see ServedNode
+	Failover bool
+	// ResolveLocal makes Ping and Trace resolve the hostname locally
+	// (see ResolveHost) and send the address instead of the hostname,
+	// ResolveIndex picks the address (1-based) and the first one is
+	// used if it's zero
+	ResolveLocal bool
+	ResolveIndex int
+
+	raw      *rawBuffer
+	served   string
+	headers  http.Header
+	resolved string
+}
+
+const cogentLGURL = "https://www.cogentco.com/lookingglass.php"
+
+var (
+	_ LookingGlass        = (*Cogent)(nil)
+	_ NodeMatcher         = (*Cogent)(nil)
+	_ NodeCompleter       = (*Cogent)(nil)
+	_ NodeGrouper         = (*Cogent)(nil)
+	_ RawRecorder         = (*Cogent)(nil)
+	_ NodesContext        = (*Cogent)(nil)
+	_ NearestNodeSelector = (*Cogent)(nil)
+	_ NodeHealthChecker   = (*Cogent)(nil)
+	_ NodeCataloger       = (*Cogent)(nil)
+)
+
+// nearestAnchor is pinged by NearestNode if the host is empty
+const nearestAnchor = "8.8.8.8"
+
+// cogentNodesTimeout bounds the nodes fetch of GetNodes
+const cogentNodesTimeout = 10 * time.Second
+
+// defaultMaxRedirects is the max redirects of the form requests
+const defaultMaxRedirects = 3
+
+// errRedirect is wrapped by the refused redirect errors, they
+// aren't retried
+var errRedirect = errors.New("cogent: redirect refused")
+
+// CogentCmd represents a Cogent looking glass command code (CMD)
+type CogentCmd string
+
+// the Cogent command codes
+const (
+	CmdPing4    CogentCmd = "P4"
+	CmdPing6    CogentCmd = "P6"
+	CmdTrace4   CogentCmd = "T4"
+	CmdTrace6   CogentCmd = "T6"
+	CmdBGPQuery CogentCmd = "BGP"
+)
+
+// commandFor returns the Cogent command code of the kind and ip version
+func commandFor(kind Command, ipv string) CogentCmd {
+	switch {
+	case kind == CmdBGP:
+		return CmdBGPQuery
+	case kind == CmdTrace && ipv == "ipv6":
+		return CmdTrace6
+	case kind == CmdTrace:
+		return CmdTrace4
+	case ipv == "ipv6":
+		return CmdPing6
+	}
+	return CmdPing4
 }
 
+// the ping options accepted ranges
+const (
+	cogentMaxCount = 10
+	cogentMaxSize  = 1472
+)
+
 var (
 	cogentNodes       = map[string]string{}
 	cogentBGPNodes    = map[string]string{}
+	cogentSources     = map[string]string{}
 	cogentDefaultNode = "US - Los Angeles"
+
+	// cogentNodesMu guards the nodes maps, the maps are
+	// replaced (see useNodes) but they aren't modified
+	cogentNodesMu sync.RWMutex
 )
 
-// Set configures host and ip version
+// cogentOptionRe matches the node name and code options, the
+// name can have any character but a double quote, e.g. D.C.
+var cogentOptionRe = regexp.MustCompile(`(?is)Option\("([^"]+)","([\w|\d]+)"`)
+
+// cogentSourceRe matches the probe source options (SRC select)
+var cogentSourceRe = regexp.MustCompile(`(?is)SRC\.options\[\d+\]\s*=\s*new\s+Option\("([^"]+)","([^"]+)"\);?`)
+
+func init() {
+	Register("cogent", func() LookingGlass { return new(Cogent) })
+}
+
+// Set configures host and ip version, the ip version
+// is detected if the host is an ip address literal
 func (p *Cogent) Set(host, version string) {
 	p.Host = host
-	p.IPv = version
+	p.IPv = ipVersion(version)
+	if ip := net.ParseIP(host); ip != nil {
+		p.IPv = "ipv4"
+		if ip.To4() == nil {
+			p.IPv = "ipv6"
+		}
+	}
 	if p.Node == "" {
-		p.Node = cogentDefaultNode
+		p.Node = defaultNode("cogent", cogentDefaultNode)
 	}
 }
 
 // GetDefaultNode returns telia default node
 func (p *Cogent) GetDefaultNode() string {
-	return cogentDefaultNode
+	return defaultNode("cogent", cogentDefaultNode)
+}
+
+// LastRaw returns the last raw response if KeepRaw is set
+func (p *Cogent) LastRaw() string {
+	if p.raw == nil {
+		return ""
+	}
+	return p.raw.String()
 }
 
-// GetNodes returns all Cogent nodes (US and International)
+// GetNodesByRegion returns the Cogent nodes grouped by region (US,
+// Europe, Asia, ...), the nodes w/ unknown prefix are in OtherRegion
+func (p *Cogent) GetNodesByRegion() map[string][]string {
+	return groupByRegion(p.GetNodes())
+}
+
+// GetNodes returns all Cogent nodes (US and International),
+// the fetch is bounded by cogentNodesTimeout
 func (p *Cogent) GetNodes() []string {
+	ctx, cancel := context.WithTimeout(context.Background(), cogentNodesTimeout)
+	defer cancel()
+	nodes, err := p.GetNodesContext(ctx)
+	if err != nil {
+		getLogger().Warn("cogent: %s", err)
+	}
+	return nodes
+}
+
+// GetNodesContext is like GetNodes but the fetch is bound to ctx, the
+// expired disk cache or else the bundled snapshot is used w/ the error
+// if the fetch fails, the snapshot error wraps ErrStaleNodes
+func (p *Cogent) GetNodesContext(ctx context.Context) ([]string, error) {
 	// Memory cache
 	if len(p.Nodes) > 1 {
-		return p.Nodes
+		return p.Nodes, nil
 	}
-	cogentNodes, cogentBGPNodes = p.FetchNodes()
-	var nodes []string
-	for node := range cogentNodes {
-		nodes = append(nodes, node)
+	// Disk cache
+	c, err := readNodesCache(p.cacheFile(), p.cacheTTL())
+	if err == nil {
+		p.useNodes(c)
+		return p.Nodes, nil
 	}
-	sort.Strings(nodes)
-	p.Nodes = nodes
-	return nodes
+	nodes, err := p.refreshNodes(ctx)
+	if err != nil && len(nodes) == 0 && len(c.Nodes) > 0 {
+		p.useNodes(c)
+		return p.Nodes, fmt.Errorf("%w (the expired nodes cache is used)", err)
+	}
+	if err != nil && len(nodes) == 0 {
+		if s, sErr := p.snapshot(); sErr == nil {
+			p.useNodes(s)
+			return p.Nodes, fmt.Errorf("%w (%s): %v", ErrStaleNodes, s.Timestamp.Format("2006-01-02"), err)
+		}
+	}
+	return nodes, err
 }
 
-// ChangeNode set new requested node
-func (p *Cogent) ChangeNode(node string) bool {
-	// Validate
-	for _, n := range p.Nodes {
-		if node == n {
-			p.Node = node
-			return true
+// RefreshNodes fetches the nodes from Cogent regardless of
+// the caches and rewrites the disk cache, the current nodes
+// are kept if the fetch fails
+func (p *Cogent) RefreshNodes() ([]string, error) {
+	return p.refreshNodes(context.Background())
+}
+
+func (p *Cogent) refreshNodes(ctx context.Context) ([]string, error) {
+	c, err := p.fetchNodes(ctx, nil)
+	if err != nil {
+		return p.Nodes, err
+	}
+	p.useNodes(c)
+	if len(c.Nodes) > 0 {
+		err := writeNodesCache(p.cacheFile(), c)
+		if err != nil {
+			getLogger().Debug("cogent: write nodes cache failed: %s", err)
 		}
 	}
+	return p.Nodes, nil
+}
+
+// useNodes makes the nodes, the sources and the form fields current
+func (p *Cogent) useNodes(c nodesCache) {
+	cogentNodesMu.Lock()
+	cogentNodes, cogentBGPNodes, cogentSources = c.Nodes, c.BGPNodes, c.Sources
+	cogentNodesMu.Unlock()
+	var f formFields
+	if c.Fields != nil {
+		f = *c.Fields
+	}
+	p.parser().setFieldNames(f)
+	p.Nodes = nodeNames(c.Nodes)
+}
+
+// loadedNodes returns the current nodes, bgp nodes and sources
+func loadedNodes() nodesCache {
+	cogentNodesMu.RLock()
+	defer cogentNodesMu.RUnlock()
+	return nodesCache{Nodes: cogentNodes, BGPNodes: cogentBGPNodes, Sources: cogentSources}
+}
+
+// InvalidateCache clears the loaded nodes of the instance and the
+// package, so the next GetNodes reads the disk cache or fetches them
+// (see RefreshNodes to bypass the disk cache too)
+func (p *Cogent) InvalidateCache() {
+	cogentNodesMu.Lock()
+	cogentNodes, cogentBGPNodes, cogentSources = map[string]string{}, map[string]string{}, map[string]string{}
+	cogentNodesMu.Unlock()
+	p.parser().setFieldNames(formFields{})
+	p.Nodes = nil
+}
+
+// cacheFile returns the nodes cache file path
+func (p *Cogent) cacheFile() string {
+	if p.CacheFile != "" {
+		return p.CacheFile
+	}
+	return cacheFile("cogent")
+}
+
+// cacheTTL returns the nodes cache lifetime
+func (p *Cogent) cacheTTL() time.Duration {
+	if p.CacheTTL > 0 {
+		return p.CacheTTL
+	}
+	return defaultCacheTTL
+}
+
+// Get returns the host and the ip version which are set
+func (p *Cogent) Get() (string, string) {
+	return p.Host, p.IPv
+}
+
+// GetNode returns the current node
+func (p *Cogent) GetNode() string {
+	return p.Node
+}
+
+// ChangeNode set new requested node, the node can be
+// a part of the name or a city abbreviation as long as
+// it matches only one node
+func (p *Cogent) ChangeNode(node string) bool {
+	if m := p.MatchNodes(node); len(m) == 1 {
+		p.Node = m[0]
+		return true
+	}
 	return false
 }
 
+// MatchNodes returns the candidate nodes for the query
+func (p *Cogent) MatchNodes(query string) []string {
+	return matchNodes(p.Nodes, loadedNodes().Nodes, query)
+}
+
+// CompletionCandidates returns the loaded nodes which match the prefix
+func (p *Cogent) CompletionCandidates(prefix string) []string {
+	return completionCandidates(p.Nodes, prefix)
+}
+
+// CurrentNodeCode returns the location code of the current node
+func (p *Cogent) CurrentNodeCode() (string, bool) {
+	return p.NodeCode(p.Node)
+}
+
+// NodeCode returns the location code of the node, e.g. losa
+func (p *Cogent) NodeCode(name string) (string, bool) {
+	n := loadedNodes()
+	if code, ok := n.Nodes[name]; ok {
+		return code, true
+	}
+	code, ok := n.BGPNodes[name]
+	return code, ok
+}
+
+// Capabilities returns the commands which the node supports
+func (p *Cogent) Capabilities(node string) (ping, trace, bgp bool) {
+	n := loadedNodes()
+	_, ping = n.Nodes[node]
+	_, bgp = n.BGPNodes[node]
+	return ping, ping, bgp
+}
+
+// supports returns ErrUnsupportedCommand if the current node can't
+// serve the command, it can't be checked before the nodes are loaded
+func (p *Cogent) supports(cmd string) error {
+	if n := loadedNodes(); len(n.Nodes) == 0 && len(n.BGPNodes) == 0 {
+		return nil
+	}
+	ping, trace, bgp := p.Capabilities(p.Node)
+	if ok := map[string]bool{"ping": ping, "trace": trace, "bgp": bgp}[cmd]; !ok {
+		return fmt.Errorf("%w: %s on %s", ErrUnsupportedCommand, cmd, p.Node)
+	}
+	return nil
+}
+
 // Ping tries to connect Cogent's ping looking glass through HTTP
 // Returns the result
 func (p *Cogent) Ping() (string, error) {
-	// Basic validate
-	if p.Node == "NA" || len(p.Host) < 5 {
-		print("Invalid node or host/ip address")
-		return "", errors.New("error")
+	return p.PingContext(context.Background())
+}
+
+// PingContext is like Ping but the request is bound to ctx, so
+// it can be canceled or timed out by the caller
+func (p *Cogent) PingContext(ctx context.Context) (string, error) {
+	if p.Node == "NA" {
+		return "", errors.New("invalid node")
+	}
+	host, err := normalizeHost(p.Host)
+	if err == nil {
+		host, err = p.resolve(ctx, host)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := p.supports("ping"); err != nil {
+		return "", err
+	}
+	node := p.servingNode(ctx)
+	form := p.form(commandFor(CmdPing, p.IPv), host, loadedNodes().Nodes[node])
+	if err := p.pingOptions(form); err != nil {
+		return "", err
+	}
+	if err := p.sourceOption(form); err != nil {
+		return "", err
 	}
-	var cmd = "P4"
-	if p.IPv == "ipv6" {
-		cmd = "P6"
+	if p.DryRun {
+		return p.describe(form), nil
 	}
-	resp, err := http.PostForm("http://www.cogentco.com/lookingglass.php",
-		url.Values{"FKT": {"go!"}, "CMD": {cmd}, "DST": {p.Host}, "LOC": {cogentNodes[p.Node]}})
+	resp, err := p.do(ctx, form)
 	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := rateLimitStatus(resp); err != nil {
 		return "", err
 	}
 	if resp.StatusCode != 200 {
 		return "", errors.New("error: cogent looking glass is not available")
 	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := ioutil.ReadAll(io.TeeReader(resp.Body, newRawWriter(&p.raw, p.KeepRaw)))
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", err
+	}
+	out, err := extractBlocks(p.parser().Output, string(body))
+	if err == nil {
+		observePing("cogent", node, out)
+	}
+	return out, err
+}
+
+// resolve returns the locally resolved address of the host if
+// ResolveLocal is set, the ip literal is returned as it is
+func (p *Cogent) resolve(ctx context.Context, host string) (string, error) {
+	p.resolved = ""
+	if !p.ResolveLocal || net.ParseIP(host) != nil {
+		return host, nil
+	}
+	addrs, err := ResolveHost(ctx, host, p.IPv)
 	if err != nil {
 		return "", err
 	}
-	r, _ := regexp.Compile(`<pre>(?s)(.*?)</pre>`)
-	b := r.FindStringSubmatch(string(body))
-	if len(b) > 0 {
-		return b[1], nil
+	i := p.ResolveIndex
+	if i == 0 {
+		i = 1
 	}
-	return "", errors.New("error")
+	if i < 0 || i > len(addrs) {
+		return "", fmt.Errorf("invalid address %d: %s has %d addresses (%s)", i, host, len(addrs), strings.Join(addrs, ", "))
+	}
+	p.resolved = addrs[i-1]
+	getLogger().Info("cogent: %s is resolved locally to %s (%d of %d: %s)", host, p.resolved, i, len(addrs), strings.Join(addrs, ", "))
+	return p.resolved, nil
+}
+
+// Resolved returns the locally resolved address of the
+// last Ping or Trace, it's empty w/o ResolveLocal
+func (p *Cogent) Resolved() string {
+	return p.resolved
+}
+
+// pingOptions validates and adds the count and size to the form
+func (p *Cogent) pingOptions(form url.Values) error {
+	if p.Count < 0 || p.Count > cogentMaxCount {
+		return fmt.Errorf("invalid count %d: it should be between 1 and %d", p.Count, cogentMaxCount)
+	}
+	if p.Size < 0 || p.Size > cogentMaxSize {
+		return fmt.Errorf("invalid size %d: it should be between 1 and %d", p.Size, cogentMaxSize)
+	}
+	if p.Count > 0 {
+		form.Set("COUNT", strconv.Itoa(p.Count))
+	}
+	if p.Size > 0 {
+		form.Set("SIZE", strconv.Itoa(p.Size))
+	}
+	return nil
+}
+
+// sourceOption validates and adds the source to the form, the source
+// is ignored w/ a warning if the looking glass doesn't offer any
+func (p *Cogent) sourceOption(form url.Values) error {
+	if p.Source == "" {
+		return nil
+	}
+	sources := loadedNodes().Sources
+	if len(sources) == 0 {
+		getLogger().Warn("cogent: the source selection isn't supported, %q is ignored", p.Source)
+		return nil
+	}
+	for name, v := range sources {
+		if strings.EqualFold(p.Source, name) || p.Source == v {
+			form.Set(p.parser().fieldNames().Source, v)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid source %q: it should be one of %s", p.Source, strings.Join(nodeNames(sources), ", "))
+}
+
+// PingStats pings the host and parses the statistics
+func (p *Cogent) PingStats() (PingStats, error) {
+	out, err := p.Ping()
+	if err != nil {
+		return PingStats{}, err
+	}
+	return ParsePing(out)
+}
+
+// PingAll pings the host from the nodes concurrently
+func (p *Cogent) PingAll(nodes []string) map[string]PingResult {
+	return p.PingAllContext(context.Background(), nodes)
+}
+
+// PingAllContext is like PingAll but all of the requests
+// are bound to ctx, so the whole batch can be canceled
+func (p *Cogent) PingAllContext(ctx context.Context, nodes []string) map[string]PingResult {
+	var (
+		results = make(map[string]PingResult, len(nodes))
+		workers = p.Workers
+		jobs    = make(chan string)
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+	)
+	if workers < 1 {
+		workers = 5
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for node := range jobs {
+				r := p.pingNode(ctx, node)
+				mu.Lock()
+				results[node] = r
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, node := range nodes {
+		jobs <- node
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// NearestNode pings the host (nearestAnchor if it's empty) from one
+// node of each region and selects the node w/ the lowest average rtt
+func (p *Cogent) NearestNode(host string) (string, time.Duration, error) {
+	if host == "" {
+		host = nearestAnchor
+	}
+	var sample []string
+	for _, nodes := range p.GetNodesByRegion() {
+		sample = append(sample, nodes[0])
+	}
+	sort.Strings(sample)
+	if len(sample) == 0 {
+		return "", 0, errors.New("cogent nodes aren't available")
+	}
+
+	q := p.clone()
+	q.Set(host, "")
+	var (
+		best    string
+		rtt     time.Duration
+		results = q.PingAll(sample)
+	)
+	for _, node := range sample {
+		r := results[node]
+		if r.Err != nil || r.PacketsReceived == 0 {
+			continue
+		}
+		if best == "" || r.Avg < rtt {
+			best, rtt = node, r.Avg
+		}
+	}
+	if best == "" {
+		return "", 0, fmt.Errorf("no reply from the sampled nodes to %s", host)
+	}
+	p.Node = best
+	return best, rtt, nil
+}
+
+// clone returns a copy of the lg for the concurrent requests,
+// the copy doesn't keep the raw response since it'd race w/ the
+// other copies and it's not the one which is dumped
+func (p *Cogent) clone() *Cogent {
+	q := *p
+	q.KeepRaw, q.raw = false, nil
+	return &q
+}
+
+// pingNode pings the host from the node
+func (p *Cogent) pingNode(ctx context.Context, node string) PingResult {
+	if _, ok := loadedNodes().Nodes[node]; !ok {
+		return PingResult{Err: fmt.Errorf("unknown node %q", node)}
+	}
+	q := p.clone()
+	// the concurrent pings aren't failed over
+	q.Node, q.Failover = node, false
+	out, err := q.PingContext(ctx)
+	if err != nil {
+		return PingResult{Err: err}
+	}
+	stats, err := ParsePing(out)
+	return PingResult{PingStats: stats, Err: err}
+}
+
+// baseURL returns the configured looking glass address or the default one
+func (p *Cogent) baseURL() string {
+	if p.BaseURL != "" {
+		return p.BaseURL
+	}
+	return cogentLGURL
+}
+
+// do sends the form to the looking glass through POST method
+// or GET if the form is nil. it retries the transient failures
+// w/ exponential backoff
+func (p *Cogent) do(ctx context.Context, form url.Values) (*http.Response, error) {
+	client, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+	ctx = withLabels(ctx, "cogent", p.Node, p.cogentCmd(form))
+	for i := 0; ; i++ {
+		resp, err := p.attempt(ctx, client, form)
+		if i >= p.retries() || ctx.Err() != nil || !retryable(resp, err) {
+			return resp, err
+		}
+		if err == nil {
+			resp.Body.Close()
+			err = errors.New(resp.Status)
+		}
+		d := backoff(i)
+		getLogger().Debug("cogent: request failed (%s), retry in %s", err, d)
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// describe returns the human-readable request which would be sent
+func (p *Cogent) describe(form url.Values) string {
+	var keys []string
+	for k := range form {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	lines := []string{"POST " + p.baseURL(), "Content-Type: application/x-www-form-urlencoded", ""}
+	for _, k := range keys {
+		lines = append(lines, k+"="+strings.Join(form[k], ","))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cogentCmd returns the metrics command label of the request
+func (p *Cogent) cogentCmd(form url.Values) string {
+	switch CogentCmd(form.Get(p.parser().fieldNames().Command)) {
+	case CmdPing4, CmdPing6:
+		return CmdPing.String()
+	case CmdTrace4, CmdTrace6:
+		return CmdTrace.String()
+	case CmdBGPQuery:
+		return CmdBGP.String()
+	}
+	return "nodes"
+}
+
+// attempt makes a request, it retries once through plain
+// HTTP if the HTTPS handshake fails
+func (p *Cogent) attempt(ctx context.Context, client *http.Client, form url.Values) (*http.Response, error) {
+	u := p.baseURL()
+	resp, err := p.send(ctx, client, u, form)
+	if err != nil && strings.HasPrefix(u, "https://") && ctx.Err() == nil && isTLSHandshakeErr(err) {
+		u = "http://" + strings.TrimPrefix(u, "https://")
+		getLogger().Debug("cogent: TLS handshake failed (%s), falling back to %s", err, u)
+		resp, err = p.send(ctx, client, u, form)
+	}
+	return resp, err
+}
+
+// retries returns the max retries
+func (p *Cogent) retries() int {
+	if p.DisableRetry {
+		return 0
+	}
+	if p.Retries > 0 {
+		return p.Retries
+	}
+	return defaultRetries
+}
+
+// maxRedirects returns the max redirects of the form requests
+func (p *Cogent) maxRedirects() int {
+	switch {
+	case p.MaxRedirects < 0:
+		return 0
+	case p.MaxRedirects > 0:
+		return p.MaxRedirects
+	}
+	return defaultMaxRedirects
+}
+
+// send makes a single request to the looking glass once the shared
+// rate limiter allows. the client would follow the 301, 302 and 303
+// redirects of the form w/ GET and w/o the form, so the form is
+// posted to the new location instead
+func (p *Cogent) send(ctx context.Context, client *http.Client, u string, form url.Values) (*http.Response, error) {
+	if err := cogentLimiter.wait(ctx); err != nil {
+		return nil, err
+	}
+	if form == nil {
+		return p.request(ctx, client, u, nil)
+	}
+	c := *client
+	c.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	for i := 0; ; i++ {
+		resp, err := p.request(ctx, &c, u, form)
+		if err != nil || !isRedirect(resp.StatusCode) {
+			return resp, err
+		}
+		loc, err := resp.Location()
+		if err != nil {
+			return resp, nil
+		}
+		resp.Body.Close()
+		if i >= p.maxRedirects() {
+			return nil, fmt.Errorf("%w: too many redirects (max %d), the last one is to %s", errRedirect, p.maxRedirects(), loc)
+		}
+		if err := checkRedirect(u, loc); err != nil {
+			return nil, err
+		}
+		getLogger().Info("cogent: the request is redirected (%s) to %s", resp.Status, loc)
+		u = loc.String()
+	}
+}
+
+// checkRedirect refuses the redirect to another host or from HTTPS
+// to plain HTTP since the form would be posted again to the location
+func checkRedirect(from string, to *url.URL) error {
+	f, err := url.Parse(from)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(f.Hostname(), to.Hostname()) {
+		return fmt.Errorf("%w: %s is another host", errRedirect, to)
+	}
+	if f.Scheme == "https" && to.Scheme != "https" {
+		return fmt.Errorf("%w: %s is plain HTTP", errRedirect, to)
+	}
+	return nil
+}
+
+// isRedirect returns true if the status is a redirect w/ the location
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// request makes the GET or else the form POST request, the
+// gzip and deflate responses are decoded (see decodeBody)
+func (p *Cogent) request(ctx context.Context, client *http.Client, u string, form url.Values) (*http.Response, error) {
+	var (
+		method = "GET"
+		body   io.Reader
+	)
+	if form != nil {
+		method = "POST"
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := newRequest(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	for k, v := range p.headers {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req)
+	if err == nil {
+		decodeBody(resp)
+	}
+	return resp, err
+}
+
+// SetHeader sets the header of the looking glass requests,
+// e.g. User-Agent replaces the package one (see SetUserAgent)
+func (p *Cogent) SetHeader(key, value string) {
+	if p.headers == nil {
+		p.headers = http.Header{}
+	}
+	p.headers.Set(key, value)
+}
+
+// client returns the configured http client or the package one,
+// it routes through the ProxyURL and dials from the LocalAddr if
+// they're set
+func (p *Cogent) client() (*http.Client, error) {
+	var (
+		c   = httpClient
+		err error
+	)
+	if p.Client != nil {
+		c = p.Client
+	}
+	if p.ProxyURL != "" {
+		if c, err = proxyClient(c, p.ProxyURL); err != nil {
+			return nil, err
+		}
+	}
+	if p.LocalAddr == "" {
+		return c, nil
+	}
+	return localAddrClient(c, p.LocalAddr)
 }
 
 // Trace gets traceroute information from Cogent
-func (p *Cogent) Trace() chan string {
+func (p *Cogent) Trace() (chan string, error) {
+	return p.TraceContext(context.Background())
+}
+
+// TraceContext is like Trace but the request is bound to ctx. the
+// returned channel should be drained or ctx canceled, the channel
+// and the response body are closed once ctx is canceled
+func (p *Cogent) TraceContext(ctx context.Context) (chan string, error) {
+	host, err := normalizeHost(p.Host)
+	if err == nil {
+		host, err = p.resolve(ctx, host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := p.supports("trace"); err != nil {
+		return nil, err
+	}
+	if p.MaxHops < 0 || p.WaitTime < 0 {
+		return nil, fmt.Errorf("invalid max hops %d or wait time %s", p.MaxHops, p.WaitTime)
+	}
 	c := make(chan string)
-	var cmd = "T4"
-	if p.IPv == "ipv6" {
-		cmd = "T6"
+	form := p.form(commandFor(CmdTrace, p.IPv), host, loadedNodes().Nodes[p.servingNode(ctx)])
+	if err := p.sourceOption(form); err != nil {
+		return nil, err
 	}
-	resp, err := http.PostForm("http://www.cogentco.com/lookingglass.php",
-		url.Values{"FKT": {"go!"}, "CMD": {cmd}, "DST": {p.Host}, "LOC": {cogentNodes[p.Node]}})
+	if p.DryRun {
+		go func() {
+			select {
+			case c <- p.describe(form):
+			case <-ctx.Done():
+			}
+			close(c)
+		}()
+		return c, nil
+	}
+	parent, cancel := ctx, context.CancelFunc(func() {})
+	if p.WaitTime > 0 {
+		ctx, cancel = context.WithTimeout(ctx, p.WaitTime)
+	}
+	resp, err := p.do(ctx, form)
 	if err != nil {
-		println(err)
+		cancel()
+		return nil, err
+	}
+	if err := rateLimitStatus(resp); err != nil {
+		resp.Body.Close()
+		cancel()
+		return nil, err
+	}
+	var (
+		scanner       = bufio.NewScanner(io.TeeReader(resp.Body, newRawWriter(&p.raw, p.KeepRaw)))
+		lineRe        = p.parser().TraceLine
+		maxHops, wait = p.MaxHops, p.WaitTime
+		first         string
+		found         bool
+		skipped       strings.Builder
+	)
+	// the output is read up to the first trace line, so the rate
+	// limit page is returned as an error instead of an empty trace
+	for !found && scanner.Scan() {
+		first = cleanLine(replaceASNTrace(scanner.Text()))
+		if found = lineRe.MatchString(first); !found {
+			skipped.WriteString(first + "\n")
+		}
+	}
+	if !found {
+		if err := rateLimitedPage(skipped.String()); err != nil {
+			resp.Body.Close()
+			cancel()
+			return nil, err
+		}
 	}
 	go func() {
+		defer cancel()
 		defer resp.Body.Close()
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
-			l := scanner.Text()
-			m, _ := regexp.MatchString(`^(traceroute|\s*\d{1,2})`, l)
-			if m {
-				l = replaceASNTrace(l)
-				c <- l
+		hops := 0
+		// send returns false once the trace should be stopped
+		send := func(l string) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case c <- l:
+			}
+			if !strings.HasPrefix(l, "traceroute") {
+				hops++
+			}
+			if maxHops > 0 && hops >= maxHops {
+				getLogger().Debug("cogent: the trace is stopped at %d hops", hops)
+				return false
 			}
+			return true
+		}
+		if found && send(first) {
+			for scanner.Scan() {
+				l := cleanLine(replaceASNTrace(scanner.Text()))
+				if lineRe.MatchString(l) && !send(l) {
+					break
+				}
+			}
+		}
+		if ctx.Err() != nil && parent.Err() == nil {
+			getLogger().Warn("cogent: the trace is cut off after %s", wait)
 		}
 		close(c)
 	}()
-	return c
+	return c, nil
+}
+
+// TraceStructured is like Trace but it parses each hop
+func (p *Cogent) TraceStructured() (chan TraceHop, error) {
+	lines, err := p.Trace()
+	if err != nil {
+		return nil, err
+	}
+	return traceHops(lines), nil
 }
 
 // BGP gets bgp information from cogent
 func (p *Cogent) BGP() chan string {
+	return p.BGPContext(context.Background())
+}
+
+// BGPContext is like BGP but the request is bound to ctx. the
+// returned channel should be drained or ctx canceled
+func (p *Cogent) BGPContext(ctx context.Context) chan string {
 	c := make(chan string)
-	if _, ok := cogentBGPNodes[p.Node]; !ok {
-		println("current node doesn't support bgp, please select one of the below nodes:")
+	bgp := loadedNodes().BGPNodes
+	if _, ok := bgp[p.Node]; !ok {
+		getLogger().Info("current node doesn't support bgp, please select one of the below nodes:")
 		go func() {
-			for n := range cogentBGPNodes {
-				println(n)
+			for n := range bgp {
+				getLogger().Info("%s", n)
 			}
 			close(c)
 		}()
 		return c
 	}
-	resp, err := http.PostForm("http://www.cogentco.com/lookingglass.php",
-		url.Values{"FKT": {"go!"}, "CMD": {"BGP"}, "DST": {p.Host}, "LOC": {cogentBGPNodes[p.Node]}})
+	if p.DryRun {
+		host, err := normalizePrefix(p.Host)
+		if err != nil {
+			getLogger().Error("%s", err)
+			close(c)
+			return c
+		}
+		go func() {
+			select {
+			case c <- p.describe(p.bgpForm(host)):
+			case <-ctx.Done():
+			}
+			close(c)
+		}()
+		return c
+	}
+	routes, err := p.bgpRoutes(ctx)
 	if err != nil {
-		println(err)
+		getLogger().Error("%s", err)
 	}
 	go func() {
-		defer resp.Body.Close()
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
-			l := scanner.Text()
-			c <- l
+	LOOP:
+		for _, r := range routes {
+			select {
+			case <-ctx.Done():
+				break LOOP
+			case c <- r.String():
+			}
 		}
 		close(c)
 	}()
 	return c
 }
 
-//FetchNodes returns all available nodes through HTTP
-func (p *Cogent) FetchNodes() (map[string]string, map[string]string) {
+// BGPRoutes gets bgp information from cogent and parses the routes
+func (p *Cogent) BGPRoutes() ([]BGPRoute, error) {
+	return p.bgpRoutes(context.Background())
+}
+
+// bgpRoutes gets the bgp routes bound to ctx
+func (p *Cogent) bgpRoutes(ctx context.Context) ([]BGPRoute, error) {
+	if _, ok := loadedNodes().BGPNodes[p.Node]; !ok {
+		return nil, fmt.Errorf("%w: bgp on %s", ErrUnsupportedCommand, p.Node)
+	}
+	host, err := normalizePrefix(p.Host)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.do(ctx, p.bgpForm(host))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := rateLimitStatus(resp); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, errors.New("error: cogent looking glass is not available")
+	}
+	body, err := ioutil.ReadAll(io.TeeReader(resp.Body, newRawWriter(&p.raw, p.KeepRaw)))
+	if err != nil {
+		return nil, err
+	}
+	cp := p.parser()
+	routes, err := parseBGPRoutes(bytes.NewReader(body), cp.BGPEntry, cp.BGPPath)
+	if err == nil && len(routes) == 0 {
+		err = rateLimitedPage(sanitize(string(body)))
+	}
+	return routes, err
+}
+
+// bgpForm returns the bgp request form of the host
+func (p *Cogent) bgpForm(host string) url.Values {
+	return p.form(CmdBGPQuery, host, loadedNodes().BGPNodes[p.Node])
+}
+
+// FetchNodes returns all available nodes through HTTP
+func (p *Cogent) FetchNodes() (map[string]string, map[string]string, error) {
+	return p.FetchNodesContext(context.Background())
+}
+
+// FetchNodesContext is like FetchNodes but the request is bound to ctx
+func (p *Cogent) FetchNodesContext(ctx context.Context) (map[string]string, map[string]string, error) {
+	c, err := p.fetchNodes(ctx, nil)
+	return c.Nodes, c.BGPNodes, err
+}
+
+// fetchNodes returns the nodes, the bgp nodes and the probe sources,
+// the stages are reported if report isn't nil
+func (p *Cogent) fetchNodes(ctx context.Context, report func(FetchStage)) (nodesCache, error) {
 	var (
 		nodes    = make(map[string]string, 100)
 		bgpNodes = make(map[string]string, 50)
+		sources  = make(map[string]string)
 	)
-	resp, err := http.Get("http://www.cogentco.com/lookingglass.php")
+	if report == nil {
+		report = func(FetchStage) {}
+	}
+	report(StageConnecting)
+	resp, err := p.do(ctx, nil)
 	if err != nil {
-		println("error: cogent looking glass unreachable (1)")
-		return map[string]string{}, map[string]string{}
+		return nodesCache{}, fmt.Errorf("cogent looking glass unreachable: %w", err)
 	}
 	defer resp.Body.Close()
-	b, err := ioutil.ReadAll(resp.Body)
+	if err := rateLimitStatus(resp); err != nil {
+		return nodesCache{}, err
+	}
+	if resp.StatusCode != 200 {
+		return nodesCache{}, fmt.Errorf("cogent looking glass unreachable: %s", resp.Status)
+	}
+	report(StageDownloading)
+	b, err := ioutil.ReadAll(io.TeeReader(resp.Body, newRawWriter(&p.raw, p.KeepRaw)))
 	if err != nil {
-		println("error: cogent looking glass unreachable (2)" + err.Error())
-		return map[string]string{}, map[string]string{}
+		return nodesCache{}, fmt.Errorf("cogent looking glass read failed: %w", err)
+	}
+	report(StageParsing)
+	var (
+		body = string(b)
+		cp   = p.parser()
+	)
+	// probe sources, they're removed so they're not taken as nodes
+	for _, v := range cp.Source.FindAllStringSubmatch(body, -1) {
+		if len(v) > 2 {
+			sources[v[1]] = v[2]
+		}
+	}
+	body = cp.Source.ReplaceAllString(body, "")
+	// form fields, the known names are used if they're not found
+	var fields *formFields
+	if f := scrapeFormFields(body); f != (formFields{}) {
+		fields = &f
+		if r := f.renamed(); len(r) > 0 {
+			getLogger().Warn("cogent: the form fields are renamed: %s", strings.Join(r, ", "))
+		}
 	}
-	body := string(b)
 	// ping, trace nodes
-	i := strings.Index(body, "default:")
-	r, _ := regexp.Compile(`(?is)Option\("([\w|,|\s|-]+)","([\w|\d]+)"`)
-	f := r.FindAllStringSubmatch(body[i:], -1)
+	i := 0
+	if loc := cp.NodesSplit.FindStringIndex(body); loc != nil {
+		i = loc[0]
+	}
+	f := cp.Node.FindAllStringSubmatch(body[i:], -1)
 	for _, v := range f {
-		nodes[v[1]] = v[2]
+		if len(v) > 2 {
+			addNode(nodes, v[1], v[2])
+		}
 	}
 	// bgp nodes
-	r, _ = regexp.Compile(`(?is)Option\("([\w|,|\s|-]+)","([\w|\d]+)"`)
-	f = r.FindAllStringSubmatch(body[:i], -1)
+	f = cp.Node.FindAllStringSubmatch(body[:i], -1)
 	for _, v := range f {
-		bgpNodes[v[1]] = v[2]
+		if len(v) > 2 {
+			addNode(bgpNodes, v[1], v[2])
+		}
 	}
 
-	return nodes, bgpNodes
+	return nodesCache{Nodes: nodes, BGPNodes: bgpNodes, Sources: sources, Fields: fields}, nil
+}
+
+// addNode adds the node w/ the whitespaces normalized, so the same name
+// is listed once. the last seen code is kept if the name has more codes
+func addNode(nodes map[string]string, name, code string) {
+	name = strings.Join(strings.Fields(name), " ")
+	if c, ok := nodes[name]; ok && c != code {
+		getLogger().Debug("cogent: the node %q has the codes %s and %s, %s is used", name, c, code, code)
+	}
+	nodes[name] = code
+}
+
+// SupportedCommands returns the commands which cogent offers
+func (p *Cogent) SupportedCommands() []Command {
+	return []Command{CmdPing, CmdTrace, CmdBGP}
 }
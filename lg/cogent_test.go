@@ -0,0 +1,2110 @@
+package lg_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+const cogentNodesFixture = `<script>
+function changeOptions() {
+	switch (document.forms[0].CMD.value) {
+	case "BGP":
+		document.forms[0].LOC.options[0] = new Option("US - Atlanta","atla");
+		document.forms[0].LOC.options[1] = new Option("NL - Amsterdam","amst");
+		break;
+	default:
+		document.forms[0].LOC.options[0] = new Option("US - Los Angeles","losa");
+		document.forms[0].LOC.options[1] = new Option("US - Atlanta","atla");
+		document.forms[0].LOC.options[2] = new Option("JP - Tokyo","toky");
+	}
+}
+</script>`
+
+const cogentPingOutput = `PING 8.8.8.8 (8.8.8.8) 56(84) bytes of data.
+64 bytes from 8.8.8.8: icmp_seq=1 ttl=57 time=1.21 ms
+
+--- 8.8.8.8 ping statistics ---
+1 packets transmitted, 1 received, 0% packet loss, time 0ms
+rtt min/avg/max/mdev = 1.210/1.210/1.210/0.000 ms
+`
+
+const cogentPingFixture = "<html><body><pre>" + cogentPingOutput + "</pre></body></html>"
+
+const cogentTraceFixture = `<html><body><pre>
+traceroute to 8.8.8.8 (8.8.8.8), 30 hops max, 60 byte packets
+ 1  be2931.ccr21.lax01.atlas.cogentco.com (154.54.44.85)  0.512 ms  0.498 ms
+ 2  google.lax01.atlas.cogentco.com (154.54.11.90)  1.045 ms  1.102 ms
+</pre></body></html>`
+
+func TestCogentRecordedResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		run     func(c *lg.Cogent) ([]string, error)
+		want    []string
+	}{
+		{
+			name:    "nodes",
+			fixture: cogentNodesFixture,
+			run: func(c *lg.Cogent) ([]string, error) {
+				nodes, bgpNodes, err := c.FetchNodes()
+				if err != nil {
+					return nil, err
+				}
+				var r []string
+				for k, v := range nodes {
+					r = append(r, k+"="+v)
+				}
+				for k, v := range bgpNodes {
+					r = append(r, "bgp:"+k+"="+v)
+				}
+				sort.Strings(r)
+				return r, nil
+			},
+			want: []string{
+				"JP - Tokyo=toky",
+				"US - Atlanta=atla",
+				"US - Los Angeles=losa",
+				"bgp:NL - Amsterdam=amst",
+				"bgp:US - Atlanta=atla",
+			},
+		},
+		{
+			name:    "ping",
+			fixture: cogentPingFixture,
+			run: func(c *lg.Cogent) ([]string, error) {
+				r, err := c.Ping()
+				return []string{r}, err
+			},
+			want: []string{cogentPingOutput},
+		},
+		{
+			name:    "trace",
+			fixture: cogentTraceFixture,
+			run: func(c *lg.Cogent) ([]string, error) {
+				var r []string
+				lines, err := c.Trace()
+				if err != nil {
+					return nil, err
+				}
+				for l := range lines {
+					r = append(r, l)
+				}
+				return r, nil
+			},
+			want: []string{
+				"traceroute to 8.8.8.8 (8.8.8.8), 30 hops max, 60 byte packets",
+				" 1  be2931.ccr21.lax01.atlas.cogentco.com (154.54.44.85)  0.512 ms  0.498 ms",
+				" 2  google.lax01.atlas.cogentco.com (154.54.11.90)  1.045 ms  1.102 ms",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		fixture := tt.fixture
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, fixture)
+		}))
+		c := &lg.Cogent{BaseURL: ts.URL}
+		c.Set("8.8.8.8", "ipv4")
+		got, err := tt.run(c)
+		ts.Close()
+		if err != nil {
+			t.Error(tt.name, "unexpected error:", err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %q but got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCogentHTTPS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+
+	c := &lg.Cogent{BaseURL: ts.URL, Client: ts.Client()}
+	c.Set("8.8.8.8", "ipv4")
+	r, err := c.Ping()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if r != cogentPingOutput {
+		t.Error("expected ping output through HTTPS but got", r)
+	}
+}
+
+func TestCogentHTTPFallback(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+
+	c := &lg.Cogent{BaseURL: "https://" + ts.Listener.Addr().String()}
+	c.Set("8.8.8.8", "ipv4")
+	r, err := c.Ping()
+	if err != nil {
+		t.Fatal("expected fallback to HTTP but got error:", err)
+	}
+	if r != cogentPingOutput {
+		t.Error("expected ping output through HTTP but got", r)
+	}
+}
+
+func TestCogentCertificateError(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	ts.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	ts.StartTLS()
+	defer ts.Close()
+
+	c := &lg.Cogent{BaseURL: ts.URL, DisableRetry: true}
+	c.Set("8.8.8.8", "ipv4")
+	if _, err := c.Ping(); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Error("expected the certificate error w/o the HTTP fallback but got", err)
+	}
+}
+
+func TestCogentNodesDiskCache(t *testing.T) {
+	var hits int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprint(w, cogentNodesFixture)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "cogent.nodes.json")
+
+	c := &lg.Cogent{BaseURL: ts.URL, CacheFile: file}
+	if nodes := c.GetNodes(); len(nodes) != 3 {
+		t.Error("expected 3 nodes but got", nodes)
+	}
+	// new instance should be loaded from the disk cache
+	c = &lg.Cogent{BaseURL: ts.URL, CacheFile: file}
+	if nodes := c.GetNodes(); len(nodes) != 3 || hits != 1 {
+		t.Error("expected 3 nodes from the disk cache but got", nodes, "hits", hits)
+	}
+	if _, err := c.RefreshNodes(); err != nil || hits != 2 {
+		t.Error("expected RefreshNodes to fetch the nodes but hits", hits)
+	}
+	// expired cache
+	c = &lg.Cogent{BaseURL: ts.URL, CacheFile: file, CacheTTL: time.Nanosecond}
+	c.GetNodes()
+	if hits != 3 {
+		t.Error("expected expired cache to be refreshed but hits", hits)
+	}
+}
+
+// newCogentFixture returns a Cogent w/ the fixture nodes loaded
+func newCogentFixture(t *testing.T) (*lg.Cogent, func()) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, cogentNodesFixture)
+	}))
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &lg.Cogent{BaseURL: ts.URL, CacheFile: filepath.Join(dir, "cogent.nodes.json")}
+	c.GetNodes()
+	return c, func() {
+		ts.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCogentInvalidateCache(t *testing.T) {
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+
+	if _, ok := c.NodeCode("US - Atlanta"); !ok || len(c.Nodes) == 0 {
+		t.Fatal("expected the loaded nodes", c.Nodes)
+	}
+	c.InvalidateCache()
+	if _, ok := c.NodeCode("US - Atlanta"); ok || c.Nodes != nil {
+		t.Error("expected the nodes to be cleared but got", c.Nodes)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q := &lg.Cogent{CacheFile: c.CacheFile}
+			q.GetNodes()
+			q.MatchNodes("atl")
+			q.InvalidateCache()
+		}()
+	}
+	wg.Wait()
+	if nodes := c.GetNodes(); len(nodes) != 3 {
+		t.Error("expected the nodes to be reloaded but got", nodes)
+	}
+}
+
+func TestCogentNodeCatalog(t *testing.T) {
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+
+	want := []lg.NodeInfo{
+		{Name: "JP - Tokyo", Code: "toky", Region: "Asia", SupportsPing: true},
+		{Name: "NL - Amsterdam", Code: "amst", Region: "Europe", SupportsBGP: true},
+		{Name: "US - Atlanta", Code: "atla", Region: "US", SupportsPing: true, SupportsBGP: true},
+		{Name: "US - Los Angeles", Code: "losa", Region: "US", SupportsPing: true},
+	}
+	got := c.NodeCatalog()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v but got %+v", want, got)
+	}
+	b, err := json.Marshal(got[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); s != `{"name":"NL - Amsterdam","code":"amst","region":"Europe","supports_ping":false,"supports_bgp":true}` {
+		t.Error("unexpected json", s)
+	}
+}
+
+func TestCogentMatchNodes(t *testing.T) {
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"US - Atlanta", []string{"US - Atlanta"}},
+		{"los angeles", []string{"US - Los Angeles"}},
+		{"LAX", []string{"US - Los Angeles"}},
+		{"toky", []string{"JP - Tokyo"}},
+		{"us", []string{"US - Atlanta", "US - Los Angeles"}},
+		{"paris", nil},
+	}
+	for _, tt := range tests {
+		if got := c.MatchNodes(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("MatchNodes(%q) expected %q but got %q", tt.query, tt.want, got)
+		}
+	}
+
+	if c.ChangeNode("us") {
+		t.Error("expected ChangeNode to fail on ambiguous node")
+	}
+	if !c.ChangeNode("lax") || c.Node != "US - Los Angeles" {
+		t.Error("expected ChangeNode to select US - Los Angeles but it is", c.Node)
+	}
+}
+
+func TestCogentCompletionCandidates(t *testing.T) {
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"lo", []string{"US - Los Angeles"}},
+		{"us - a", []string{"US - Atlanta"}},
+		{"US", []string{"US - Atlanta", "US - Los Angeles"}},
+		{"ngeles", nil},
+	}
+	for _, tt := range tests {
+		if got := c.CompletionCandidates(tt.prefix); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("CompletionCandidates(%q) expected %q but got %q", tt.prefix, tt.want, got)
+		}
+	}
+
+	// no nodes are loaded, it shouldn't fetch them
+	if got := new(lg.Cogent).CompletionCandidates("lo"); got != nil {
+		t.Error("expected no candidates w/o loaded nodes but got", got)
+	}
+}
+
+const cogentTraceHopsFixture = `<html><body><pre>
+traceroute to 8.8.8.8 (8.8.8.8), 30 hops max, 60 byte packets
+ 1  be2931.ccr21.lax01.atlas.cogentco.com (154.54.44.85) [AS174]  0.512 ms  0.498 ms
+ 2  * * *
+ 3  72.14.236.69  1.5 ms *  1.25 ms
+</pre></body></html>`
+
+func TestCogentTraceStructured(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, cogentTraceHopsFixture)
+	}))
+	defer ts.Close()
+
+	c := &lg.Cogent{BaseURL: ts.URL}
+	c.Set("8.8.8.8", "ipv4")
+	hops, err := c.TraceStructured()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []lg.TraceHop
+	for hop := range hops {
+		got = append(got, hop)
+	}
+	want := []lg.TraceHop{
+		{
+			Index: 1,
+			Host:  "be2931.ccr21.lax01.atlas.cogentco.com",
+			IP:    "154.54.44.85",
+			ASN:   "174",
+			RTTs:  []time.Duration{512 * time.Microsecond, 498 * time.Microsecond},
+		},
+		{Index: 2, Timeouts: 3},
+		{
+			Index:    3,
+			Host:     "72.14.236.69",
+			IP:       "72.14.236.69",
+			RTTs:     []time.Duration{1500 * time.Microsecond, 1250 * time.Microsecond},
+			Timeouts: 1,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v but got %+v", want, got)
+	}
+}
+
+func TestCogentTraceControlSequences(t *testing.T) {
+	const fixture = "<pre>\x1b]0;lg\x07traceroute to 8.8.8.8 (8.8.8.8), 30 hops max\n" +
+		"\x1b[1;31m 1\x1b[0m  gw.lax01.us (192.0.2.1)\t0.5 ms\x08\x00\n" +
+		" 2  192.0.2.2  1.5 ms\x1bc\u009b\r\n</pre>"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, fixture)
+	}))
+	defer ts.Close()
+
+	trace := func() []string {
+		c := &lg.Cogent{BaseURL: ts.URL}
+		c.Set("8.8.8.8", "ipv4")
+		lines, err := c.Trace()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for l := range lines {
+			got = append(got, l)
+		}
+		return got
+	}
+	want := []string{
+		"traceroute to 8.8.8.8 (8.8.8.8), 30 hops max",
+		" 1  gw.lax01.us (192.0.2.1)\t0.5 ms",
+		" 2  192.0.2.2  1.5 ms",
+	}
+	if got := trace(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q but got %q", want, got)
+	}
+
+	lg.SetRawOutput(true)
+	defer lg.SetRawOutput(false)
+	if got := trace(); !strings.Contains(strings.Join(got, "\n"), "\x1bc") {
+		t.Errorf("expected the raw escape codes but got %q", got)
+	}
+}
+
+func TestCogentTraceRTTUnits(t *testing.T) {
+	const fixture = `<html><body><pre>
+traceroute to 8.8.8.8 (8.8.8.8), 30 hops max, 60 byte packets
+ 1  gw.lax01.us (192.0.2.1)  498 &micro;s  750us  *
+ 2  192.0.2.2  1.5ms  2 μs  0.25 ms
+ 3  * * *
+</pre></body></html>`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, fixture)
+	}))
+	defer ts.Close()
+
+	c := &lg.Cogent{BaseURL: ts.URL}
+	c.Set("8.8.8.8", "ipv4")
+	hops, err := c.TraceStructured()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []lg.TraceHop
+	for hop := range hops {
+		got = append(got, hop)
+	}
+	us := time.Microsecond
+	tests := []struct {
+		rtts     []time.Duration
+		timeouts int
+		avg      time.Duration
+	}{
+		{[]time.Duration{498 * us, 750 * us}, 1, 624 * us},
+		{[]time.Duration{1500 * us, 2 * us, 250 * us}, 0, 584 * us},
+		{nil, 3, 0},
+	}
+	if len(got) != len(tests) {
+		t.Fatal("unexpected hops", got)
+	}
+	for i, tt := range tests {
+		if !reflect.DeepEqual(got[i].RTTs, tt.rtts) || got[i].Timeouts != tt.timeouts {
+			t.Errorf("hop %d: expected %v w/ %d timeouts but got %v w/ %d", i+1, tt.rtts, tt.timeouts, got[i].RTTs, got[i].Timeouts)
+		}
+		if avg := got[i].AvgRTT(); avg != tt.avg {
+			t.Errorf("hop %d: expected avg %s but got %s", i+1, tt.avg, avg)
+		}
+	}
+}
+
+const cogentBGPDetailFixture = `<html><body><pre>
+BGP routing table entry for 8.8.8.0/24, version 3364457
+Paths: (2 available, best #1, table default)
+  Advertised to update-groups:
+     1          2
+  15169
+    154.54.11.90 from 154.54.11.90 (72.14.227.1)
+      Origin IGP, metric 0, localpref 100, valid, external, best
+      Community: 174:21000 174:22013
+  3356 15169
+    4.68.62.1 from 4.68.62.1 (4.69.184.193)
+      Origin IGP, localpref 100, valid, external
+</pre></body></html>`
+
+const cogentBGPTableFixture = `<html><body><pre>
+BGP table version is 3364457, local router ID is 154.54.66.76
+Status codes: s suppressed, d damped, h history, * valid, &gt; best, i - internal
+Origin codes: i - IGP, e - EGP, ? - incomplete
+
+   Network          Next Hop            Metric LocPrf Weight Path
+*&gt; 8.8.8.0/24       154.54.11.90             0    100      0 15169 i
+*                   4.68.62.1                     100      0 3356 15169 ?
+
+Total number of prefixes 1
+</pre></body></html>`
+
+const cogentBGPPrependFixture = `<html><body><pre>
+BGP routing table entry for 192.0.2.0/24, version 3364457
+Paths: (1 available, best #1, table default)
+  174 174 174 64500
+    154.54.11.90 from 154.54.11.90 (72.14.227.1)
+      Origin IGP, metric 0, localpref 100, valid, external, best
+</pre></body></html>`
+
+func TestCogentBGPRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		want    []lg.BGPRoute
+	}{
+		{
+			name:    "detail",
+			fixture: cogentBGPDetailFixture,
+			want: []lg.BGPRoute{
+				{
+					Prefix:      "8.8.8.0/24",
+					NextHop:     "154.54.11.90",
+					ASPath:      []string{"15169"},
+					Communities: []string{"174:21000", "174:22013"},
+					Origin:      "IGP",
+					Best:        true,
+				},
+				{
+					Prefix:  "8.8.8.0/24",
+					NextHop: "4.68.62.1",
+					ASPath:  []string{"3356", "15169"},
+					Origin:  "IGP",
+				},
+			},
+		},
+		{
+			name:    "table",
+			fixture: cogentBGPTableFixture,
+			want: []lg.BGPRoute{
+				{Prefix: "8.8.8.0/24", NextHop: "154.54.11.90", ASPath: []string{"15169"}, Origin: "IGP", Best: true},
+				{Prefix: "8.8.8.0/24", NextHop: "4.68.62.1", ASPath: []string{"3356", "15169"}, Origin: "incomplete"},
+			},
+		},
+		{
+			name:    "prepend",
+			fixture: cogentBGPPrependFixture,
+			want: []lg.BGPRoute{
+				{
+					Prefix:   "192.0.2.0/24",
+					NextHop:  "154.54.11.90",
+					ASPath:   []string{"174", "174", "174", "64500"},
+					Origin:   "IGP",
+					Best:     true,
+					Prepends: map[string]int{"174": 2},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "GET" {
+				fmt.Fprint(w, cogentNodesFixture)
+				return
+			}
+			fmt.Fprint(w, tt.fixture)
+		}))
+		dir, _ := ioutil.TempDir("", "mylg")
+		c := &lg.Cogent{BaseURL: ts.URL, CacheFile: filepath.Join(dir, "cogent.nodes.json")}
+		c.GetNodes()
+		c.Set("8.8.8.0/24", "ipv4")
+		c.ChangeNode("US - Atlanta")
+
+		got, err := c.BGPRoutes()
+		if err != nil {
+			t.Error(tt.name, err)
+		} else if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %+v but got %+v", tt.name, tt.want, got)
+		}
+
+		var lines []string
+		for l := range c.BGP() {
+			lines = append(lines, l)
+		}
+		if len(lines) != len(tt.want) {
+			t.Errorf("%s: expected %d bgp lines but got %q", tt.name, len(tt.want), lines)
+		}
+		ts.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestBGPRoutePrepend(t *testing.T) {
+	r := lg.BGPRoute{Prefix: "192.0.2.0/24", NextHop: "192.0.2.1", ASPath: []string{"174", "174", "174", "64500"}}
+	if !r.HasPrepend() {
+		t.Error("expected prepend for", r.ASPath)
+	}
+	if want := " prepend 174x3"; !strings.HasSuffix(r.String(), want) {
+		t.Errorf("expected %q suffix but got %q", want, r.String())
+	}
+	r.ASPath = []string{"174", "64500", "174"}
+	if r.HasPrepend() {
+		t.Error("expected no prepend for", r.ASPath)
+	}
+}
+
+func TestCogentValidateHost(t *testing.T) {
+	var hits int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		host  string
+		valid bool
+	}{
+		{"a.co", true},
+		{"1.1.1.1", true},
+		{"2001:4860:4860::8888", true},
+		{"www.google.com.", true},
+		{"hello world", false},
+		{"host\x00name", false},
+		{"-bad.com", false},
+		{"bad..com", false},
+		{"under_score.com", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		hits = 0
+		c := &lg.Cogent{BaseURL: ts.URL}
+		c.Set(tt.host, "ipv4")
+		_, err := c.Ping()
+		if tt.valid && err != nil {
+			t.Errorf("expected %q to be valid but got %s", tt.host, err)
+		}
+		if !tt.valid && (err == nil || hits != 0) {
+			t.Errorf("expected %q to be rejected before any request", tt.host)
+		}
+		if _, err := c.Trace(); tt.valid != (err == nil) {
+			t.Errorf("expected trace to validate %q consistently", tt.host)
+		}
+	}
+}
+
+func TestCogentIDNHost(t *testing.T) {
+	var dst string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dst = r.FormValue("DST")
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"例え.jp", "xn--r8jz45g.jp"},
+		{"Bücher.example", "xn--bcher-kva.example"},
+		{"مثال.إختبار", "xn--mgbh0fb.xn--kgbechtv"},
+		{"xn--r8jz45g.jp", "xn--r8jz45g.jp"},
+		{"2001:db8::1", "2001:db8::1"},
+		{"192.0.2.1", "192.0.2.1"},
+		// invalid
+		{"xn--zz.com", ""},
+		{"a\u200db.com", ""},
+		{"例え_.jp", ""},
+	}
+	for _, tt := range tests {
+		dst = ""
+		c := &lg.Cogent{BaseURL: ts.URL}
+		c.Set(tt.host, "")
+		_, err := c.Ping()
+		if tt.want == "" {
+			if err == nil || dst != "" {
+				t.Errorf("expected %q to be rejected but got %q", tt.host, dst)
+			}
+			continue
+		}
+		if err != nil || dst != tt.want {
+			t.Errorf("expected %q to be sent as %q but got %q (%v)", tt.host, tt.want, dst, err)
+		}
+	}
+}
+
+func TestCogentIPVersion(t *testing.T) {
+	var cmd string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cmd = r.FormValue("CMD")
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		host    string
+		version string
+		want    string
+	}{
+		{"2001:db8::1", "ipv4", "P6"},
+		{"192.0.2.1", "ipv6", "P4"},
+		{"example.com", "ipv4", "P4"},
+		{"example.com", "ipv6", "P6"},
+	}
+	for _, tt := range tests {
+		c := &lg.Cogent{BaseURL: ts.URL}
+		c.Set(tt.host, tt.version)
+		if _, err := c.Ping(); err != nil {
+			t.Error(err)
+		}
+		if cmd != tt.want {
+			t.Errorf("expected %s for %s but got %s", tt.want, tt.host, cmd)
+		}
+	}
+}
+
+// recordTransport records the outgoing requests
+type recordTransport struct {
+	reqs []*http.Request
+}
+
+func (rt *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.reqs = append(rt.reqs, req)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestCogentSetHTTPClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+
+	rt := &recordTransport{}
+	lg.SetHTTPClient(&http.Client{Transport: rt, Timeout: time.Second})
+	defer lg.SetHTTPClient(&http.Client{Timeout: 30 * time.Second})
+
+	c := &lg.Cogent{BaseURL: ts.URL}
+	c.Set("8.8.8.8", "ipv4")
+	if _, err := c.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.reqs) != 1 {
+		t.Fatalf("expected 1 recorded request but got %d", len(rt.reqs))
+	}
+	if req := rt.reqs[0]; req.Method != "POST" || req.URL.String() != ts.URL {
+		t.Error("unexpected request", req.Method, req.URL)
+	}
+}
+
+func TestCogentFetchNodesError(t *testing.T) {
+	c, cleanup := newCogentFixture(t)
+	cleanup()
+	c.DisableRetry = true
+
+	if _, _, err := c.FetchNodes(); err == nil {
+		t.Error("expected error from unreachable looking glass")
+	}
+	nodes, err := c.RefreshNodes()
+	if err == nil {
+		t.Error("expected RefreshNodes to return the fetch error")
+	}
+	if len(nodes) != 3 || !c.ChangeNode("US - Atlanta") {
+		t.Error("expected the current nodes to be kept but got", nodes)
+	}
+}
+
+func TestCogentPingAll(t *testing.T) {
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+
+	var (
+		mu   sync.Mutex
+		locs []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		locs = append(locs, r.FormValue("LOC"))
+		mu.Unlock()
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+
+	c.BaseURL = ts.URL
+	c.Set("8.8.8.8", "ipv4")
+	results := c.PingAll([]string{"US - Los Angeles", "JP - Tokyo", "XX - Unknown"})
+	if len(results) != 3 {
+		t.Fatal("expected 3 results but got", results)
+	}
+	for _, node := range []string{"US - Los Angeles", "JP - Tokyo"} {
+		r := results[node]
+		if r.Err != nil || r.Avg != 1210*time.Microsecond || r.LossPercent != 0 {
+			t.Errorf("unexpected %s result %+v", node, r)
+		}
+	}
+	if results["XX - Unknown"].Err == nil {
+		t.Error("expected error for unknown node")
+	}
+	sort.Strings(locs)
+	if !reflect.DeepEqual(locs, []string{"losa", "toky"}) {
+		t.Error("unexpected requested locations", locs)
+	}
+}
+
+// TestCogentConcurrentQueries runs the queries while the nodes are
+// reloaded, it's meaningful w/ go test -race
+func TestCogentConcurrentQueries(t *testing.T) {
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, cogentNodesFixture)
+			return
+		}
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+
+	var wg sync.WaitGroup
+	run := func(f func(q *lg.Cogent)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q := &lg.Cogent{BaseURL: ts.URL, CacheFile: c.CacheFile, Node: "US - Atlanta"}
+			q.Set("8.8.8.8", "ipv4")
+			f(q)
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		run(func(q *lg.Cogent) { q.RefreshNodes() })
+		run(func(q *lg.Cogent) { q.InvalidateCache() })
+		run(func(q *lg.Cogent) { q.Ping() })
+		run(func(q *lg.Cogent) {
+			if lines, err := q.Trace(); err == nil {
+				for range lines {
+				}
+			}
+		})
+		run(func(q *lg.Cogent) {
+			for range q.BGP() {
+			}
+		})
+		run(func(q *lg.Cogent) { q.PingAll([]string{"US - Los Angeles", "JP - Tokyo"}) })
+		run(func(q *lg.Cogent) { q.BGPDiff("atla", "amst", "8.8.8.0/24") })
+	}
+	wg.Wait()
+
+	// the node tables and the cache file are consistent after the races
+	q := &lg.Cogent{BaseURL: ts.URL, CacheFile: c.CacheFile}
+	nodes := q.GetNodes()
+	sort.Strings(nodes)
+	if want := []string{"JP - Tokyo", "US - Atlanta", "US - Los Angeles"}; !reflect.DeepEqual(nodes, want) {
+		t.Errorf("expected %q but got %q", want, nodes)
+	}
+	if code, ok := q.NodeCode("US - Atlanta"); !ok || code != "atla" {
+		t.Error("expected atla but got", code)
+	}
+	if !q.ChangeNode("US - Atlanta") {
+		t.Fatal("ChangeNode failed")
+	}
+	q.Set("8.8.8.8", "ipv4")
+	if r, err := q.Ping(); err != nil || r != cogentPingOutput {
+		t.Errorf("unexpected ping %q %v", r, err)
+	}
+}
+
+func TestCogentPingStats(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<pre>"+pingNoReplyOutput+"</pre>")
+	}))
+	defer ts.Close()
+
+	c := &lg.Cogent{BaseURL: ts.URL}
+	c.Set("192.0.2.1", "ipv4")
+	s, err := c.PingStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.LossPercent != 100 || s.PacketsReceived != 0 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestCogentRetry(t *testing.T) {
+	var hits int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		switch r.FormValue("DST") {
+		case "retry.example.com":
+			if hits < 3 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		case "notfound.example.com":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case "down.example.com":
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		host    string
+		cogent  lg.Cogent
+		wantErr bool
+		want    int
+	}{
+		{"retry.example.com", lg.Cogent{}, false, 3},
+		{"notfound.example.com", lg.Cogent{}, true, 1},
+		{"down.example.com", lg.Cogent{Retries: 1}, true, 2},
+		{"down.example.com", lg.Cogent{DisableRetry: true}, true, 1},
+	}
+	for _, tt := range tests {
+		hits = 0
+		c := tt.cogent
+		c.BaseURL = ts.URL
+		c.Set(tt.host, "ipv4")
+		_, err := c.Ping()
+		if tt.wantErr != (err != nil) {
+			t.Errorf("%s: unexpected error %v", tt.host, err)
+		}
+		if hits != tt.want {
+			t.Errorf("%s: expected %d requests but got %d", tt.host, tt.want, hits)
+		}
+	}
+}
+
+func TestCogentRedirect(t *testing.T) {
+	var posts []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("DST") != "8.8.8.8" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		posts = append(posts, r.URL.Path)
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/lg", http.StatusTemporaryRedirect)
+		case "/lg":
+			http.Redirect(w, r, "/lookingglass.php", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			fmt.Fprint(w, cogentPingFixture)
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		path    string
+		cogent  lg.Cogent
+		wantErr bool
+		want    []string
+	}{
+		{"/", lg.Cogent{}, false, []string{"/", "/lg", "/lookingglass.php"}},
+		{"/", lg.Cogent{MaxRedirects: 1}, true, []string{"/", "/lg"}},
+		{"/", lg.Cogent{MaxRedirects: -1}, true, []string{"/"}},
+		{"/loop", lg.Cogent{}, true, []string{"/loop", "/loop", "/loop", "/loop"}},
+	}
+	for _, tt := range tests {
+		posts = nil
+		c := tt.cogent
+		c.BaseURL = ts.URL + tt.path
+		c.Set("8.8.8.8", "ipv4")
+		out, err := c.Ping()
+		if tt.wantErr != (err != nil) {
+			t.Errorf("%s %+v: unexpected error %v", tt.path, tt.cogent, err)
+		}
+		if !tt.wantErr && out != cogentPingOutput {
+			t.Errorf("%s: unexpected output %q", tt.path, out)
+		}
+		if !reflect.DeepEqual(posts, tt.want) {
+			t.Errorf("%s %+v: expected the posts %q but got %q", tt.path, tt.cogent, tt.want, posts)
+		}
+		if tt.path == "/loop" && (err == nil || !strings.Contains(err.Error(), "too many redirects")) {
+			t.Error("expected too many redirects error but got", err)
+		}
+	}
+}
+
+func TestCogentRedirectRefused(t *testing.T) {
+	var posted bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted = true
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer target.Close()
+	// the target is localhost so it's another host than 127.0.0.1
+	other := strings.Replace(target.URL, "127.0.0.1", "localhost", 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other+"/lookingglass.php", http.StatusFound)
+	}))
+	defer ts.Close()
+	tls := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL+"/lookingglass.php", http.StatusFound)
+	}))
+	defer tls.Close()
+
+	tests := []struct {
+		name   string
+		cogent lg.Cogent
+		want   string
+	}{
+		{"cross host", lg.Cogent{BaseURL: ts.URL}, "another host"},
+		{"downgrade", lg.Cogent{BaseURL: tls.URL, Client: tls.Client()}, "plain HTTP"},
+	}
+	for _, tt := range tests {
+		posted = false
+		c := tt.cogent
+		c.DisableRetry = true
+		c.Set("8.8.8.8", "ipv4")
+		if _, err := c.Ping(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected the refused redirect but got %v", tt.name, err)
+		}
+		if posted {
+			t.Errorf("%s: expected the form isn't posted to the location", tt.name)
+		}
+	}
+}
+
+func TestCogentCompressedResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var (
+			buf bytes.Buffer
+			zw  io.WriteCloser
+		)
+		switch r.FormValue("DST") {
+		case "gzip.example.com":
+			w.Header().Set("Content-Encoding", "gzip")
+			zw = gzip.NewWriter(&buf)
+		case "zlib.example.com":
+			w.Header().Set("Content-Encoding", "deflate")
+			zw = zlib.NewWriter(&buf)
+		default:
+			w.Header().Set("Content-Encoding", "deflate")
+			zw, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+		}
+		fmt.Fprint(zw, cogentPingFixture)
+		zw.Close()
+		w.Write(buf.Bytes())
+	}))
+	defer ts.Close()
+
+	for _, host := range []string{"gzip.example.com", "zlib.example.com", "deflate.example.com"} {
+		c := &lg.Cogent{BaseURL: ts.URL}
+		c.Set(host, "ipv4")
+		out, err := c.Ping()
+		if err != nil || out != cogentPingOutput {
+			t.Errorf("%s: unexpected output %q (%v)", host, out, err)
+		}
+	}
+}
+
+func TestCogentNodeCode(t *testing.T) {
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+
+	c.Set("8.8.8.8", "ipv4")
+	if code, ok := c.CurrentNodeCode(); !ok || code != "losa" {
+		t.Error("expected losa for the default node but got", code)
+	}
+	if code, ok := c.NodeCode("NL - Amsterdam"); !ok || code != "amst" {
+		t.Error("expected amst for the bgp node but got", code)
+	}
+	if _, ok := c.NodeCode("XX - Unknown"); ok {
+		t.Error("expected unknown node not to be found")
+	}
+}
+
+func TestCogentPingResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		want    string
+		err     error
+	}{
+		{"multiple pre", "<pre>line 1</pre><p>x</p><PRE class=\"out\">line 2</PRE>", "line 1\nline 2", nil},
+		{"missing pre", "<html><body><b>maintenance</b></body></html>", "maintenance", lg.ErrUnexpectedResponse},
+	}
+	for _, tt := range tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, tt.fixture)
+		}))
+		c := &lg.Cogent{BaseURL: ts.URL}
+		c.Set("8.8.8.8", "ipv4")
+		got, err := c.Ping()
+		if got != tt.want {
+			t.Errorf("%s: expected %q but got %q", tt.name, tt.want, got)
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected error %v but got %v", tt.name, tt.err, err)
+		}
+		ts.Close()
+	}
+}
+
+func TestCogentTraceContextCancel(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "traceroute to 8.8.8.8 (8.8.8.8), 30 hops max, 60 byte packets")
+		for i := 1; ; i++ {
+			select {
+			case <-r.Context().Done():
+				return
+			default:
+			}
+			fmt.Fprintf(w, " %d  10.0.0.%d (10.0.0.%d)  1.0 ms\n", i%30, i%255, i%255)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &lg.Cogent{BaseURL: ts.URL}
+	c.Set("8.8.8.8", "ipv4")
+	lines, err := c.TraceContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-lines
+	cancel()
+
+	// the consumer stops reading, the producer has to exit
+	// and close the channel by itself
+	time.Sleep(50 * time.Millisecond)
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-lines:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("expected the trace channel to be closed after cancel")
+		}
+	}
+}
+
+func TestCogentCapabilities(t *testing.T) {
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+
+	tests := []struct {
+		node             string
+		ping, trace, bgp bool
+	}{
+		{"US - Atlanta", true, true, true},
+		{"US - Los Angeles", true, true, false},
+		{"NL - Amsterdam", false, false, true},
+	}
+	for _, tt := range tests {
+		ping, trace, bgp := c.Capabilities(tt.node)
+		if ping != tt.ping || trace != tt.trace || bgp != tt.bgp {
+			t.Errorf("unexpected %s capabilities %v %v %v", tt.node, ping, trace, bgp)
+		}
+	}
+
+	c.Node = "NL - Amsterdam"
+	c.Host = "8.8.8.8"
+	if _, err := c.Ping(); !errors.Is(err, lg.ErrUnsupportedCommand) {
+		t.Error("expected ErrUnsupportedCommand for ping but got", err)
+	}
+	if _, err := c.Trace(); !errors.Is(err, lg.ErrUnsupportedCommand) {
+		t.Error("expected ErrUnsupportedCommand for trace but got", err)
+	}
+	c.Node = "US - Los Angeles"
+	if _, err := c.BGPRoutes(); !errors.Is(err, lg.ErrUnsupportedCommand) {
+		t.Error("expected ErrUnsupportedCommand for bgp but got", err)
+	}
+}
+
+const cogentBGPEntitiesFixture = `<html><body><pre><b>BGP table version is 3364457</b>
+   Network          Next Hop            Metric LocPrf Weight Path
+*&gt;&nbsp;8.8.8.0/24       154.54.11.90             0    100      0 <a href="/as/15169">15169</a> i
+</pre></body></html>`
+
+func TestCogentCleanOutput(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET":
+			fmt.Fprint(w, cogentNodesFixture)
+		case r.FormValue("CMD") == "BGP":
+			fmt.Fprint(w, cogentBGPEntitiesFixture)
+		default:
+			fmt.Fprint(w, "<pre><b>traceroute</b> to 8.8.8.8 (8.8.8.8)\n 1  a&amp;b.example.com (192.0.2.1)  1 ms</pre>")
+		}
+	}))
+	defer ts.Close()
+
+	dir, _ := ioutil.TempDir("", "mylg")
+	defer os.RemoveAll(dir)
+	c := &lg.Cogent{BaseURL: ts.URL, CacheFile: filepath.Join(dir, "cogent.nodes.json")}
+	c.GetNodes()
+	c.Set("8.8.8.8", "ipv4")
+	c.ChangeNode("US - Atlanta")
+
+	var got []string
+	for l := range c.BGP() {
+		got = append(got, l)
+	}
+	want := []string{"*> 8.8.8.0/24 via 154.54.11.90 as-path [15169] origin IGP"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q but got %q", want, got)
+	}
+
+	lines, err := c.Trace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	for l := range lines {
+		got = append(got, l)
+	}
+	want = []string{"traceroute to 8.8.8.8 (8.8.8.8)", " 1  a&b.example.com (192.0.2.1)  1 ms"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q but got %q", want, got)
+	}
+}
+
+func TestCogentPingOptions(t *testing.T) {
+	var form url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		count, size int
+		wantErr     bool
+		want        url.Values
+	}{
+		{0, 0, false, url.Values{}},
+		{3, 100, false, url.Values{"COUNT": {"3"}, "SIZE": {"100"}}},
+		{11, 0, true, nil},
+		{0, 1500, true, nil},
+		{-1, 0, true, nil},
+	}
+	for _, tt := range tests {
+		form = nil
+		c := &lg.Cogent{BaseURL: ts.URL, Count: tt.count, Size: tt.size}
+		c.Set("8.8.8.8", "ipv4")
+		_, err := c.Ping()
+		if tt.wantErr {
+			if err == nil || form != nil {
+				t.Errorf("expected count %d size %d to be rejected locally", tt.count, tt.size)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		for _, k := range []string{"COUNT", "SIZE"} {
+			if form.Get(k) != tt.want.Get(k) {
+				t.Errorf("expected %s=%q but got %q", k, tt.want.Get(k), form.Get(k))
+			}
+		}
+	}
+}
+
+// fakeMetrics records the observations
+type fakeMetrics struct {
+	requests []string
+	rtts     []time.Duration
+}
+
+func (m *fakeMetrics) ObserveRequest(provider, node, cmd string, d time.Duration, errType string) {
+	m.requests = append(m.requests, strings.Join([]string{provider, node, cmd, errType}, "|"))
+}
+
+func (m *fakeMetrics) ObservePing(provider, node string, rtt time.Duration) {
+	m.rtts = append(m.rtts, rtt)
+}
+
+func TestCogentMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("DST") == "down.example.com" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+
+	m := &fakeMetrics{}
+	lg.SetMetrics(m)
+	defer lg.SetMetrics(nil)
+
+	c := &lg.Cogent{BaseURL: ts.URL, DisableRetry: true}
+	c.Set("192.0.2.1", "ipv4")
+	if _, err := c.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	c.Set("down.example.com", "ipv4")
+	c.Ping()
+
+	expected := []string{
+		"cogent|US - Los Angeles|ping|",
+		"cogent|US - Los Angeles|ping|http",
+	}
+	if !reflect.DeepEqual(m.requests, expected) {
+		t.Errorf("expected requests %q but got %q", expected, m.requests)
+	}
+	if len(m.rtts) != 1 || m.rtts[0] != 1210*time.Microsecond {
+		t.Error("expected the ping rtt but got", m.rtts)
+	}
+}
+
+func TestCogentDryRun(t *testing.T) {
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+
+	c.BaseURL = "http://192.0.2.1/lookingglass.php"
+	c.DryRun = true
+	c.Set("8.8.8.8", "ipv4")
+	c.ChangeNode("lax")
+	out, err := c.Ping()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "POST http://192.0.2.1/lookingglass.php\n" +
+		"Content-Type: application/x-www-form-urlencoded\n\n" +
+		"CMD=P4\nDST=8.8.8.8\nFKT=go!\nLOC=losa"
+	if out != expected {
+		t.Errorf("expected %q but got %q", expected, out)
+	}
+
+	lines, err := c.Trace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var trace []string
+	for l := range lines {
+		trace = append(trace, l)
+	}
+	if len(trace) != 1 || !strings.Contains(trace[0], "CMD=T4") {
+		t.Error("expected a single trace request description but got", trace)
+	}
+}
+
+func TestCogentProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer proxy.Close()
+
+	c := &lg.Cogent{BaseURL: "http://lg.example.com/lookingglass.php", ProxyURL: proxy.URL}
+	c.Set("192.0.2.1", "ipv4")
+	if _, err := c.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if proxied != "http://lg.example.com/lookingglass.php" {
+		t.Error("expected the request through the proxy but got", proxied)
+	}
+
+	for _, p := range []string{"ftp://192.0.2.1:21", "socks4://192.0.2.1:1080", "http://"} {
+		c.ProxyURL = p
+		if _, err := c.Ping(); err == nil || !strings.Contains(err.Error(), "proxy") {
+			t.Errorf("%s: expected proxy error but got %v", p, err)
+		}
+	}
+}
+
+func TestCogentLocalAddr(t *testing.T) {
+	var remote string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote = r.RemoteAddr
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+
+	c := &lg.Cogent{BaseURL: ts.URL}
+	c.Set("192.0.2.1", "ipv4")
+	for _, addr := range []string{"192.0.2.250", "eth0"} {
+		c.LocalAddr = addr
+		if _, err := c.Ping(); err == nil || !strings.Contains(err.Error(), "local address") {
+			t.Errorf("%s: expected local address error but got %v", addr, err)
+		}
+	}
+
+	// 127.0.0.1 is the default source so the binding is checked w/ 127.0.0.2
+	if _, err := lg.ValidateLocalAddr("127.0.0.2"); err != nil {
+		t.Skip(err)
+	}
+	c.LocalAddr = "127.0.0.2"
+	if _, err := c.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if host, _, _ := net.SplitHostPort(remote); host != "127.0.0.2" {
+		t.Error("expected the request from 127.0.0.2 but got", remote)
+	}
+}
+
+func BenchmarkCogentFetchNodes(b *testing.B) {
+	var page strings.Builder
+	page.WriteString(`<script>switch (document.forms[0].CMD.value) { case "BGP":`)
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&page, `document.forms[0].LOC.options[%d] = new Option("US - Node %d","bgp%d");`, i, i, i)
+	}
+	page.WriteString("default:")
+	for i := 0; i < 300; i++ {
+		fmt.Fprintf(&page, `document.forms[0].LOC.options[%d] = new Option("US - Node %d","node%d");`, i, i, i)
+	}
+	page.WriteString("}</script>")
+	body := page.String()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+
+	c := &lg.Cogent{BaseURL: ts.URL}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := c.FetchNodes(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCogentFetchNodesNames(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<script>
+	switch (document.forms[0].CMD.value) {
+	case "BGP":
+		document.forms[0].LOC.options[0] = new Option("US - Washington D.C.","wash");
+		break;
+	default:
+		document.forms[0].LOC.options[0] = new Option("US - Washington D.C.","wash");
+		document.forms[0].LOC.options[1] = new Option("UK - London (Docklands)","lond");
+		document.forms[0].LOC.options[2] = new Option("US - Dallas/Fort Worth","dfw1");
+	}
+</script>`)
+	}))
+	defer ts.Close()
+
+	c := &lg.Cogent{BaseURL: ts.URL}
+	nodes, bgpNodes, err := c.FetchNodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"US - Washington D.C.":    "wash",
+		"UK - London (Docklands)": "lond",
+		"US - Dallas/Fort Worth":  "dfw1",
+	}
+	if !reflect.DeepEqual(nodes, expected) {
+		t.Errorf("expected nodes %v but got %v", expected, nodes)
+	}
+	if bgpNodes["US - Washington D.C."] != "wash" || len(bgpNodes) != 1 {
+		t.Error("expected the bgp node but got", bgpNodes)
+	}
+}
+
+func TestCogentNodesByRegion(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<script>
+	default:
+		document.forms[0].LOC.options[0] = new Option("US - Los Angeles","losa");
+		document.forms[0].LOC.options[1] = new Option("US - Atlanta","atla");
+		document.forms[0].LOC.options[2] = new Option("JP - Tokyo","toky");
+		document.forms[0].LOC.options[3] = new Option("NL - Amsterdam","amst");
+		document.forms[0].LOC.options[4] = new Option("XX - Nowhere","nowh");
+		document.forms[0].LOC.options[5] = new Option("Lab","lab1");
+</script>`)
+	}))
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := &lg.Cogent{BaseURL: ts.URL, CacheFile: filepath.Join(dir, "cogent.nodes.json")}
+	expected := map[string][]string{
+		"US":     {"US - Atlanta", "US - Los Angeles"},
+		"Asia":   {"JP - Tokyo"},
+		"Europe": {"NL - Amsterdam"},
+		"Other":  {"Lab", "XX - Nowhere"},
+	}
+	if got := c.GetNodesByRegion(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+	if len(c.GetNodes()) != 6 {
+		t.Error("expected the flat node list but got", c.GetNodes())
+	}
+}
+
+func TestCogentLastRaw(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html>unexpected format</html>")
+	}))
+	defer ts.Close()
+
+	c := &lg.Cogent{BaseURL: ts.URL}
+	c.Set("192.0.2.1", "ipv4")
+	c.Ping()
+	if c.LastRaw() != "" {
+		t.Error("expected no raw response w/o KeepRaw but got", c.LastRaw())
+	}
+
+	c.KeepRaw = true
+	if _, err := c.Ping(); err == nil {
+		t.Error("expected the parsing error")
+	}
+	if c.LastRaw() != "<html>unexpected format</html>" {
+		t.Error("expected the raw response but got", c.LastRaw())
+	}
+
+	lines, err := c.Trace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range lines {
+	}
+	if c.LastRaw() != "<html>unexpected format</html>" {
+		t.Error("expected the raw trace response but got", c.LastRaw())
+	}
+}
+
+func TestCogentGetNodesContext(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(done)
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "cogent.nodes.json")
+	stale := `{"timestamp":"2016-01-01T00:00:00Z","nodes":{"US - Los Angeles":"losa","JP - Tokyo":"toky"}}`
+	if err := ioutil.WriteFile(file, []byte(stale), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &lg.Cogent{BaseURL: ts.URL, CacheFile: file}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	nodes, err := c.GetNodesContext(ctx)
+	if err == nil {
+		t.Error("expected the fetch timeout error")
+	}
+	if time.Since(start) > time.Second {
+		t.Error("expected GetNodesContext to respect the deadline but it took", time.Since(start))
+	}
+	if !reflect.DeepEqual(nodes, []string{"JP - Tokyo", "US - Los Angeles"}) {
+		t.Error("expected the stale cached nodes but got", nodes)
+	}
+}
+
+func TestCogentNearestNode(t *testing.T) {
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+
+	var (
+		mu   sync.Mutex
+		locs []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		locs = append(locs, r.FormValue("LOC"))
+		mu.Unlock()
+		if r.FormValue("DST") != "192.0.2.1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		out := cogentPingOutput
+		if r.FormValue("LOC") == "atla" {
+			out = strings.Replace(out, "1.210/1.210/1.210", "20.100/20.100/20.100", 1)
+		}
+		fmt.Fprint(w, "<pre>"+out+"</pre>")
+	}))
+	defer ts.Close()
+
+	c.BaseURL = ts.URL
+	c.DisableRetry = true
+	node, rtt, err := c.NearestNode("192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node != "JP - Tokyo" || rtt != 1210*time.Microsecond || c.Node != "JP - Tokyo" {
+		t.Errorf("expected JP - Tokyo w/ 1.21ms but got %s w/ %s", node, rtt)
+	}
+	// one node per region
+	sort.Strings(locs)
+	if !reflect.DeepEqual(locs, []string{"atla", "toky"}) {
+		t.Error("unexpected sampled locations", locs)
+	}
+}
+
+func TestCogentFailover(t *testing.T) {
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+
+	var (
+		mu     sync.Mutex
+		probes = map[string]int{}
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("DST") == "8.8.8.8" {
+			mu.Lock()
+			probes[r.FormValue("LOC")]++
+			mu.Unlock()
+		}
+		if r.FormValue("LOC") == "losa" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "<pre>"+cogentPingOutput+"</pre>")
+	}))
+	defer ts.Close()
+
+	c.BaseURL = ts.URL
+	c.DisableRetry = true
+	c.Failover = true
+	c.Node = "US - Los Angeles"
+	c.Set("192.0.2.1", "")
+	for i := 0; i < 2; i++ {
+		r, err := c.Ping()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r != cogentPingOutput {
+			t.Error("unexpected ping output", r)
+		}
+		if c.ServedNode() != "US - Atlanta" || c.Node != "US - Los Angeles" {
+			t.Errorf("expected the request is served by US - Atlanta but got %s", c.ServedNode())
+		}
+	}
+	// the health status is cached
+	if probes["losa"] != 1 || probes["atla"] != 1 {
+		t.Error("expected one probe per node but got", probes)
+	}
+	if !c.NodeHealthy("US - Atlanta") || c.NodeHealthy("US - Los Angeles") {
+		t.Error("unexpected nodes health")
+	}
+}
+
+func TestCogentHealthTimeoutNotCached(t *testing.T) {
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+
+	var (
+		mu   sync.Mutex
+		down = true
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		d := down
+		mu.Unlock()
+		if d {
+			// the body is read so the canceled request is detected
+			r.ParseForm()
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprint(w, "<pre>"+cogentPingOutput+"</pre>")
+	}))
+	defer ts.Close()
+
+	c.BaseURL = ts.URL
+	c.DisableRetry = true
+	c.Failover = true
+	c.Node = "JP - Tokyo"
+	c.Set("192.0.2.1", "")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := c.PingContext(ctx); err == nil {
+		t.Error("expected the timeout error")
+	}
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	// the timed out probe isn't cached as unhealthy
+	if !c.NodeHealthy("JP - Tokyo") {
+		t.Error("expected JP - Tokyo to be probed again")
+	}
+}
+
+func TestCogentRateLimited(t *testing.T) {
+	const page = `<html><body><h1>Too many requests</h1>
+<p>Please slow down and try again in 30 seconds.</p></body></html>`
+
+	tests := []struct {
+		name    string
+		status  int
+		header  string
+		fixture string
+		want    time.Duration
+	}{
+		{"page", 200, "", page, 30 * time.Second},
+		{"status", http.StatusTooManyRequests, "120", "", 2 * time.Minute},
+		{"status w/o retry-after", http.StatusTooManyRequests, "", "", time.Minute},
+	}
+	for _, tt := range tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.header != "" {
+				w.Header().Set("Retry-After", tt.header)
+			}
+			w.WriteHeader(tt.status)
+			fmt.Fprint(w, tt.fixture)
+		}))
+		c := &lg.Cogent{BaseURL: ts.URL, DisableRetry: true}
+		c.Set("8.8.8.8", "ipv4")
+		_, pingErr := c.Ping()
+		_, traceErr := lg.Run(c, lg.CmdTrace, "8.8.8.8")
+		ts.Close()
+
+		for cmd, err := range map[string]error{"ping": pingErr, "trace": traceErr} {
+			var rl *lg.RateLimitError
+			if !errors.Is(err, lg.ErrRateLimited) || !errors.As(err, &rl) {
+				t.Errorf("%s %s: expected rate limited error but got %v", tt.name, cmd, err)
+				continue
+			}
+			if rl.RetryAfter != tt.want {
+				t.Errorf("%s %s: expected retry after %s but got %s", tt.name, cmd, tt.want, rl.RetryAfter)
+			}
+		}
+	}
+}
+
+func TestCogentSource(t *testing.T) {
+	const fixture = cogentNodesFixture + `<script>
+	document.forms[0].SRC.options[0] = new Option("Default","");
+	document.forms[0].SRC.options[1] = new Option("IPv4 anycast","38.104.0.1");
+</script>`
+
+	var src []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("CMD") == "" {
+			fmt.Fprint(w, fixture)
+			return
+		}
+		src = append(src, r.FormValue("SRC"))
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := &lg.Cogent{BaseURL: ts.URL, CacheFile: filepath.Join(dir, "cogent.nodes.json"), DisableRetry: true}
+	c.GetNodes()
+	if len(c.Nodes) != 3 {
+		t.Fatal("expected the sources aren't taken as nodes but got", c.Nodes)
+	}
+	c.Set("8.8.8.8", "ipv4")
+	for _, s := range []string{"ipv4 anycast", "38.104.0.1"} {
+		c.Source = s
+		if _, err := c.Ping(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(src, []string{"38.104.0.1", "38.104.0.1"}) {
+		t.Error("unexpected sources", src)
+	}
+	c.Source = "paris"
+	if _, err := c.Ping(); err == nil || !strings.Contains(err.Error(), "IPv4 anycast") {
+		t.Error("expected invalid source error but got", err)
+	}
+
+	// the source is ignored if the lg doesn't offer it
+	src = nil
+	f, cleanup := newCogentFixture(t)
+	defer cleanup()
+	f.BaseURL, f.Source = ts.URL, "ipv4 anycast"
+	f.Set("8.8.8.8", "ipv4")
+	if _, err := f.Ping(); err != nil || len(src) != 1 || src[0] != "" {
+		t.Error("expected the source is ignored but got", src, err)
+	}
+}
+
+func TestCogentPingBoth(t *testing.T) {
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+
+	var (
+		mu   sync.Mutex
+		cmds []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		cmds = append(cmds, r.FormValue("CMD"))
+		mu.Unlock()
+		if strings.HasPrefix(r.FormValue("CMD"), "T") {
+			fmt.Fprint(w, cogentTraceFixture)
+			return
+		}
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+	c.BaseURL = ts.URL
+	c.DisableRetry = true
+	c.Node = "US - Los Angeles"
+
+	r := c.PingBoth("8.8.8.8")
+	if r.IPv4.Stats == nil || r.IPv4.Stats.PacketsReceived != 1 || r.IPv4.Err != nil {
+		t.Errorf("unexpected IPv4 result %+v", r.IPv4)
+	}
+	if r.IPv6.Stats != nil || r.IPv6.Note != "8.8.8.8 has no IPv6 address" {
+		t.Errorf("expected IPv6 is skipped but got %+v", r.IPv6)
+	}
+
+	tr := c.TraceBoth("2001:db8::1")
+	if tr.IPv4.Note == "" || len(tr.IPv6.Lines) != 3 {
+		t.Errorf("unexpected trace result %+v", tr)
+	}
+	if !reflect.DeepEqual(cmds, []string{"P4", "T6"}) {
+		t.Error("unexpected commands", cmds)
+	}
+}
+
+func TestCogentFetchNodesProgress(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, cogentNodesFixture)
+	}))
+	defer ts.Close()
+
+	c := &lg.Cogent{BaseURL: ts.URL, DisableRetry: true}
+	events, result := c.FetchNodesProgress(context.Background())
+	var stages []string
+	for e := range events {
+		stages = append(stages, e.Stage.String())
+	}
+	if want := []string{"connecting", "downloading", "parsing", "done"}; !reflect.DeepEqual(stages, want) {
+		t.Errorf("expected %q but got %q", want, stages)
+	}
+	r := <-result
+	if r.Err != nil || len(r.Nodes) != 3 || len(r.BGPNodes) != 2 {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	ts.Close()
+	events, result = c.FetchNodesProgress(context.Background())
+	if r := <-result; r.Err == nil {
+		t.Error("expected unreachable error")
+	}
+	if n := len(events); n != 2 {
+		t.Error("expected connecting and done events but got", n)
+	}
+}
+
+func TestCogentTraceLimits(t *testing.T) {
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<pre>\ntraceroute to 192.0.2.1 (192.0.2.1), 30 hops max, 60 byte packets\n")
+		for i := 1; i <= 5; i++ {
+			fmt.Fprintf(w, " %d  * * *\n", i)
+		}
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer ts.Close()
+	c.BaseURL = ts.URL
+	c.DisableRetry = true
+	c.Set("192.0.2.1", "")
+
+	tests := []struct {
+		maxHops int
+		wait    time.Duration
+		want    int
+	}{
+		{2, 0, 3},
+		{0, 200 * time.Millisecond, 6},
+	}
+	for _, tt := range tests {
+		c.MaxHops, c.WaitTime = tt.maxHops, tt.wait
+		start := time.Now()
+		lines, err := c.Trace()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var n int
+		for range lines {
+			n++
+		}
+		if n != tt.want {
+			t.Errorf("max hops %d wait %s: expected %d lines but got %d", tt.maxHops, tt.wait, tt.want, n)
+		}
+		if time.Since(start) > 2*time.Second {
+			t.Error("expected the trace is stopped early")
+		}
+	}
+
+	c.MaxHops = -1
+	if _, err := c.Trace(); err == nil {
+		t.Error("expected invalid max hops error")
+	}
+}
+
+func TestCogentGetNodesUnique(t *testing.T) {
+	const fixture = `<script>
+	default:
+		document.forms[0].LOC.options[0] = new Option("US - Los Angeles","lax2");
+		document.forms[0].LOC.options[1] = new Option("US - Atlanta","atla");
+		document.forms[0].LOC.options[2] = new Option("US -  Los Angeles ","losa");
+		document.forms[0].LOC.options[3] = new Option("JP - Tokyo","toky");
+		document.forms[0].LOC.options[4] = new Option("US - Atlanta","atla");
+</script>`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, fixture)
+	}))
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	want := []string{"JP - Tokyo", "US - Atlanta", "US - Los Angeles"}
+	for i := 0; i < 3; i++ {
+		c := &lg.Cogent{BaseURL: ts.URL, CacheFile: filepath.Join(dir, "cogent.nodes.json")}
+		if got := c.GetNodes(); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %q but got %q", want, got)
+		}
+		if code, _ := c.NodeCode("US - Los Angeles"); code != "losa" {
+			t.Error("expected the last seen code but got", code)
+		}
+	}
+}
+
+func TestCogentNodesSnapshot(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	snapshot := filepath.Join(dir, "snapshot.json")
+	ioutil.WriteFile(snapshot, []byte(`{"timestamp": "2020-01-02T00:00:00Z",
+		"nodes": {"US - Los Angeles": "losa", "US - Atlanta": "atla", "JP - Tokyo": "toky"}}`), 0600)
+
+	c := &lg.Cogent{
+		BaseURL:      ts.URL,
+		DisableRetry: true,
+		CacheFile:    filepath.Join(dir, "cogent.nodes.json"),
+		SnapshotFile: snapshot,
+	}
+	nodes, err := c.GetNodesContext(context.Background())
+	if !errors.Is(err, lg.ErrStaleNodes) || !strings.Contains(err.Error(), "2020-01-02") {
+		t.Error("expected stale nodes error but got", err)
+	}
+	if want := []string{"JP - Tokyo", "US - Atlanta", "US - Los Angeles"}; !reflect.DeepEqual(nodes, want) {
+		t.Errorf("expected %q but got %q", want, nodes)
+	}
+}
+
+func TestCogentEmbeddedSnapshot(t *testing.T) {
+	var snapshot struct {
+		Timestamp time.Time         `json:"timestamp"`
+		Nodes     map[string]string `json:"nodes"`
+	}
+	b, err := ioutil.ReadFile("cogent_nodes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &snapshot); err != nil {
+		t.Fatal("invalid bundled snapshot", err)
+	}
+	if snapshot.Timestamp.IsZero() || len(snapshot.Nodes) == 0 {
+		t.Fatal("the bundled snapshot is empty, it should be generated by go generate ./lg")
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := &lg.Cogent{BaseURL: ts.URL, DisableRetry: true, CacheFile: filepath.Join(dir, "cogent.nodes.json")}
+	nodes, err := c.GetNodesContext(context.Background())
+	if !errors.Is(err, lg.ErrStaleNodes) {
+		t.Error("expected stale nodes error but got", err)
+	}
+	if len(nodes) != len(snapshot.Nodes) {
+		t.Errorf("expected %d nodes of the bundled snapshot but got %d", len(snapshot.Nodes), len(nodes))
+	}
+}
+
+func TestCogentFormFields(t *testing.T) {
+	const fixture = `<form method="post">
+<select name="QUERY"><option value="P4">ping</option><option value="T4">trace</option></select>
+<input type="text" name="ADDR">
+<select name="ROUTER"></select>
+<input type="submit" name="RUN" value="go">
+</form>` + cogentNodesFixture
+
+	var forms []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, fixture)
+			return
+		}
+		r.ParseForm()
+		forms = append(forms, r.PostForm.Encode())
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i := 0; i < 2; i++ {
+		// the first one scrapes the form and the second one reads the cache
+		c := &lg.Cogent{BaseURL: ts.URL, CacheFile: filepath.Join(dir, "cogent.nodes.json"), Parser: lg.DefaultCogentParser()}
+		c.GetNodes()
+		c.Set("8.8.8.8", "ipv4")
+		if _, err := c.Ping(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "ADDR=8.8.8.8&QUERY=P4&ROUTER=losa&RUN=go"
+	if len(forms) != 2 || forms[0] != want || forms[1] != want {
+		t.Errorf("expected %q but got %q", want, forms)
+	}
+}
+
+func TestCogentHeaders(t *testing.T) {
+	var agents, tokens []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.UserAgent())
+		tokens = append(tokens, r.Header.Get("X-Token"))
+		fmt.Fprint(w, cogentNodesFixture)
+	}))
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lg.SetUserAgent("mylg/test")
+	defer lg.SetUserAgent("mylg")
+	c := &lg.Cogent{BaseURL: ts.URL, CacheFile: filepath.Join(dir, "cogent.nodes.json")}
+	c.FetchNodes()
+	c.SetHeader("User-Agent", "custom")
+	c.SetHeader("X-Token", "secret")
+	c.FetchNodes()
+	if !reflect.DeepEqual(agents, []string{"mylg/test", "custom"}) || !reflect.DeepEqual(tokens, []string{"", "secret"}) {
+		t.Errorf("unexpected headers %q %q", agents, tokens)
+	}
+}
+
+func TestCogentResolveLocal(t *testing.T) {
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+
+	var dst []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dst = append(dst, r.FormValue("DST"))
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+	c.BaseURL = ts.URL
+
+	c.Set("localhost", "ipv4")
+	if _, err := c.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	c.ResolveLocal = true
+	if _, err := c.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst, []string{"localhost", "127.0.0.1"}) || c.Resolved() != "127.0.0.1" {
+		t.Errorf("unexpected targets %q, resolved %q", dst, c.Resolved())
+	}
+	c.ResolveIndex = 9
+	if _, err := c.Ping(); err == nil || !strings.Contains(err.Error(), "127.0.0.1") {
+		t.Error("expected invalid address error but got", err)
+	}
+}
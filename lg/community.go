@@ -0,0 +1,80 @@
+package lg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+)
+
+// wellKnownCommunities maps the well-known (RFC1997, RFC7999, RFC8326)
+// and the Cogent (AS174) communities to their descriptions
+var wellKnownCommunities = map[string]string{
+	"65535:0":      "graceful shutdown",
+	"65535:666":    "blackhole",
+	"65535:65281":  "no export",
+	"65535:65282":  "no advertise",
+	"65535:65283":  "no export subconfed",
+	"65535:65284":  "no peer",
+	"no-export":    "no export",
+	"no-advertise": "no advertise",
+	"local-AS":     "no export subconfed",
+
+	"174:10":   "cogent: set local preference 10",
+	"174:70":   "cogent: set local preference 70",
+	"174:120":  "cogent: set local preference 120",
+	"174:140":  "cogent: set local preference 140",
+	"174:990":  "cogent: don't advertise to the peers",
+	"174:3000": "cogent: don't advertise to the peers",
+	"174:3001": "cogent: prepend 1x to the peers",
+	"174:3002": "cogent: prepend 2x to the peers",
+	"174:3003": "cogent: prepend 3x to the peers",
+}
+
+var (
+	communitiesMu     sync.RWMutex
+	customCommunities map[string]string
+)
+
+// LoadCommunities reads a json object of the community to description
+// mapping, the custom descriptions have priority over the embedded ones
+func LoadCommunities(file string) error {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var m map[string]string
+	if err := json.Unmarshal(b, &m); err != nil {
+		return fmt.Errorf("invalid communities %s: %v", file, err)
+	}
+	communitiesMu.Lock()
+	customCommunities = m
+	communitiesMu.Unlock()
+	return nil
+}
+
+// CommunityDescription returns the community description,
+// the unknown community is returned as it is
+func CommunityDescription(c string) string {
+	communitiesMu.RLock()
+	d, ok := customCommunities[c]
+	communitiesMu.RUnlock()
+	if ok {
+		return d
+	}
+	if d, ok := wellKnownCommunities[c]; ok {
+		return d
+	}
+	return c
+}
+
+// DescribeCommunities sets the communities descriptions of the routes
+func DescribeCommunities(routes []BGPRoute) {
+	for i := range routes {
+		var d []string
+		for _, c := range routes[i].Communities {
+			d = append(d, CommunityDescription(c))
+		}
+		routes[i].CommunityDescriptions = d
+	}
+}
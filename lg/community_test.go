@@ -0,0 +1,52 @@
+package lg_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestDescribeCommunities(t *testing.T) {
+	routes := []lg.BGPRoute{
+		{Prefix: "8.8.8.0/24", Communities: []string{"174:3001", "65535:65281", "15169:100"}},
+		{Prefix: "8.8.4.0/24"},
+	}
+	lg.DescribeCommunities(routes)
+	want := []string{"cogent: prepend 1x to the peers", "no export", "15169:100"}
+	if !reflect.DeepEqual(routes[0].CommunityDescriptions, want) {
+		t.Errorf("expected %q but got %q", want, routes[0].CommunityDescriptions)
+	}
+	if routes[1].CommunityDescriptions != nil {
+		t.Error("expected no descriptions but got", routes[1].CommunityDescriptions)
+	}
+
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "communities.json")
+	if err := ioutil.WriteFile(file, []byte(`{"15169:100":"google: internal","174:3001":"prepend once"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := lg.LoadCommunities(file); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		ioutil.WriteFile(file, []byte(`{}`), 0600)
+		lg.LoadCommunities(file)
+	}()
+
+	lg.DescribeCommunities(routes)
+	want = []string{"prepend once", "no export", "google: internal"}
+	if !reflect.DeepEqual(routes[0].CommunityDescriptions, want) {
+		t.Errorf("expected %q but got %q", want, routes[0].CommunityDescriptions)
+	}
+	if err := lg.LoadCommunities(filepath.Join(dir, "none.json")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
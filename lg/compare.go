@@ -0,0 +1,44 @@
+package lg
+
+import (
+	"context"
+	"sync"
+)
+
+// CompareProviders runs the command against the providers concurrently
+// through Run, each provider is switched to its default node so the
+// carriers are compared the same way and its node and host are restored
+// after the query. the trace and bgp lines are collected into Text and
+// the results are keyed by the provider names
+func CompareProviders(cmd Command, host string, providers map[string]LookingGlass) map[string]Result {
+	var (
+		results = make(map[string]Result, len(providers))
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+	)
+	for name, p := range providers {
+		wg.Add(1)
+		go func(name string, p LookingGlass) {
+			defer wg.Done()
+			var (
+				node            = p.GetNode()
+				target, version = p.Get()
+			)
+			defer func() {
+				p.Set(target, version)
+				if node != "" && p.GetNode() != node {
+					p.ChangeNode(node)
+				}
+			}()
+			if d := p.GetDefaultNode(); d != "" {
+				p.ChangeNode(d)
+			}
+			r := collect(context.Background(), p, cmd, host)
+			mu.Lock()
+			results[name] = r
+			mu.Unlock()
+		}(name, p)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,209 @@
+package lg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Config represents the looking glass user preferences
+type Config struct {
+	// Provider is the looking glass which is selected by default
+	Provider string `json:"provider,omitempty"`
+	// Nodes maps the provider name to its default node
+	Nodes map[string]string `json:"nodes,omitempty"`
+	// IPVersion is used once the ip version isn't set, ipv4 or ipv6
+	IPVersion string `json:"ip_version,omitempty"`
+	// HTTPTimeout is the looking glass requests timeout e.g. 30s
+	HTTPTimeout string `json:"http_timeout,omitempty"`
+	// RateLimit is the max Cogent requests per second, zero keeps
+	// the default and negative value disables the rate limit
+	RateLimit float64 `json:"rate_limit,omitempty"`
+	// CommunitiesFile is a json file of the bgp community to
+	// description mapping, see LoadCommunities
+	CommunitiesFile string `json:"communities_file,omitempty"`
+	// CogentPatterns overrides the Cogent parser patterns
+	// by name, see CogentParser
+	CogentPatterns map[string]string `json:"cogent_patterns,omitempty"`
+	// UserAgent replaces the User-Agent of the looking glass requests
+	UserAgent string `json:"user_agent,omitempty"`
+	// RawOutput keeps the ANSI escape codes and the control
+	// characters of the looking glass output, see SetRawOutput
+	RawOutput bool `json:"raw_output,omitempty"`
+	// Presets are the saved queries, see SavePreset and RunPreset
+	Presets []Preset `json:"presets,omitempty"`
+}
+
+// ConfigFile is the looking glass config file path
+var ConfigFile = configFile()
+
+var (
+	configMu   sync.RWMutex
+	userConfig Config
+)
+
+// configFile returns the default config file path
+func configFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "mylg", "lg.json")
+}
+
+// LoadConfig reads the config file and applies it, the
+// zero config is returned if the file doesn't exist
+func LoadConfig() (Config, error) {
+	var c Config
+	b, err := ioutil.ReadFile(ConfigFile)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&c); err != nil {
+		return c, fmt.Errorf("invalid config %s: %v", ConfigFile, err)
+	}
+	if err = c.validate(); err != nil {
+		return c, fmt.Errorf("invalid config %s: %v", ConfigFile, err)
+	}
+	c.apply()
+	return c, nil
+}
+
+// SaveConfig validates, writes and applies the config
+func SaveConfig(c Config) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ConfigFile), 0700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(ConfigFile, b, 0600); err != nil {
+		return err
+	}
+	c.apply()
+	return nil
+}
+
+// DefaultProvider returns the configured provider or telia
+func DefaultProvider() string {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	if userConfig.Provider != "" {
+		return userConfig.Provider
+	}
+	return "telia"
+}
+
+// validate checks the config values
+func (c Config) validate() error {
+	if c.Provider != "" {
+		if _, err := New(c.Provider); err != nil {
+			return err
+		}
+	}
+	for name := range c.Nodes {
+		if _, err := New(name); err != nil {
+			return fmt.Errorf("nodes: %v", err)
+		}
+	}
+	switch c.IPVersion {
+	case "", "ipv4", "ipv6":
+	default:
+		return fmt.Errorf("ip_version should be ipv4 or ipv6 but it's %q", c.IPVersion)
+	}
+	if c.HTTPTimeout != "" {
+		d, err := time.ParseDuration(c.HTTPTimeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("http_timeout should be a positive duration but it's %q", c.HTTPTimeout)
+		}
+	}
+	if _, err := c.cogentParser(); err != nil {
+		return err
+	}
+	names := map[string]bool{}
+	for _, ps := range c.Presets {
+		if err := ps.validate(); err != nil {
+			return err
+		}
+		if names[ps.Name] {
+			return fmt.Errorf("preset %s is duplicated", ps.Name)
+		}
+		names[ps.Name] = true
+	}
+	return nil
+}
+
+// cogentParser returns the default Cogent parser w/ the patterns overrides
+func (c Config) cogentParser() (*CogentParser, error) {
+	cp := DefaultCogentParser()
+	for name, expr := range c.CogentPatterns {
+		if err := cp.Override(name, expr); err != nil {
+			return nil, err
+		}
+	}
+	return cp, nil
+}
+
+// apply makes the config effective
+func (c Config) apply() {
+	configMu.Lock()
+	userConfig = c
+	configMu.Unlock()
+
+	if d, err := time.ParseDuration(c.HTTPTimeout); err == nil {
+		SetHTTPClient(&http.Client{Timeout: d})
+	}
+	if c.RateLimit != 0 {
+		SetRateLimit(c.RateLimit)
+	}
+	if c.UserAgent != "" {
+		SetUserAgent(c.UserAgent)
+	}
+	SetRawOutput(c.RawOutput)
+	if cp, err := c.cogentParser(); err == nil {
+		SetCogentParser(cp)
+	}
+	if c.CommunitiesFile != "" {
+		if err := LoadCommunities(c.CommunitiesFile); err != nil {
+			getLogger().Warn("config: %s", err)
+		}
+	}
+}
+
+// defaultNode returns the configured provider node or the fallback
+func defaultNode(provider, fallback string) string {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	if n, ok := userConfig.Nodes[provider]; ok && n != "" {
+		return n
+	}
+	return fallback
+}
+
+// ipVersion returns the version or the configured ip version
+func ipVersion(version string) string {
+	if version != "" {
+		return version
+	}
+	configMu.RLock()
+	defer configMu.RUnlock()
+	if userConfig.IPVersion != "" {
+		return userConfig.IPVersion
+	}
+	return "ipv4"
+}
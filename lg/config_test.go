@@ -0,0 +1,80 @@
+package lg_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	lg.ConfigFile = filepath.Join(dir, "mylg", "lg.json")
+	defer lg.SaveConfig(lg.Config{})
+
+	if _, err := lg.LoadConfig(); err != nil {
+		t.Error("expected no error w/o config file but got", err)
+	}
+
+	c := lg.Config{
+		Provider:  "cogent",
+		Nodes:     map[string]string{"cogent": "NL - Amsterdam"},
+		IPVersion: "ipv6",
+	}
+	if err := lg.SaveConfig(c); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lg.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if p := lg.DefaultProvider(); p != "cogent" {
+		t.Error("expected cogent provider but got", p)
+	}
+
+	p := &lg.Cogent{}
+	p.Set("example.com", "")
+	if p.Node != "NL - Amsterdam" || p.GetDefaultNode() != "NL - Amsterdam" {
+		t.Error("expected the configured node but got", p.Node)
+	}
+	if p.IPv != "ipv6" {
+		t.Error("expected the configured ip version but got", p.IPv)
+	}
+	telia := &lg.Telia{}
+	if telia.GetDefaultNode() != "Los Angeles" {
+		t.Error("expected telia default node but got", telia.GetDefaultNode())
+	}
+}
+
+func TestConfigInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	lg.ConfigFile = filepath.Join(dir, "lg.json")
+
+	tests := []struct {
+		content, want string
+	}{
+		{`{"provider": "cogent", "node": "x"}`, `unknown field "node"`},
+		{`{"provider": "foo"}`, `unknown looking glass provider "foo"`},
+		{`{"ip_version": "ipv5"}`, "ip_version"},
+		{`{"http_timeout": "10"}`, "http_timeout"},
+		{`{"cogent_patterns": {"foo": "x"}}`, `unknown cogent pattern "foo"`},
+		{`{"cogent_patterns": {"node": "("}}`, "cogent pattern node"},
+	}
+	for _, tt := range tests {
+		ioutil.WriteFile(lg.ConfigFile, []byte(tt.content), 0600)
+		_, err := lg.LoadConfig()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q error but got %v", tt.content, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package lg
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVMarshaler is implemented by the result types
+// which can be written as CSV w/ a stable header
+type CSVMarshaler interface {
+	CSVHeader() []string
+	CSVRecord() []string
+}
+
+// ToCSV returns the CSV encoding of a result, a slice of the results
+// (one row per item) or the PingAll results (one row per node)
+func ToCSV(v interface{}) ([]byte, error) {
+	var (
+		buf  bytes.Buffer
+		rows [][]string
+	)
+
+	switch r := v.(type) {
+	case CSVMarshaler:
+		rows = [][]string{r.CSVHeader(), r.CSVRecord()}
+	case map[string]PingResult:
+		var nodes []string
+		for node := range r {
+			nodes = append(nodes, node)
+		}
+		sort.Strings(nodes)
+		rows = append(rows, append([]string{"node"}, PingResult{}.CSVHeader()...))
+		for _, node := range nodes {
+			rows = append(rows, append([]string{node}, r[node].CSVRecord()...))
+		}
+	default:
+		s := reflect.ValueOf(v)
+		if s.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("csv output doesn't support %T", v)
+		}
+		m, ok := reflect.Zero(s.Type().Elem()).Interface().(CSVMarshaler)
+		if !ok {
+			return nil, fmt.Errorf("csv output doesn't support %T", v)
+		}
+		rows = append(rows, m.CSVHeader())
+		for i := 0; i < s.Len(); i++ {
+			rows = append(rows, s.Index(i).Interface().(CSVMarshaler).CSVRecord())
+		}
+	}
+
+	w := csv.NewWriter(&buf)
+	w.WriteAll(rows)
+	return buf.Bytes(), w.Error()
+}
+
+// CSVHeader returns the PingStats CSV columns
+func (s PingStats) CSVHeader() []string {
+	return []string{"packets_sent", "packets_received", "loss_percent", "min_ms", "avg_ms", "max_ms"}
+}
+
+// CSVRecord returns the PingStats CSV row
+func (s PingStats) CSVRecord() []string {
+	return []string{
+		strconv.Itoa(s.PacketsSent),
+		strconv.Itoa(s.PacketsReceived),
+		formatFloat(s.LossPercent),
+		formatMs(s.Min),
+		formatMs(s.Avg),
+		formatMs(s.Max),
+	}
+}
+
+// CSVHeader returns the PingResult CSV columns
+func (r PingResult) CSVHeader() []string {
+	return append(r.PingStats.CSVHeader(), "error")
+}
+
+// CSVRecord returns the PingResult CSV row
+func (r PingResult) CSVRecord() []string {
+	var err string
+	if r.Err != nil {
+		err = r.Err.Error()
+	}
+	return append(r.PingStats.CSVRecord(), err)
+}
+
+// CSVHeader returns the TraceHop CSV columns
+func (h TraceHop) CSVHeader() []string {
+	return []string{"index", "host", "ip", "asn", "ptr", "timeouts", "rtt_ms", "country", "city"}
+}
+
+// CSVRecord returns the TraceHop CSV row, the rtts are separated by space
+func (h TraceHop) CSVRecord() []string {
+	var rtts []string
+	for _, rtt := range h.RTTs {
+		rtts = append(rtts, formatMs(rtt))
+	}
+	var country, city string
+	if h.Geo != nil {
+		country, city = h.Geo.Country, h.Geo.City
+	}
+	return []string{
+		strconv.Itoa(h.Index),
+		h.Host,
+		h.IP,
+		h.ASN,
+		h.PTR,
+		strconv.Itoa(h.Timeouts),
+		strings.Join(rtts, " "),
+		country,
+		city,
+	}
+}
+
+// CSVHeader returns the BGPRoute CSV columns
+func (r BGPRoute) CSVHeader() []string {
+	return []string{"prefix", "next_hop", "as_path", "communities", "origin", "best"}
+}
+
+// CSVRecord returns the BGPRoute CSV row, the as path and
+// the communities are separated by space
+func (r BGPRoute) CSVRecord() []string {
+	return []string{
+		r.Prefix,
+		r.NextHop,
+		strings.Join(r.ASPath, " "),
+		strings.Join(r.Communities, " "),
+		r.Origin,
+		strconv.FormatBool(r.Best),
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatMs(d time.Duration) string {
+	return formatFloat(durationToMs(d))
+}
@@ -0,0 +1,70 @@
+package lg_test
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestToCSV(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		golden string
+		v      interface{}
+	}{
+		{
+			"ping.csv",
+			lg.PingStats{PacketsSent: 5, PacketsReceived: 4, LossPercent: 20, Min: ms, Avg: 1500 * time.Microsecond, Max: 2 * ms},
+		},
+		{
+			"ping_all.csv",
+			map[string]lg.PingResult{
+				"US - Los Angeles": {PingStats: lg.PingStats{PacketsSent: 1, PacketsReceived: 1, Min: ms, Avg: ms, Max: ms}},
+				"NL - Amsterdam":   {Err: errors.New(`unexpected "response", try again`)},
+			},
+		},
+		{
+			"trace.csv",
+			[]lg.TraceHop{
+				{Index: 1, Host: "a.example.com", IP: "192.0.2.1", ASN: "174", PTR: "a.example.com",
+					RTTs: []time.Duration{500 * time.Microsecond, ms}, Geo: &lg.GeoInfo{Country: "United States", City: "Los Angeles, CA"}},
+				{Index: 2, Timeouts: 3},
+			},
+		},
+		{
+			"bgp.csv",
+			[]lg.BGPRoute{
+				{Prefix: "8.8.8.0/24", NextHop: "192.0.2.1", ASPath: []string{"174", "15169"}, Communities: []string{"174:21000", "174:22013"}, Origin: "IGP", Best: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		b, err := lg.ToCSV(tt.v)
+		if err != nil {
+			t.Error(tt.golden, err)
+			continue
+		}
+		golden := filepath.Join("testdata", tt.golden)
+		if *update {
+			ioutil.WriteFile(golden, b, 0644)
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != string(want) {
+			t.Errorf("%s: expected\n%s\nbut got\n%s", tt.golden, want, b)
+		}
+	}
+
+	if _, err := lg.ToCSV("x"); err == nil {
+		t.Error("expected error for unsupported type")
+	}
+}
@@ -0,0 +1,99 @@
+package lg
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dotEdge represents the consecutive hops of the traces
+type dotEdge struct {
+	from, to string
+	rtt      time.Duration
+	n        int
+}
+
+// ToDOT returns the Graphviz DOT graph of the traces (e.g. the dual-stack
+// or the repeated traces), the nodes are the hop ips w/ their asns and the
+// edges connect the consecutive hops. the shared segments are merged and
+// the edges are labeled by the average rtt of the next hop, e.g.
+//
+//	mylg trace 8.8.8.8 -dot | dot -Tpng -o trace.png
+func ToDOT(traces ...[]TraceHop) []byte {
+	var (
+		nodes  []string
+		labels = map[string]string{}
+		edges  []*dotEdge
+		seen   = map[[2]string]*dotEdge{}
+	)
+	for _, hops := range traces {
+		var prev string
+		for _, h := range hops {
+			id := dotNodeID(h)
+			if _, ok := labels[id]; !ok {
+				nodes = append(nodes, id)
+				labels[id] = dotLabel(h)
+			}
+			if prev != "" && prev != id {
+				e, ok := seen[[2]string{prev, id}]
+				if !ok {
+					e = &dotEdge{from: prev, to: id}
+					seen[[2]string{prev, id}] = e
+					edges = append(edges, e)
+				}
+				for _, rtt := range h.RTTs {
+					e.rtt += rtt
+					e.n++
+				}
+			}
+			prev = id
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("digraph trace {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	for _, id := range nodes {
+		fmt.Fprintf(&b, "\t%s [label=%s];\n", dotQuote(id), dotQuote(labels[id]))
+	}
+	for _, e := range edges {
+		if e.n == 0 {
+			fmt.Fprintf(&b, "\t%s -> %s;\n", dotQuote(e.from), dotQuote(e.to))
+			continue
+		}
+		avg := float64(e.rtt) / float64(e.n) / float64(time.Millisecond)
+		fmt.Fprintf(&b, "\t%s -> %s [label=\"%.2f ms\"];\n", dotQuote(e.from), dotQuote(e.to), avg)
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+// dotNodeID returns the hop ip, the hop w/o reply is
+// identified by its index so it's shared by the traces
+func dotNodeID(h TraceHop) string {
+	if h.IP != "" {
+		return h.IP
+	}
+	return fmt.Sprintf("* %d", h.Index)
+}
+
+// dotLabel returns the hop ip, host and asn lines
+func dotLabel(h TraceHop) string {
+	if h.IP == "" {
+		return "*"
+	}
+	lines := []string{h.IP}
+	if h.Host != "" && h.Host != h.IP {
+		lines = append(lines, h.Host)
+	}
+	if h.ASN != "" {
+		lines = append(lines, "AS"+h.ASN)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// dotQuote returns the DOT quoted string, the new lines are escaped
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,43 @@
+package lg_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestToDOT(t *testing.T) {
+	ms := time.Millisecond
+	a := []lg.TraceHop{
+		{Index: 1, Host: "be2931.ccr21.lax01.atlas.cogentco.com", IP: "154.54.44.85", ASN: "174", RTTs: []time.Duration{ms, 3 * ms}},
+		{Index: 2, Timeouts: 3},
+		{Index: 3, Host: "72.14.236.69", IP: "72.14.236.69", ASN: "15169", RTTs: []time.Duration{4 * ms}},
+	}
+	b := []lg.TraceHop{
+		a[0],
+		{Index: 2, IP: "154.54.11.90", RTTs: []time.Duration{2 * ms}},
+		{Index: 3, Host: "72.14.236.69", IP: "72.14.236.69", ASN: "15169", RTTs: []time.Duration{6 * ms}},
+	}
+	dot := string(lg.ToDOT(a, b))
+	if !strings.HasPrefix(dot, "digraph trace {\n") || !strings.HasSuffix(dot, "}\n") {
+		t.Errorf("expected digraph but got %q", dot)
+	}
+	for _, want := range []string{
+		`"154.54.44.85" [label="154.54.44.85\nbe2931.ccr21.lax01.atlas.cogentco.com\nAS174"];`,
+		`"* 2" [label="*"];`,
+		`"72.14.236.69" [label="72.14.236.69\nAS15169"];`,
+		`"154.54.44.85" -> "* 2";`,
+		`"* 2" -> "72.14.236.69" [label="4.00 ms"];`,
+		`"154.54.44.85" -> "154.54.11.90" [label="2.00 ms"];`,
+		`"154.54.11.90" -> "72.14.236.69" [label="6.00 ms"];`,
+	} {
+		if !strings.Contains(dot, "\t"+want+"\n") {
+			t.Errorf("expected %s in %s", want, dot)
+		}
+	}
+	if n := strings.Count(dot, "\t\"72.14.236.69\" [label="); n != 1 {
+		t.Errorf("expected the shared hop once but got %d", n)
+	}
+}
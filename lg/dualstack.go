@@ -0,0 +1,144 @@
+package lg
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// FamilyPing represents the ping result of one address family,
+// Note is set once the family is skipped
+type FamilyPing struct {
+	Text  string     `json:"-"`
+	Stats *PingStats `json:"stats,omitempty"`
+	Err   error      `json:"-"`
+	Error string     `json:"error,omitempty"`
+	Note  string     `json:"note,omitempty"`
+}
+
+// DualPing represents the ping results of the both address families
+type DualPing struct {
+	Host string     `json:"host"`
+	IPv4 FamilyPing `json:"ipv4"`
+	IPv6 FamilyPing `json:"ipv6"`
+}
+
+// FamilyTrace represents the trace result of one address family,
+// Note is set once the family is skipped
+type FamilyTrace struct {
+	Lines []string `json:"lines,omitempty"`
+	Err   error    `json:"-"`
+	Error string   `json:"error,omitempty"`
+	Note  string   `json:"note,omitempty"`
+}
+
+// DualTrace represents the trace results of the both address families
+type DualTrace struct {
+	Host string      `json:"host"`
+	IPv4 FamilyTrace `json:"ipv4"`
+	IPv6 FamilyTrace `json:"ipv6"`
+}
+
+// hostFamilies returns a note per address family the host doesn't have,
+// an ip literal has one family and the both families are queried if
+// the host can't be resolved locally (the lg may resolve it)
+func hostFamilies(ctx context.Context, host string) (v4, v6 string) {
+	const skip = "%s has no %s address"
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.To4() != nil {
+			return "", fmt.Sprintf(skip, host, "IPv6")
+		}
+		return fmt.Sprintf(skip, host, "IPv4"), ""
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return "", ""
+	}
+	v4, v6 = fmt.Sprintf(skip, host, "IPv4"), fmt.Sprintf(skip, host, "IPv6")
+	for _, a := range addrs {
+		if a.IP.To4() != nil {
+			v4 = ""
+		} else {
+			v6 = ""
+		}
+	}
+	return v4, v6
+}
+
+// family returns a copy of the lg bound to the host and the address family
+func (p *Cogent) family(host, version string) *Cogent {
+	q := p.clone()
+	q.Host, q.IPv = host, version
+	return q
+}
+
+// PingBoth pings the host over IPv4 (P4) and IPv6 (P6) concurrently
+func (p *Cogent) PingBoth(host string) DualPing {
+	return p.PingBothContext(context.Background(), host)
+}
+
+// PingBothContext is like PingBoth but the requests are bound to ctx
+func (p *Cogent) PingBothContext(ctx context.Context, host string) DualPing {
+	var (
+		r      = DualPing{Host: host}
+		v4, v6 = hostFamilies(ctx, host)
+		wg     sync.WaitGroup
+	)
+	ping := func(f *FamilyPing, version, note string) {
+		defer wg.Done()
+		if note != "" {
+			f.Note = note
+			return
+		}
+		f.Text, f.Err = p.family(host, version).PingContext(ctx)
+		if f.Err == nil {
+			var stats PingStats
+			if stats, f.Err = ParsePing(f.Text); f.Err == nil {
+				f.Stats = &stats
+			}
+		}
+		if f.Err != nil {
+			f.Error = f.Err.Error()
+		}
+	}
+	wg.Add(2)
+	go ping(&r.IPv4, "ipv4", v4)
+	go ping(&r.IPv6, "ipv6", v6)
+	wg.Wait()
+	return r
+}
+
+// TraceBoth traces the host over IPv4 (T4) and IPv6 (T6) concurrently
+func (p *Cogent) TraceBoth(host string) DualTrace {
+	return p.TraceBothContext(context.Background(), host)
+}
+
+// TraceBothContext is like TraceBoth but the requests are bound to ctx
+func (p *Cogent) TraceBothContext(ctx context.Context, host string) DualTrace {
+	var (
+		r      = DualTrace{Host: host}
+		v4, v6 = hostFamilies(ctx, host)
+		wg     sync.WaitGroup
+	)
+	trace := func(f *FamilyTrace, version, note string) {
+		defer wg.Done()
+		if note != "" {
+			f.Note = note
+			return
+		}
+		lines, err := p.family(host, version).TraceContext(ctx)
+		if err != nil {
+			f.Err, f.Error = err, err.Error()
+			return
+		}
+		for l := range lines {
+			f.Lines = append(f.Lines, l)
+		}
+	}
+	wg.Add(2)
+	go trace(&r.IPv4, "ipv4", v4)
+	go trace(&r.IPv6, "ipv6", v6)
+	wg.Wait()
+	return r
+}
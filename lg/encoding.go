@@ -0,0 +1,74 @@
+package lg
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// acceptEncoding is the Accept-Encoding of the looking glass requests,
+// the transport doesn't decode the responses once it's set explicitly
+// so they're decoded by decodeBody regardless of the transport
+const acceptEncoding = "gzip, deflate"
+
+// decodedBody decodes the response body on the first read
+type decodedBody struct {
+	body     io.ReadCloser
+	encoding string
+	r        io.Reader
+	err      error
+}
+
+// decodeBody replaces the gzip or deflate encoded response
+// body w/ the decoded one, the other bodies are kept
+func decodeBody(resp *http.Response) {
+	enc := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch enc {
+	case "gzip", "x-gzip", "deflate":
+	default:
+		return
+	}
+	resp.Body = &decodedBody{body: resp.Body, encoding: enc}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.r == nil && b.err == nil {
+		b.r, b.err = newDecoder(b.encoding, b.body)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.r.Read(p)
+}
+
+func (b *decodedBody) Close() error {
+	return b.body.Close()
+}
+
+// newDecoder returns the reader of the encoded body, the deflate
+// body can be the zlib (RFC 1950) or the raw deflate stream
+func newDecoder(encoding string, body io.Reader) (io.Reader, error) {
+	if encoding != "deflate" {
+		return gzip.NewReader(body)
+	}
+	br := bufio.NewReader(body)
+	h, err := br.Peek(2)
+	if len(h) < 2 {
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
@@ -0,0 +1,172 @@
+package lg
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// formFields holds the Cogent form field names, the empty
+// names are the known ones (see defaultFormFields)
+type formFields struct {
+	Submit      string `json:"submit,omitempty"`
+	SubmitValue string `json:"submit_value,omitempty"`
+	Command     string `json:"command,omitempty"`
+	Target      string `json:"target,omitempty"`
+	Node        string `json:"node,omitempty"`
+	Source      string `json:"source,omitempty"`
+}
+
+// defaultFormFields are the known Cogent form field names
+var defaultFormFields = formFields{
+	Submit:      "FKT",
+	SubmitValue: "go!",
+	Command:     "CMD",
+	Target:      "DST",
+	Node:        "LOC",
+	Source:      "SRC",
+}
+
+var (
+	formTagRe    = regexp.MustCompile(`(?is)<(input|select)\b([^>]*)>`)
+	formOptionRe = regexp.MustCompile(`(?is)<option\b([^>]*)>`)
+	formAttrRe   = regexp.MustCompile(`(?is)([\w-]+)\s*=\s*("[^"]*"|'[^']*'|[^\s>]+)`)
+)
+
+// withDefaults returns the fields w/ the empty names set to the known ones
+func (f formFields) withDefaults() formFields {
+	d := defaultFormFields
+	if f.Submit == "" {
+		f.Submit = d.Submit
+	}
+	if f.SubmitValue == "" {
+		f.SubmitValue = d.SubmitValue
+	}
+	if f.Command == "" {
+		f.Command = d.Command
+	}
+	if f.Target == "" {
+		f.Target = d.Target
+	}
+	if f.Node == "" {
+		f.Node = d.Node
+	}
+	if f.Source == "" {
+		f.Source = d.Source
+	}
+	return f
+}
+
+// formAttrs returns the attributes of the tag by the lowercase names
+func formAttrs(tag string) map[string]string {
+	attrs := map[string]string{}
+	for _, m := range formAttrRe.FindAllStringSubmatch(tag, -1) {
+		attrs[strings.ToLower(m[1])] = strings.Trim(m[2], `"'`)
+	}
+	return attrs
+}
+
+// scrapeFormFields returns the field names of the looking glass form.
+// the submit and the text inputs are the submit and the target, the select
+// w/ the command options is the command and the other selects are the node
+// and then the source unless they have the known names. the fields which
+// aren't found are empty, so the known names are used
+func scrapeFormFields(page string) formFields {
+	var (
+		f       formFields
+		selects []string
+	)
+	for _, loc := range formTagRe.FindAllStringSubmatchIndex(page, -1) {
+		attrs := formAttrs(page[loc[4]:loc[5]])
+		name, typ := attrs["name"], strings.ToLower(attrs["type"])
+		if name == "" {
+			continue
+		}
+		switch {
+		case strings.EqualFold(page[loc[2]:loc[3]], "select"):
+			body := page[loc[1]:]
+			if i := strings.Index(strings.ToLower(body), "</select>"); i >= 0 {
+				body = body[:i]
+			}
+			if hasCommandOption(body) {
+				f.Command = name
+			} else {
+				selects = append(selects, name)
+			}
+		case typ == "submit" || typ == "image" || typ == "button":
+			f.Submit, f.SubmitValue = name, attrs["value"]
+		case (typ == "" || typ == "text" || typ == "search") && f.Target == "":
+			f.Target = name
+		}
+	}
+	for _, name := range selects {
+		switch name {
+		case defaultFormFields.Node:
+			f.Node = name
+		case defaultFormFields.Source:
+			f.Source = name
+		}
+	}
+	for _, name := range selects {
+		switch {
+		case name == f.Node || name == f.Source:
+		case f.Node == "":
+			f.Node = name
+		case f.Source == "":
+			f.Source = name
+		}
+	}
+	return f
+}
+
+// hasCommandOption returns true if the select has any of the command options
+func hasCommandOption(body string) bool {
+	for _, m := range formOptionRe.FindAllStringSubmatch(body, -1) {
+		switch CogentCmd(formAttrs(m[1])["value"]) {
+		case CmdPing4, CmdPing6, CmdTrace4, CmdTrace6, CmdBGPQuery:
+			return true
+		}
+	}
+	return false
+}
+
+// renamed returns the fields which differ from the known names
+func (f formFields) renamed() []string {
+	var (
+		d       = defaultFormFields
+		renames []string
+	)
+	f = f.withDefaults()
+	for _, v := range [][2]string{
+		{d.Submit, f.Submit},
+		{d.Command, f.Command},
+		{d.Target, f.Target},
+		{d.Node, f.Node},
+		{d.Source, f.Source},
+	} {
+		if v[0] != v[1] {
+			renames = append(renames, v[0]+" => "+v[1])
+		}
+	}
+	return renames
+}
+
+// fieldNames returns the form field names of the parser
+func (cp *CogentParser) fieldNames() formFields {
+	cp.mu.RLock()
+	defer cp.mu.RUnlock()
+	return cp.fields.withDefaults()
+}
+
+// setFieldNames replaces the scraped form field names of the parser
+func (cp *CogentParser) setFieldNames(f formFields) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.fields = f
+}
+
+// form returns the request form of the command, host and node code
+func (p *Cogent) form(cmd CogentCmd, host, loc string) url.Values {
+	f := p.parser().fieldNames()
+	return url.Values{f.Submit: {f.SubmitValue}, f.Command: {string(cmd)}, f.Target: {host}, f.Node: {loc}}
+}
@@ -0,0 +1,41 @@
+//go:build ignore
+// +build ignore
+
+// gen_cogent_nodes fetches the Cogent nodes and writes the
+// snapshot which is bundled w/ mylg, see go generate ./lg
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"log"
+	"time"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func main() {
+	out := flag.String("o", "cogent_nodes.json", "snapshot file")
+	flag.Parse()
+
+	nodes, bgpNodes, err := new(lg.Cogent).FetchNodes()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(nodes) == 0 {
+		log.Fatal("no nodes fetched, the snapshot isn't changed")
+	}
+	b, err := json.MarshalIndent(struct {
+		Timestamp time.Time         `json:"timestamp"`
+		Nodes     map[string]string `json:"nodes"`
+		BGPNodes  map[string]string `json:"bgp_nodes,omitempty"`
+	}{time.Now().UTC(), nodes, bgpNodes}, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*out, append(b, '\n'), 0644); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%d nodes and %d bgp nodes are written to %s", len(nodes), len(bgpNodes), *out)
+}
@@ -0,0 +1,124 @@
+package lg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GeoInfo represents an IP address location
+type GeoInfo struct {
+	Country string  `json:"country,omitempty"`
+	City    string  `json:"city,omitempty"`
+	Lat     float64 `json:"lat,omitempty"`
+	Lon     float64 `json:"lon,omitempty"`
+	ASN     string  `json:"asn,omitempty"`
+	Org     string  `json:"org,omitempty"`
+}
+
+// GeoLocator returns the location of an IP address
+type GeoLocator interface {
+	Locate(ip string) (GeoInfo, error)
+}
+
+// GeoClient locates the IP addresses through a HTTP geo API,
+// the results are cached on disk per IP address
+type GeoClient struct {
+	// URL is the API address, %s is replaced w/ the IP address
+	URL string
+	// Client is used for the API requests, the package
+	// http client is used if it's nil
+	Client *http.Client
+	// CacheDir keeps the results, the caching is disabled if it's empty
+	CacheDir string
+}
+
+// DefaultGeoURL is the default geo API address
+const DefaultGeoURL = "http://ip-api.com/json/%s?fields=status,message,country,city,lat,lon,as,org"
+
+// geoLocator enriches the trace hops w/ the location if it's set
+var geoLocator GeoLocator
+
+// SetGeoLocator enables the trace hops location enrichment,
+// it's disabled if the locator is nil
+func SetGeoLocator(g GeoLocator) {
+	geoLocator = g
+}
+
+// NewGeoClient returns a geo client w/ the default API and cache dir
+func NewGeoClient() *GeoClient {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return &GeoClient{URL: DefaultGeoURL, CacheDir: filepath.Join(dir, "mylg", "geo")}
+}
+
+// Locate returns the IP address location, the private and
+// bogon addresses return empty location w/o any request
+func (g *GeoClient) Locate(ip string) (GeoInfo, error) {
+	var info GeoInfo
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return info, fmt.Errorf("invalid ip address %q", ip)
+	}
+	if IsBogon(addr) {
+		return info, nil
+	}
+	file := filepath.Join(g.CacheDir, strings.Replace(addr.String(), ":", "_", -1)+".json")
+	if g.CacheDir != "" {
+		if b, err := ioutil.ReadFile(file); err == nil && json.Unmarshal(b, &info) == nil {
+			return info, nil
+		}
+	}
+
+	client := g.Client
+	if client == nil {
+		client = httpClient
+	}
+	u := g.URL
+	if u == "" {
+		u = DefaultGeoURL
+	}
+	resp, err := get(client, fmt.Sprintf(u, addr))
+	if err != nil {
+		return info, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return info, fmt.Errorf("geo: %s", resp.Status)
+	}
+	var r struct {
+		Status  string  `json:"status"`
+		Message string  `json:"message"`
+		Country string  `json:"country"`
+		City    string  `json:"city"`
+		Lat     float64 `json:"lat"`
+		Lon     float64 `json:"lon"`
+		AS      string  `json:"as"`
+		Org     string  `json:"org"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return info, err
+	}
+	if r.Status == "fail" {
+		return info, fmt.Errorf("geo: %s", r.Message)
+	}
+	info = GeoInfo{Country: r.Country, City: r.City, Lat: r.Lat, Lon: r.Lon, Org: r.Org}
+	// e.g. AS15169 Google LLC
+	if f := strings.Fields(r.AS); len(f) > 0 {
+		info.ASN = f[0]
+	}
+	if g.CacheDir != "" {
+		if b, err := json.Marshal(info); err == nil && os.MkdirAll(g.CacheDir, 0700) == nil {
+			ioutil.WriteFile(file, b, 0600)
+		}
+	}
+	return info, nil
+}
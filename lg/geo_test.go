@@ -0,0 +1,80 @@
+package lg_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestGeoClientLocate(t *testing.T) {
+	var hits int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprint(w, `{"status":"success","country":"United States","city":"Mountain View","lat":37.4,"lon":-122.1,"as":"AS15169 Google LLC","org":"Google Public DNS"}`)
+	}))
+	defer ts.Close()
+
+	dir, _ := ioutil.TempDir("", "mylg")
+	defer os.RemoveAll(dir)
+
+	g := &lg.GeoClient{URL: ts.URL + "/json/%s", CacheDir: dir}
+	want := lg.GeoInfo{Country: "United States", City: "Mountain View", Lat: 37.4, Lon: -122.1, ASN: "AS15169", Org: "Google Public DNS"}
+	for i := 0; i < 2; i++ {
+		info, err := g.Locate("8.8.8.8")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info != want {
+			t.Errorf("expected %+v but got %+v", want, info)
+		}
+	}
+	if hits != 1 {
+		t.Error("expected the second lookup from the cache but hits", hits)
+	}
+
+	for _, ip := range []string{"10.1.1.1", "192.168.1.1", "127.0.0.1", "fe80::1"} {
+		if info, err := g.Locate(ip); err != nil || info != (lg.GeoInfo{}) {
+			t.Errorf("expected empty location for %s but got %+v %v", ip, info, err)
+		}
+	}
+	if hits != 1 {
+		t.Error("expected no request for the bogon addresses but hits", hits)
+	}
+}
+
+// fakeLocator returns the same location for all of the IPs
+type fakeLocator struct{}
+
+func (fakeLocator) Locate(ip string) (lg.GeoInfo, error) {
+	return lg.GeoInfo{Country: "US"}, nil
+}
+
+func TestTraceStructuredGeo(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, cogentTraceHopsFixture)
+	}))
+	defer ts.Close()
+
+	lg.SetGeoLocator(fakeLocator{})
+	defer lg.SetGeoLocator(nil)
+
+	c := &lg.Cogent{BaseURL: ts.URL}
+	c.Set("8.8.8.8", "ipv4")
+	hops, err := c.TraceStructured()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for hop := range hops {
+		if hop.IP != "" && (hop.Geo == nil || hop.Geo.Country != "US") {
+			t.Errorf("expected hop %d location but got %+v", hop.Index, hop.Geo)
+		}
+		if hop.IP == "" && hop.Geo != nil {
+			t.Errorf("expected no location for hop %d w/o ip", hop.Index)
+		}
+	}
+}
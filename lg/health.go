@@ -0,0 +1,123 @@
+package lg
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NodeHealthChecker is implemented by the providers which can probe
+// the nodes and fail over to a healthy one
+type NodeHealthChecker interface {
+	NodeHealthy(node string) bool
+	// ServedNode returns the node which served the last request
+	ServedNode() string
+}
+
+// the node health probe timeout, the status lifetime and the
+// max probed nodes of a failover
+const (
+	healthTimeout  = 5 * time.Second
+	healthTTL      = 30 * time.Second
+	failoverProbes = 3
+)
+
+type healthStatus struct {
+	healthy bool
+	checked time.Time
+}
+
+var (
+	healthMu     sync.Mutex
+	cogentHealth = map[string]healthStatus{}
+)
+
+// NodeHealthy probes the node by a single packet ping of
+// nearestAnchor, the status is cached for healthTTL
+func (p *Cogent) NodeHealthy(node string) bool {
+	return p.nodeHealthy(context.Background(), node)
+}
+
+func (p *Cogent) nodeHealthy(ctx context.Context, node string) bool {
+	healthMu.Lock()
+	s, ok := cogentHealth[node]
+	healthMu.Unlock()
+	if ok && time.Since(s.checked) < healthTTL {
+		return s.healthy
+	}
+
+	pctx, cancel := context.WithTimeout(ctx, healthTimeout)
+	defer cancel()
+	q := p.clone()
+	q.Set(nearestAnchor, "ipv4")
+	q.Count, q.DisableRetry = 1, true
+	r := q.pingNode(pctx, node)
+	// the probe is canceled or timed out, the status is unknown
+	if ctx.Err() != nil || errors.Is(r.Err, context.Canceled) || errors.Is(r.Err, context.DeadlineExceeded) {
+		getLogger().Debug("cogent: node %s health is unknown: %v", node, r.Err)
+		return false
+	}
+	healthy := r.Err == nil && r.PacketsReceived > 0
+	if !healthy {
+		getLogger().Debug("cogent: node %s is unhealthy: %v", node, r.Err)
+	}
+	healthMu.Lock()
+	cogentHealth[node] = healthStatus{healthy: healthy, checked: time.Now()}
+	healthMu.Unlock()
+	return healthy
+}
+
+// servingNode returns the selected node, or the next-best healthy node
+// of its region once Failover is set and the selected node is unhealthy.
+// the candidates are probed one at a time, see failoverCandidates
+func (p *Cogent) servingNode(ctx context.Context) string {
+	p.served = p.Node
+	if !p.Failover || p.DryRun || p.nodeHealthy(ctx, p.Node) {
+		return p.Node
+	}
+	for _, n := range failoverCandidates(p.Node, nodeNames(loadedNodes().Nodes)) {
+		if ctx.Err() != nil {
+			break
+		}
+		if p.nodeHealthy(ctx, n) {
+			getLogger().Warn("cogent: node %s is unhealthy, failed over to %s", p.Node, n)
+			p.served = n
+			return n
+		}
+	}
+	getLogger().Warn("cogent: node %s is unhealthy and there isn't a healthy node in %s", p.Node, nodeRegion(p.Node))
+	return p.Node
+}
+
+// failoverCandidates returns the nearest nodes of the node region, the
+// nodes of the same country come first and they're capped by failoverProbes
+// so the failover doesn't probe the whole region
+func failoverCandidates(node string, names []string) []string {
+	var (
+		country = strings.SplitN(node, " - ", 2)[0]
+		near    []string
+		far     []string
+	)
+	for _, n := range groupByRegion(names)[nodeRegion(node)] {
+		switch {
+		case n == node:
+		case strings.SplitN(n, " - ", 2)[0] == country:
+			near = append(near, n)
+		default:
+			far = append(far, n)
+		}
+	}
+	nodes := append(near, far...)
+	if len(nodes) > failoverProbes {
+		nodes = nodes[:failoverProbes]
+	}
+	return nodes
+}
+
+// ServedNode returns the node which served the last Ping or Trace,
+// it's other than Node once the request is failed over
+func (p *Cogent) ServedNode() string {
+	return p.served
+}
@@ -0,0 +1,129 @@
+package lg
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+// normalizeHost returns the host w/ the internationalized domain name
+// converted to punycode, e.g. xn--r8jz45g.jp for 例え.jp, and validates
+// it (see validateHost). the IP literals are kept untouched
+func normalizeHost(host string) (string, error) {
+	if net.ParseIP(host) == nil && needsIDNA(host) {
+		name, err := idna.Lookup.ToASCII(host)
+		if err != nil {
+			return "", fmt.Errorf("invalid host %q: %v", host, err)
+		}
+		host = name
+	}
+	return host, validateHost(host)
+}
+
+// ResolveHost resolves the hostname locally to the addresses of the ip
+// version (ipv4 or ipv6) in the resolver order, the ip literal is kept
+func ResolveHost(ctx context.Context, host, version string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	network := "ip4"
+	if version == "ipv6" {
+		network = "ip6"
+	}
+	ips, err := net.DefaultResolver.LookupIP(ctx, network, host)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %v", host, err)
+	}
+	var addrs []string
+	for _, ip := range ips {
+		addrs = append(addrs, ip.String())
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("resolve %s: no %s address", host, version)
+	}
+	return addrs, nil
+}
+
+// normalizePrefix is like normalizeHost but it accepts CIDR notation too
+func normalizePrefix(host string) (string, error) {
+	if _, _, err := net.ParseCIDR(host); err == nil {
+		return host, nil
+	}
+	return normalizeHost(host)
+}
+
+// needsIDNA returns true if the host has a non-ASCII
+// character or a punycode (xn--) label
+func needsIDNA(host string) bool {
+	for _, r := range host {
+		if r > unicode.MaxASCII {
+			return true
+		}
+	}
+	for _, label := range strings.Split(host, ".") {
+		if strings.HasPrefix(strings.ToLower(label), "xn--") {
+			return true
+		}
+	}
+	return false
+}
+
+// validateHost makes sure the host is an IPv4/IPv6 literal
+// or a RFC 1123 hostname before it's sent to a looking glass
+func validateHost(host string) error {
+	if host == "" {
+		return fmt.Errorf("invalid host: empty host")
+	}
+	for _, r := range host {
+		if unicode.IsSpace(r) {
+			return fmt.Errorf("invalid host %q: contains space", host)
+		}
+		if unicode.IsControl(r) {
+			return fmt.Errorf("invalid host %q: contains control character", host)
+		}
+	}
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	name := strings.TrimSuffix(host, ".")
+	if len(name) > 253 {
+		return fmt.Errorf("invalid host %q: longer than 253 characters", host)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if err := validateLabel(label); err != nil {
+			return fmt.Errorf("invalid host %q: %s", host, err)
+		}
+	}
+	return nil
+}
+
+// validateLabel checks a hostname label
+func validateLabel(label string) error {
+	if label == "" {
+		return fmt.Errorf("empty label")
+	}
+	if len(label) > 63 {
+		return fmt.Errorf("label %q is longer than 63 characters", label)
+	}
+	if label[0] == '-' || label[len(label)-1] == '-' {
+		return fmt.Errorf("label %q starts or ends with hyphen", label)
+	}
+	for _, r := range label {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+			return fmt.Errorf("label %q contains invalid character %q", label, r)
+		}
+	}
+	return nil
+}
+
+// validatePrefix is like validateHost but it accepts CIDR notation too
+func validatePrefix(host string) error {
+	if _, _, err := net.ParseCIDR(host); err == nil {
+		return nil
+	}
+	return validateHost(host)
+}
@@ -0,0 +1,117 @@
+package lg
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// StructuredTracer is implemented by the providers
+// which can parse the traceroute hops
+type StructuredTracer interface {
+	TraceStructured() (chan TraceHop, error)
+}
+
+// BGPRouter is implemented by the providers
+// which can parse the bgp routes
+type BGPRouter interface {
+	BGPRoutes() ([]BGPRoute, error)
+}
+
+// ToJSON returns the indented JSON encoding of the results
+func ToJSON(v interface{}) ([]byte, error) {
+	return json.MarshalIndent(v, "", "  ")
+}
+
+// pingStatsJSON is the JSON representation of the PingStats
+type pingStatsJSON struct {
+	PacketsSent     int     `json:"packets_sent"`
+	PacketsReceived int     `json:"packets_received"`
+	LossPercent     float64 `json:"loss_percent"`
+	Min             float64 `json:"min_ms"`
+	Avg             float64 `json:"avg_ms"`
+	Max             float64 `json:"max_ms"`
+	Error           string  `json:"error,omitempty"`
+
+	P50    *float64 `json:"p50_ms,omitempty"`
+	P90    *float64 `json:"p90_ms,omitempty"`
+	P99    *float64 `json:"p99_ms,omitempty"`
+	Jitter *float64 `json:"jitter_ms,omitempty"`
+
+	Replies []pingReplyJSON `json:"replies,omitempty"`
+}
+
+// pingReplyJSON is the JSON representation of the PingReply
+type pingReplyJSON struct {
+	Seq  int     `json:"seq"`
+	TTL  int     `json:"ttl,omitempty"`
+	RTT  float64 `json:"rtt_ms"`
+	Lost bool    `json:"lost,omitempty"`
+}
+
+// traceHopJSON is the JSON representation of the TraceHop
+type traceHopJSON struct {
+	Index    int       `json:"index"`
+	Host     string    `json:"host"`
+	IP       string    `json:"ip"`
+	ASN      string    `json:"asn"`
+	RTTs     []float64 `json:"rtt_ms"`
+	Timeouts int       `json:"timeouts,omitempty"`
+	Geo      *GeoInfo  `json:"geo,omitempty"`
+	PTR      string    `json:"ptr,omitempty"`
+}
+
+// MarshalJSON encodes the statistics w/ the rtt in milliseconds
+func (s PingStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.toJSON())
+}
+
+// MarshalJSON encodes the node statistics or its error
+func (r PingResult) MarshalJSON() ([]byte, error) {
+	v := r.PingStats.toJSON()
+	if r.Err != nil {
+		v.Error = r.Err.Error()
+	}
+	return json.Marshal(v)
+}
+
+func (s PingStats) toJSON() pingStatsJSON {
+	v := pingStatsJSON{
+		PacketsSent:     s.PacketsSent,
+		PacketsReceived: s.PacketsReceived,
+		LossPercent:     s.LossPercent,
+		Min:             durationToMs(s.Min),
+		Avg:             durationToMs(s.Avg),
+		Max:             durationToMs(s.Max),
+		P50:             durationToMsPtr(s.P50),
+		P90:             durationToMsPtr(s.P90),
+		P99:             durationToMsPtr(s.P99),
+		Jitter:          durationToMsPtr(s.Jitter),
+	}
+	for _, r := range s.Replies {
+		v.Replies = append(v.Replies, pingReplyJSON{Seq: r.Seq, TTL: r.TTL, RTT: durationToMs(r.RTT), Lost: r.Lost})
+	}
+	return v
+}
+
+// MarshalJSON encodes the hop w/ the rtts in milliseconds
+func (h TraceHop) MarshalJSON() ([]byte, error) {
+	v := traceHopJSON{Index: h.Index, Host: h.Host, IP: h.IP, ASN: h.ASN, RTTs: []float64{}, Timeouts: h.Timeouts, Geo: h.Geo, PTR: h.PTR}
+	for _, rtt := range h.RTTs {
+		v.RTTs = append(v.RTTs, durationToMs(rtt))
+	}
+	return json.Marshal(v)
+}
+
+// durationToMs converts time.Duration to milliseconds
+func durationToMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// durationToMsPtr is like durationToMs but nil is kept
+func durationToMsPtr(d *time.Duration) *float64 {
+	if d == nil {
+		return nil
+	}
+	ms := durationToMs(*d)
+	return &ms
+}
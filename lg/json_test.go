@@ -0,0 +1,66 @@
+package lg_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestToJSON(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{
+			name: "ping",
+			v:    lg.PingStats{PacketsSent: 2, PacketsReceived: 1, LossPercent: 50, Min: time.Millisecond, Avg: 1500 * time.Microsecond, Max: 2 * time.Millisecond},
+			want: `{"packets_sent":2,"packets_received":1,"loss_percent":50,"min_ms":1,"avg_ms":1.5,"max_ms":2}`,
+		},
+		{
+			name: "ping percentiles",
+			v:    lg.PingStats{PacketsSent: 2, PacketsReceived: 1, P50: &ms, P90: &ms, P99: &ms, Replies: []lg.PingReply{{Seq: 1, TTL: 57, RTT: ms}, {Seq: 2, Lost: true}}},
+			want: `{"packets_sent":2,"packets_received":1,"loss_percent":0,"min_ms":0,"avg_ms":0,"max_ms":0,"p50_ms":1,"p90_ms":1,"p99_ms":1,"replies":[{"seq":1,"ttl":57,"rtt_ms":1},{"seq":2,"rtt_ms":0,"lost":true}]}`,
+		},
+		{
+			name: "ping result",
+			v:    lg.PingResult{Err: errors.New("timeout")},
+			want: `{"packets_sent":0,"packets_received":0,"loss_percent":0,"min_ms":0,"avg_ms":0,"max_ms":0,"error":"timeout"}`,
+		},
+		{
+			name: "trace",
+			v: []lg.TraceHop{
+				{Index: 1, Host: "a.example.com", IP: "192.0.2.1", ASN: "174", RTTs: []time.Duration{500 * time.Microsecond}},
+				{Index: 2},
+			},
+			want: `[{"index":1,"host":"a.example.com","ip":"192.0.2.1","asn":"174","rtt_ms":[0.5]},{"index":2,"host":"","ip":"","asn":"","rtt_ms":[]}]`,
+		},
+		{
+			name: "bgp",
+			v:    lg.BGPRoute{Prefix: "8.8.8.0/24", NextHop: "192.0.2.1", ASPath: []string{"15169"}, Origin: "IGP", Best: true},
+			want: `{"prefix":"8.8.8.0/24","next_hop":"192.0.2.1","as_path":["15169"],"communities":null,"origin":"IGP","best":true}`,
+		},
+	}
+	for _, tt := range tests {
+		b, err := lg.ToJSON(tt.v)
+		if err != nil {
+			t.Error(tt.name, err)
+			continue
+		}
+		if got := compact(b); got != tt.want {
+			t.Errorf("%s: expected %s but got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+// compact removes the JSON indentation
+func compact(b []byte) string {
+	var buf bytes.Buffer
+	json.Compact(&buf, b)
+	return buf.String()
+}
@@ -4,9 +4,9 @@ package lg
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"io/ioutil"
-	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
@@ -24,18 +24,22 @@ type KPN struct {
 
 var KPNDefaultNode = "Amsterdam (NL)"
 
+func init() {
+	Register("kpn", func() LookingGlass { return new(KPN) })
+}
+
 // Set configures host and ip version
 func (p *KPN) Set(host, version string) {
 	p.Host = host
-	p.IPv = version
+	p.IPv = ipVersion(version)
 	if p.Node == "" {
-		p.Node = KPNDefaultNode
+		p.Node = defaultNode("kpn", KPNDefaultNode)
 	}
 }
 
 // GetDefaultNode returns KPN default node
 func (p *KPN) GetDefaultNode() string {
-	return KPNDefaultNode
+	return defaultNode("kpn", KPNDefaultNode)
 }
 
 // GetNodes returns all KPN nodes (US and International)
@@ -53,6 +57,26 @@ func (p *KPN) GetNodes() []string {
 	return nodes
 }
 
+// MatchNodes returns the candidate nodes for the query
+func (p *KPN) MatchNodes(query string) []string {
+	return matchNodes(p.Nodes, nil, query)
+}
+
+// CompletionCandidates returns the loaded nodes which match the prefix
+func (p *KPN) CompletionCandidates(prefix string) []string {
+	return completionCandidates(p.Nodes, prefix)
+}
+
+// Get returns the host and the ip version which are set
+func (p *KPN) Get() (string, string) {
+	return p.Host, p.IPv
+}
+
+// GetNode returns the current node
+func (p *KPN) GetNode() string {
+	return p.Node
+}
+
 // ChangeNode set new requested node
 func (p *KPN) ChangeNode(node string) bool {
 	// Validate
@@ -70,10 +94,10 @@ func (p *KPN) ChangeNode(node string) bool {
 func (p *KPN) Ping() (string, error) {
 	// Basic validate
 	if p.Node == "NA" || len(p.Host) < 5 {
-		print("Invalid node or host/ip address")
+		getLogger().Error("Invalid node or host/ip address")
 		return "", errors.New("error")
 	}
-	resp, err := http.PostForm("http://lg.eurorings.net/index.cgi",
+	resp, err := postFormContext(withLabels(context.Background(), "kpn", p.Node, "ping"), httpClient, "http://lg.eurorings.net/index.cgi",
 		url.Values{"query": {"ping"}, "protocol": {p.IPv}, "addr": {p.Host}, "router": {p.Node}})
 	if err != nil {
 		return "", err
@@ -89,23 +113,23 @@ func (p *KPN) Ping() (string, error) {
 	r, _ := regexp.Compile(`<CODE>(?s)(.*?)</CODE>`)
 	b := r.FindStringSubmatch(string(body))
 	if len(b) > 0 {
+		observePing("kpn", p.Node, b[1])
 		return b[1], nil
 	}
 	return "", errors.New("error")
 }
 
 // Trace gets traceroute information from KPN
-func (p *KPN) Trace() chan string {
+func (p *KPN) Trace() (chan string, error) {
 	c := make(chan string)
-	sigCh := make(chan os.Signal, 1)
-
-	signal.Notify(sigCh, os.Interrupt)
-
-	resp, err := http.PostForm("http://lg.eurorings.net/index.cgi",
+	resp, err := postFormContext(withLabels(context.Background(), "kpn", p.Node, "trace"), httpClient, "http://lg.eurorings.net/index.cgi",
 		url.Values{"query": {"trace"}, "protocol": {p.IPv}, "addr": {p.Host}, "router": {p.Node}})
 	if err != nil {
-		println(err)
+		return nil, err
 	}
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+
 	go func() {
 		defer resp.Body.Close()
 		scanner := bufio.NewScanner(resp.Body)
@@ -114,7 +138,7 @@ func (p *KPN) Trace() chan string {
 			l := scanner.Text()
 			m, _ := regexp.MatchString(`^(traceroute|\s*\d{1,2})`, l)
 			if m {
-				l = replaceASNTrace(l)
+				l = cleanLine(replaceASNTrace(l))
 				select {
 				case <-sigCh:
 					break LOOP
@@ -125,16 +149,27 @@ func (p *KPN) Trace() chan string {
 		signal.Stop(sigCh)
 		close(c)
 	}()
-	return c
+	return c, nil
+}
+
+// TraceStructured is like Trace but it parses each hop
+func (p *KPN) TraceStructured() (chan TraceHop, error) {
+	lines, err := p.Trace()
+	if err != nil {
+		return nil, err
+	}
+	return traceHops(lines), nil
 }
 
 // BGP gets bgp information from KPN
 func (p *KPN) BGP() chan string {
 	c := make(chan string)
-	resp, err := http.PostForm("http://lg.eurorings.net/index.cgi",
+	resp, err := postFormContext(withLabels(context.Background(), "kpn", p.Node, "bgp"), httpClient, "http://lg.eurorings.net/index.cgi",
 		url.Values{"query": {"bgp"}, "protocol": {p.IPv}, "addr": {p.Host}, "router": {p.Node}})
 	if err != nil {
-		println(err)
+		getLogger().Error("%s", err)
+		close(c)
+		return c
 	}
 	go func() {
 		var (
@@ -164,15 +199,15 @@ func (p *KPN) BGP() chan string {
 //FetchNodes returns all available nodes through HTTP
 func (p *KPN) FetchNodes() map[string]string {
 	var nodes = make(map[string]string, 100)
-	resp, err := http.Get("http://lg.eurorings.net/index.cgi")
+	resp, err := getContext(withLabels(context.Background(), "kpn", p.Node, "nodes"), httpClient, "http://lg.eurorings.net/index.cgi")
 	if err != nil {
-		println("error: KPN looking glass unreachable (1) ")
+		getLogger().Warn("KPN looking glass unreachable (1)")
 		return map[string]string{}
 	}
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		println("error: KPN looking glass unreachable (2)" + err.Error())
+		getLogger().Warn("KPN looking glass unreachable (2) %s", err)
 		return map[string]string{}
 	}
 	r, _ := regexp.Compile(`(?i)<option value="(?s)([\w|\s|)(._-]+)"> (?s)([\w|\s|)(._-]+)`)
@@ -182,3 +217,8 @@ func (p *KPN) FetchNodes() map[string]string {
 	}
 	return nodes
 }
+
+// SupportedCommands returns the commands which KPN offers
+func (p *KPN) SupportedCommands() []Command {
+	return []Command{CmdPing, CmdTrace, CmdBGP}
+}
@@ -4,10 +4,10 @@ package lg
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"html"
 	"io/ioutil"
-	"net/http"
 	"net/url"
 	"regexp"
 	"sort"
@@ -42,7 +42,11 @@ func sanitize(b string) string {
 	b = re.ReplaceAllString(b, "\n")
 	re = regexp.MustCompile(`<[^>]*>`)
 	b = re.ReplaceAllString(b, "")
-	return html.UnescapeString(b)
+	return stripControl(html.UnescapeString(b))
+}
+
+func init() {
+	Register("level3", func() LookingGlass { return new(Level3) })
 }
 
 // Set configures host and ip version
@@ -54,16 +58,16 @@ func (p *Level3) Set(host, version string) {
 		p.Host = host
 		p.CIDR = "24"
 	}
-	p.IPv = version
+	p.IPv = ipVersion(version)
 	p.Count = "5"
 	if p.Node == "" {
-		p.Node = level3DefaultNode
+		p.Node = defaultNode("level3", level3DefaultNode)
 	}
 }
 
 // GetDefaultNode returns telia default node
 func (p *Level3) GetDefaultNode() string {
-	return level3DefaultNode
+	return defaultNode("level3", level3DefaultNode)
 }
 
 // GetNodes returns all level3 nodes (US and International)
@@ -82,6 +86,29 @@ func (p *Level3) GetNodes() []string {
 	return nodes
 }
 
+// MatchNodes returns the candidate nodes for the query
+func (p *Level3) MatchNodes(query string) []string {
+	return matchNodes(p.Nodes, nil, query)
+}
+
+// CompletionCandidates returns the loaded nodes which match the prefix
+func (p *Level3) CompletionCandidates(prefix string) []string {
+	return completionCandidates(p.Nodes, prefix)
+}
+
+// Get returns the host and the ip version which are set
+func (p *Level3) Get() (string, string) {
+	if p.CIDR != "" && p.CIDR != "24" {
+		return p.Host + "/" + p.CIDR, p.IPv
+	}
+	return p.Host, p.IPv
+}
+
+// GetNode returns the current node
+func (p *Level3) GetNode() string {
+	return p.Node
+}
+
 // ChangeNode set new requested node
 func (p *Level3) ChangeNode(node string) bool {
 	// Validate
@@ -99,10 +126,10 @@ func (p *Level3) ChangeNode(node string) bool {
 func (p *Level3) Ping() (string, error) {
 	// Basic validate
 	if p.Node == "NA" || len(p.Host) < 5 {
-		print("Invalid node or host/ip address")
+		getLogger().Error("Invalid node or host/ip address")
 		return "", errors.New("error")
 	}
-	resp, err := http.PostForm(level3LGURL+level3LGPing,
+	resp, err := postFormContext(withLabels(context.Background(), "level3", p.Node, "ping"), httpClient, level3LGURL+level3LGPing,
 		url.Values{"count": {p.Count}, "size": {"64"}, "address": {p.Host}, "sitename": {level3Nodes[p.Node]}})
 	if err != nil {
 		return "", err
@@ -118,18 +145,20 @@ func (p *Level3) Ping() (string, error) {
 	r, _ := regexp.Compile(`</div></div>(?s)(.*?)</font></pre>`)
 	b := r.FindStringSubmatch(strings.Replace(string(body), "<br>", "\n", -1))
 	if len(b) > 0 {
-		return sanitize(b[1]), nil
+		out := sanitize(b[1])
+		observePing("level3", p.Node, out)
+		return out, nil
 	}
 	return "", errors.New("error")
 }
 
 // Trace gets traceroute information from level3
-func (p *Level3) Trace() chan string {
+func (p *Level3) Trace() (chan string, error) {
 	c := make(chan string)
-	resp, err := http.PostForm(level3LGURL+level3LGTrace,
+	resp, err := postFormContext(withLabels(context.Background(), "level3", p.Node, "trace"), httpClient, level3LGURL+level3LGTrace,
 		url.Values{"address": {p.Host}, "sitename": {level3Nodes[p.Node]}})
 	if err != nil {
-		println(err)
+		return nil, err
 	}
 	go func() {
 		defer resp.Body.Close()
@@ -144,17 +173,27 @@ func (p *Level3) Trace() chan string {
 		}
 		close(c)
 	}()
-	return c
+	return c, nil
+}
+
+// TraceStructured is like Trace but it parses each hop
+func (p *Level3) TraceStructured() (chan TraceHop, error) {
+	lines, err := p.Trace()
+	if err != nil {
+		return nil, err
+	}
+	return traceHops(lines), nil
 }
 
 // BGP gets bgp information
 func (p *Level3) BGP() chan string {
 	c := make(chan string)
-	resp, err := http.PostForm(level3LGURL+level3LGBGP,
+	resp, err := postFormContext(withLabels(context.Background(), "level3", p.Node, "bgp"), httpClient, level3LGURL+level3LGBGP,
 		url.Values{"address": {p.Host}, "length": {p.CIDR}, "sitename": {level3Nodes[p.Node]}})
 	if err != nil {
-		println(err.Error())
+		getLogger().Error("%s", err)
 		close(c)
+		return c
 	}
 	go func() {
 		defer resp.Body.Close()
@@ -178,15 +217,15 @@ func (p *Level3) BGP() chan string {
 //FetchNodes returns all available nodes through HTTP
 func (p *Level3) FetchNodes() map[string]string {
 	var nodes = make(map[string]string, 100)
-	resp, err := http.Get("http://lookingglass.level3.net/ping/lg_ping_main.php")
+	resp, err := getContext(withLabels(context.Background(), "level3", p.Node, "nodes"), httpClient, "http://lookingglass.level3.net/ping/lg_ping_main.php")
 	if err != nil {
-		println("error: level3 looking glass unreachable (1)")
+		getLogger().Warn("level3 looking glass unreachable (1)")
 		return map[string]string{}
 	}
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		println("error: level3 looking glass unreachable (2)" + err.Error())
+		getLogger().Warn("level3 looking glass unreachable (2) %s", err)
 		return map[string]string{}
 	}
 	r, _ := regexp.Compile(`(?i)<option value="(?s)([\w|\s|)(._-]+)">(?s)([a-z|\s|)(,._-]+)</option>`)
@@ -196,3 +235,8 @@ func (p *Level3) FetchNodes() map[string]string {
 	}
 	return nodes
 }
+
+// SupportedCommands returns the commands which level3 offers
+func (p *Level3) SupportedCommands() []Command {
+	return []Command{CmdPing, CmdTrace, CmdBGP}
+}
@@ -0,0 +1,209 @@
+package lg
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// LookingGlass represents the common looking glass methods
+// all of the providers need to implement
+type LookingGlass interface {
+	Set(host, version string)
+	Get() (host, version string)
+	GetDefaultNode() string
+	GetNodes() []string
+	GetNode() string
+	ChangeNode(node string) bool
+	Ping() (string, error)
+	Trace() (chan string, error)
+	BGP() chan string
+	SupportedCommands() []Command
+}
+
+// ErrUnexpectedResponse is returned if the looking glass
+// response doesn't have the expected format
+var ErrUnexpectedResponse = errors.New("unexpected looking glass response")
+
+// ErrUnsupportedCommand is returned if the selected node
+// doesn't support the requested command
+var ErrUnsupportedCommand = errors.New("the node doesn't support the command")
+
+var preRe = regexp.MustCompile(`(?is)<pre[^>]*>(.*?)</pre>`)
+
+// httpClient is used for the looking glass requests, the
+// default transport respects HTTP_PROXY and HTTPS_PROXY
+var httpClient = instrument(&http.Client{Timeout: 30 * time.Second})
+
+// SetHTTPClient replaces the looking glass http client, e.g.
+// to change the timeout, the proxy or the TLS settings. its
+// transport is wrapped so the requests are observed (see SetMetrics)
+func SetHTTPClient(c *http.Client) {
+	httpClient = instrument(c)
+}
+
+// deriveTransport returns a copy of the round tripper w/ its underlying
+// http.Transport cloned and changed by set, the metrics and the Recorder
+// wrappers are copied around the clone
+func deriveTransport(rt http.RoundTripper, set func(*http.Transport)) (http.RoundTripper, error) {
+	switch t := rt.(type) {
+	case nil:
+		return deriveTransport(http.DefaultTransport, set)
+	case *http.Transport:
+		c := t.Clone()
+		set(c)
+		return c, nil
+	case *metricsTransport:
+		base, err := deriveTransport(t.base, set)
+		if err != nil {
+			return nil, err
+		}
+		return &metricsTransport{base: base}, nil
+	case *Recorder:
+		base, err := deriveTransport(t.Transport, set)
+		if err != nil {
+			return nil, err
+		}
+		r := *t
+		r.Transport = base
+		return &r, nil
+	}
+	return nil, fmt.Errorf("the %T transport can't be derived", rt)
+}
+
+// userAgent is the User-Agent of the looking glass requests
+var userAgent = "mylg"
+
+// SetUserAgent replaces the User-Agent of the looking glass
+// requests, mylg sets it to mylg/<version>
+func SetUserAgent(ua string) {
+	userAgent = ua
+}
+
+// newRequest returns the looking glass request w/ the User-Agent
+func newRequest(ctx context.Context, method, u string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	return req, nil
+}
+
+// get is like http.Get but through the client w/ the User-Agent
+func get(c *http.Client, u string) (*http.Response, error) {
+	return getContext(context.Background(), c, u)
+}
+
+// getContext is like get but the request is bound to ctx
+func getContext(ctx context.Context, c *http.Client, u string) (*http.Response, error) {
+	req, err := newRequest(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// postForm is like http.PostForm but through the client w/ the User-Agent
+func postForm(c *http.Client, u string, form url.Values) (*http.Response, error) {
+	return postFormContext(context.Background(), c, u, form)
+}
+
+// postFormContext is like postForm but the request is bound to ctx
+func postFormContext(ctx context.Context, c *http.Client, u string, form url.Values) (*http.Response, error) {
+	req, err := newRequest(ctx, "POST", u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.Do(req)
+}
+
+// isTLSHandshakeErr returns true if the error happened during the TLS
+// handshake, the certificate errors aren't since the plain HTTP fallback
+// would bypass the verification
+func isTLSHandshakeErr(err error) bool {
+	var (
+		recordErr  tls.RecordHeaderError
+		authErr    x509.UnknownAuthorityError
+		hostErr    x509.HostnameError
+		invalidErr x509.CertificateInvalidError
+	)
+	if errors.As(err, &authErr) || errors.As(err, &hostErr) ||
+		errors.As(err, &invalidErr) || strings.Contains(err.Error(), "x509: ") {
+		return false
+	}
+	if errors.As(err, &recordErr) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "tls: ") || strings.Contains(msg, "HTTP response to HTTPS client")
+}
+
+// extractPre returns the content of all <pre> blocks, it returns
+// the stripped body w/ ErrUnexpectedResponse if there isn't any
+func extractPre(body string) (string, error) {
+	return extractBlocks(preRe, body)
+}
+
+// extractBlocks is like extractPre but the blocks are matched by re,
+// the block is the first group of re
+func extractBlocks(re *regexp.Regexp, body string) (string, error) {
+	var blocks []string
+	for _, m := range re.FindAllStringSubmatch(body, -1) {
+		if len(m) > 1 {
+			blocks = append(blocks, m[1])
+		}
+	}
+	if len(blocks) > 0 {
+		return strings.Join(blocks, "\n"), nil
+	}
+	text := strings.TrimSpace(sanitize(body))
+	if err := rateLimitedPage(text); err != nil {
+		return text, err
+	}
+	snippet := text
+	if len(snippet) > 100 {
+		snippet = snippet[:100] + "..."
+	}
+	return text, fmt.Errorf("%w: %q", ErrUnexpectedResponse, snippet)
+}
+
+var tagRe = regexp.MustCompile(`<[^>]*>`)
+
+// controlRe matches the ANSI escape sequences (CSI, OSC and the short
+// ones) and the control characters except the tab and the new line
+var controlRe = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)?|\x1b[ -/]*[0-~]?|[\x00-\x08\x0b-\x1f\x7f\x{80}-\x{9f}]`)
+
+// rawOutput keeps the control sequences of the looking glass output
+var rawOutput bool
+
+// SetRawOutput turns off the stripping of the ANSI escape codes and the
+// control characters of the looking glass output, e.g. to get the raw bytes
+func SetRawOutput(raw bool) {
+	rawOutput = raw
+}
+
+// stripControl removes the ANSI escape codes and the control characters
+func stripControl(s string) string {
+	if rawOutput {
+		return s
+	}
+	return controlRe.ReplaceAllString(s, "")
+}
+
+// cleanLine strips the HTML tags, the control sequences and
+// decodes the HTML entities
+func cleanLine(s string) string {
+	s = stripControl(html.UnescapeString(tagRe.ReplaceAllString(s, "")))
+	return strings.TrimRight(strings.Replace(s, "\u00a0", " ", -1), " \t\r")
+}
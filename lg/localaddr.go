@@ -0,0 +1,65 @@
+package lg
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// localAddrClients keeps the local address clients, so
+// the connections are reused across the requests
+var localAddrClients sync.Map
+
+type localAddrKey struct {
+	client *http.Client
+	addr   string
+}
+
+// ValidateLocalAddr parses the local source address and checks
+// that it's assigned to a local interface
+func ValidateLocalAddr(addr string) (net.IP, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid local address %q: it should be an ip address", addr)
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("local address %s: %v", addr, err)
+	}
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok && n.IP.Equal(ip) {
+			return ip, nil
+		}
+	}
+	return nil, fmt.Errorf("local address %s isn't assigned to any local interface", addr)
+}
+
+// localAddrClient returns a copy of the client w/ its transport
+// dialing from the local address, the address is validated once
+// and the client is cached
+func localAddrClient(c *http.Client, addr string) (*http.Client, error) {
+	key := localAddrKey{c, addr}
+	if lc, ok := localAddrClients.Load(key); ok {
+		return lc.(*http.Client), nil
+	}
+	ip, err := ValidateLocalAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	t, err := deriveTransport(c.Transport, func(t *http.Transport) {
+		t.DialContext = (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			LocalAddr: &net.TCPAddr{IP: ip},
+		}).DialContext
+	})
+	if err != nil {
+		return nil, fmt.Errorf("local address %s: %v", addr, err)
+	}
+	lc := *c
+	lc.Transport = t
+	v, _ := localAddrClients.LoadOrStore(key, &lc)
+	return v.(*http.Client), nil
+}
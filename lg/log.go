@@ -0,0 +1,107 @@
+package lg
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Logger is implemented by the loggers which the looking glass
+// notices, warnings and troubleshooting details are written to
+type Logger interface {
+	Debug(format string, v ...interface{})
+	Info(format string, v ...interface{})
+	Warn(format string, v ...interface{})
+	Error(format string, v ...interface{})
+}
+
+// Level represents the minimum level which is logged
+type Level int
+
+// the log levels, LevelQuiet turns off the logs
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelQuiet
+)
+
+// StdLogger writes the messages at or above the Level to W
+type StdLogger struct {
+	W     io.Writer
+	Level Level
+
+	mu sync.Mutex
+}
+
+var (
+	loggerMu sync.RWMutex
+	logger   Logger = NewStdLogger(os.Stderr, defaultLevel())
+)
+
+// NewStdLogger returns a logger which writes to w
+func NewStdLogger(w io.Writer, level Level) *StdLogger {
+	return &StdLogger{W: w, Level: level}
+}
+
+// SetLogger replaces the package logger, nil
+// turns off the logs
+func SetLogger(l Logger) {
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+	if l == nil {
+		l = NewStdLogger(os.Stderr, LevelQuiet)
+	}
+	logger = l
+}
+
+func getLogger() Logger {
+	loggerMu.RLock()
+	defer loggerMu.RUnlock()
+	return logger
+}
+
+// defaultLevel returns debug once MYLG_DEBUG
+// environment variable is set, otherwise info
+func defaultLevel() Level {
+	if os.Getenv("MYLG_DEBUG") != "" {
+		return LevelDebug
+	}
+	return LevelInfo
+}
+
+// Debug logs the details which are useful for troubleshooting
+func (l *StdLogger) Debug(format string, v ...interface{}) {
+	l.logf(LevelDebug, "debug: ", format, v...)
+}
+
+// Info logs the notices
+func (l *StdLogger) Info(format string, v ...interface{}) {
+	l.logf(LevelInfo, "", format, v...)
+}
+
+// Warn logs the failures which have a fallback
+func (l *StdLogger) Warn(format string, v ...interface{}) {
+	l.logf(LevelWarn, "warning: ", format, v...)
+}
+
+// Error logs the failures
+func (l *StdLogger) Error(format string, v ...interface{}) {
+	l.logf(LevelError, "error: ", format, v...)
+}
+
+func (l *StdLogger) logf(level Level, prefix, format string, v ...interface{}) {
+	if level < l.Level {
+		return
+	}
+	msg := fmt.Sprintf(format, v...)
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprint(l.W, prefix+msg)
+}
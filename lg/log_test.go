@@ -0,0 +1,29 @@
+package lg_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := lg.NewStdLogger(&buf, lg.LevelInfo)
+	l.Debug("request %d", 1)
+	l.Info("node doesn't support bgp")
+	l.Warn("cogent: %s", "unreachable")
+	l.Error("invalid host\n")
+
+	expected := "node doesn't support bgp\nwarning: cogent: unreachable\nerror: invalid host\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q but got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	l.Level = lg.LevelQuiet
+	l.Error("invalid host")
+	if buf.Len() != 0 {
+		t.Error("expected quiet logger but got", buf.String())
+	}
+}
@@ -0,0 +1,112 @@
+package lg
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// NodeMatcher is implemented by the providers which can
+// resolve a partial node name to the node(s)
+type NodeMatcher interface {
+	MatchNodes(query string) []string
+}
+
+// cityCodes maps the common airport / city abbreviations to the city names
+var cityCodes = map[string]string{
+	"ams": "amsterdam", "atl": "atlanta", "bos": "boston", "cdg": "paris",
+	"chi": "chicago", "den": "denver", "dfw": "dallas", "fra": "frankfurt",
+	"hkg": "hong kong", "iad": "washington", "jfk": "new york", "lax": "los angeles",
+	"lhr": "london", "lon": "london", "mad": "madrid", "mia": "miami",
+	"mil": "milan", "nrt": "tokyo", "nyc": "new york", "ord": "chicago",
+	"par": "paris", "phx": "phoenix", "sea": "seattle", "sfo": "san francisco",
+	"sin": "singapore", "sjc": "san jose", "sto": "stockholm", "syd": "sydney",
+	"tyo": "tokyo", "was": "washington", "yyz": "toronto", "zrh": "zurich",
+}
+
+// matchNodes returns the node names which match the query. an exact
+// match takes priority, otherwise the query is matched case-insensitive
+// against the names, the location codes and the city abbreviations
+func matchNodes(names []string, codes map[string]string, query string) []string {
+	var r []string
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return r
+	}
+	for _, n := range names {
+		if n == query {
+			return []string{n}
+		}
+	}
+	q := strings.ToLower(query)
+	for _, n := range names {
+		if strings.ToLower(n) == q {
+			return []string{n}
+		}
+	}
+
+	city, isCity := cityCodes[q]
+	for _, n := range names {
+		name := strings.ToLower(n)
+		switch {
+		case strings.Contains(name, q):
+		case strings.ToLower(codes[n]) == q:
+		case isCity && strings.Contains(name, city):
+		default:
+			continue
+		}
+		r = append(r, n)
+	}
+	return r
+}
+
+// NodeCompleter is implemented by the providers which can
+// suggest the node names for the console completion
+type NodeCompleter interface {
+	CompletionCandidates(prefix string) []string
+}
+
+// completionCandidates returns the names which the name or one of
+// its words starts with the prefix, case-insensitive
+func completionCandidates(names []string, prefix string) []string {
+	var r []string
+
+	p := strings.ToLower(strings.TrimLeft(prefix, " "))
+	for _, n := range names {
+		if wordPrefixIndex(strings.ToLower(n), p) >= 0 {
+			r = append(r, n)
+		}
+	}
+	return r
+}
+
+// wordPrefixIndex returns the index of the first word of name
+// which starts with the prefix or -1 if there is no such word
+func wordPrefixIndex(name, prefix string) int {
+	for i := 0; i < len(name); i++ {
+		if i > 0 && isWordChar(name[i-1]) {
+			continue
+		}
+		if strings.HasPrefix(name[i:], prefix) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// NodesContext is implemented by the providers which can
+// fetch the nodes w/ a deadline, see Cogent.GetNodesContext
+type NodesContext interface {
+	GetNodesContext(ctx context.Context) ([]string, error)
+}
+
+// NearestNodeSelector is implemented by the providers which can
+// select the node w/ the lowest rtt to the host, see Cogent.NearestNode
+type NearestNodeSelector interface {
+	NearestNode(host string) (string, time.Duration, error)
+}
@@ -0,0 +1,136 @@
+package lg
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Metrics is implemented by the metrics backends (e.g. prometheus)
+// so the looking glass requests can be observed w/o depending on them,
+// the requests are observed by the package http client transport
+// (see SetHTTPClient)
+type Metrics interface {
+	// ObserveRequest is called once a looking glass query is done,
+	// the errType is empty on success (see ErrorType)
+	ObserveRequest(provider, node, cmd string, d time.Duration, errType string)
+	// ObservePing is called w/ the average rtt of a successful ping
+	ObservePing(provider, node string, rtt time.Duration)
+}
+
+var (
+	metricsMu sync.RWMutex
+	metrics   Metrics = nopMetrics{}
+)
+
+// SetMetrics sets the looking glass metrics backend,
+// nil turns off the metrics
+func SetMetrics(m Metrics) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	if m == nil {
+		m = nopMetrics{}
+	}
+	metrics = m
+}
+
+func getMetrics() Metrics {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+	return metrics
+}
+
+// ErrorType returns the error category which is used as the metrics label
+func ErrorType(err error) string {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &netErr):
+		return "network"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrUnexpectedResponse):
+		return "unexpected_response"
+	case errors.Is(err, ErrUnsupportedCommand):
+		return "unsupported"
+	}
+	return "other"
+}
+
+// observeRequest reports the request result, the
+// status code >= 400 is counted as http error
+func observeRequest(provider, node, cmd string, start time.Time, resp *http.Response, err error) {
+	errType := ErrorType(err)
+	if err == nil && resp.StatusCode >= 400 {
+		errType = "http"
+	}
+	getMetrics().ObserveRequest(provider, node, cmd, time.Since(start), errType)
+}
+
+// observePing reports the ping average rtt if the output has the statistics
+func observePing(provider, node, out string) {
+	if s, err := ParsePing(out); err == nil && s.PacketsReceived > 0 {
+		getMetrics().ObservePing(provider, node, s.Avg)
+	}
+}
+
+// requestLabels are the metrics labels of a looking glass request
+type requestLabels struct {
+	provider, node, cmd string
+}
+
+type requestLabelsKey struct{}
+
+// withLabels returns the ctx w/ the metrics labels of the requests
+func withLabels(ctx context.Context, provider, node, cmd string) context.Context {
+	return context.WithValue(ctx, requestLabelsKey{}, &requestLabels{provider, node, cmd})
+}
+
+// metricsTransport observes the labeled requests (see withLabels) until
+// the response headers, the labels are dropped from the request which is
+// passed to the base so a nested metricsTransport doesn't count it twice
+type metricsTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	l, _ := req.Context().Value(requestLabelsKey{}).(*requestLabels)
+	if l == nil {
+		return base.RoundTrip(req)
+	}
+	start := time.Now()
+	resp, err := base.RoundTrip(req.WithContext(context.WithValue(req.Context(), requestLabelsKey{}, (*requestLabels)(nil))))
+	observeRequest(l.provider, l.node, l.cmd, start, resp, err)
+	return resp, err
+}
+
+// instrument returns a copy of the client w/ its transport wrapped
+// by metricsTransport, it returns the client if it's already wrapped
+func instrument(c *http.Client) *http.Client {
+	if _, ok := c.Transport.(*metricsTransport); ok {
+		return c
+	}
+	ic := *c
+	ic.Transport = &metricsTransport{base: c.Transport}
+	return &ic
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveRequest(provider, node, cmd string, d time.Duration, errType string) {}
+func (nopMetrics) ObservePing(provider, node string, rtt time.Duration)                       {}
@@ -0,0 +1,132 @@
+package lg
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// HopStats represents the accumulated statistics of a hop
+type HopStats struct {
+	Index    int
+	IPs      []string
+	Sent     int
+	Received int
+	Loss     float64
+	Last     time.Duration
+	Avg      time.Duration
+	Best     time.Duration
+	Worst    time.Duration
+
+	total time.Duration
+}
+
+// MTR re-runs the trace every interval and sends the accumulated
+// hop statistics after each round, it runs until ctx is canceled
+// if cycles is zero. the channel is closed once it's done
+func MTR(ctx context.Context, t StructuredTracer, interval time.Duration, cycles int) chan []HopStats {
+	c := make(chan []HopStats)
+	go func() {
+		defer close(c)
+		stats := map[int]*HopStats{}
+		for round := 0; cycles <= 0 || round < cycles; round++ {
+			if round > 0 {
+				timer := time.NewTimer(interval)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+			}
+			hops, err := t.TraceStructured()
+			if err != nil {
+				getLogger().Debug("mtr: round %d failed: %s", round, err)
+				continue
+			}
+			if !collectHops(ctx, hops, stats) {
+				return
+			}
+			select {
+			case c <- snapshot(stats):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// collectHops accumulates the round hops, it returns false if
+// ctx is canceled and the rest of the hops are drained
+func collectHops(ctx context.Context, hops chan TraceHop, stats map[int]*HopStats) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			go func() {
+				for range hops {
+				}
+			}()
+			return false
+		case hop, ok := <-hops:
+			if !ok {
+				return true
+			}
+			s, ok := stats[hop.Index]
+			if !ok {
+				s = &HopStats{Index: hop.Index}
+				stats[hop.Index] = s
+			}
+			s.add(hop)
+		}
+	}
+}
+
+// add accumulates the hop probes
+func (s *HopStats) add(hop TraceHop) {
+	if hop.IP != "" && !containsString(s.IPs, hop.IP) {
+		s.IPs = append(s.IPs, hop.IP)
+	}
+	s.Sent += len(hop.RTTs) + hop.Timeouts
+	if len(hop.RTTs) == 0 && hop.Timeouts == 0 {
+		s.Sent++
+	}
+	for _, rtt := range hop.RTTs {
+		if s.Received == 0 || rtt < s.Best {
+			s.Best = rtt
+		}
+		if rtt > s.Worst {
+			s.Worst = rtt
+		}
+		s.Received++
+		s.total += rtt
+		s.Last = rtt
+	}
+	if s.Received > 0 {
+		s.Avg = s.total / time.Duration(s.Received)
+	}
+	if s.Sent > 0 {
+		s.Loss = float64(s.Sent-s.Received) * 100 / float64(s.Sent)
+	}
+}
+
+// snapshot returns a copy of the statistics ordered by the hop index
+func snapshot(stats map[int]*HopStats) []HopStats {
+	var r []HopStats
+	for _, s := range stats {
+		h := *s
+		h.IPs = append([]string(nil), s.IPs...)
+		r = append(r, h)
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Index < r[j].Index })
+	return r
+}
+
+func containsString(s []string, v string) bool {
+	for _, i := range s {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package lg_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+// fakeTracer returns the rounds hops in order
+type fakeTracer struct {
+	rounds [][]lg.TraceHop
+	n      int
+}
+
+func (f *fakeTracer) TraceStructured() (chan lg.TraceHop, error) {
+	c := make(chan lg.TraceHop)
+	hops := f.rounds[f.n%len(f.rounds)]
+	f.n++
+	go func() {
+		for _, h := range hops {
+			c <- h
+		}
+		close(c)
+	}()
+	return c, nil
+}
+
+func TestMTR(t *testing.T) {
+	ms := time.Millisecond
+	f := &fakeTracer{rounds: [][]lg.TraceHop{
+		{
+			{Index: 1, IP: "192.0.2.1", RTTs: []time.Duration{1 * ms, 3 * ms}},
+			{Index: 2, Timeouts: 2},
+		},
+		{
+			{Index: 1, IP: "192.0.2.2", RTTs: []time.Duration{2 * ms}, Timeouts: 1},
+			{Index: 2, IP: "192.0.2.9", RTTs: []time.Duration{10 * ms, 10 * ms}},
+		},
+	}}
+
+	var snapshots [][]lg.HopStats
+	for s := range lg.MTR(context.Background(), f, time.Millisecond, 2) {
+		snapshots = append(snapshots, s)
+	}
+	if len(snapshots) != 2 {
+		t.Fatal("expected 2 snapshots but got", len(snapshots))
+	}
+	hop := snapshots[1][0]
+	if !reflect.DeepEqual(hop.IPs, []string{"192.0.2.1", "192.0.2.2"}) {
+		t.Error("expected the observed ips but got", hop.IPs)
+	}
+	if hop.Sent != 4 || hop.Received != 3 || hop.Loss != 25 {
+		t.Errorf("unexpected hop 1 counters %+v", hop)
+	}
+	if hop.Last != 2*ms || hop.Avg != 2*ms || hop.Best != 1*ms || hop.Worst != 3*ms {
+		t.Errorf("unexpected hop 1 rtts %+v", hop)
+	}
+	if hop := snapshots[1][1]; hop.Loss != 50 || hop.Best != 10*ms {
+		t.Errorf("unexpected hop 2 %+v", hop)
+	}
+	// the first snapshot shouldn't be changed by the next rounds
+	if s := snapshots[0][0]; s.Sent != 2 || len(s.IPs) != 1 {
+		t.Errorf("unexpected first snapshot %+v", s)
+	}
+}
+
+func TestMTRCancel(t *testing.T) {
+	f := &fakeTracer{rounds: [][]lg.TraceHop{{{Index: 1, IP: "192.0.2.1"}}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := lg.MTR(ctx, f, time.Millisecond, 0)
+	<-c
+	<-c
+	cancel()
+	for range c {
+	}
+}
@@ -4,10 +4,10 @@ package lg
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"io/ioutil"
 	"net"
-	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
@@ -24,12 +24,16 @@ type NTT struct {
 
 var NTTDefaultNode = "Los Angeles, CA - US"
 
+func init() {
+	Register("ntt", func() LookingGlass { return new(NTT) })
+}
+
 // Set configures host and ip version
 func (p *NTT) Set(host, version string) {
 	p.Host = host
-	p.IPv = version
+	p.IPv = ipVersion(version)
 	if p.Node == "" {
-		p.Node = NTTDefaultNode
+		p.Node = defaultNode("ntt", NTTDefaultNode)
 	}
 }
 
@@ -51,15 +55,15 @@ func (p *NTT) GetNodes() []string {
 //FetchNodes returns all available nodes through HTTP
 func (p *NTT) FetchNodes() map[string]string {
 	var nodes = make(map[string]string, 100)
-	resp, err := http.Get("http://ssp.pme.gin.ntt.net/lg/lg.cgi")
+	resp, err := getContext(withLabels(context.Background(), "ntt", p.Node, "nodes"), httpClient, "http://ssp.pme.gin.ntt.net/lg/lg.cgi")
 	if err != nil {
-		println("error: NTT looking glass unreachable (1) ")
+		getLogger().Warn("NTT looking glass unreachable (1)")
 		return map[string]string{}
 	}
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		println("error: NTT looking glass unreachable (2)" + err.Error())
+		getLogger().Warn("NTT looking glass unreachable (2) %s", err)
 		return map[string]string{}
 	}
 	r, _ := regexp.Compile(`(?i)<option value="(?s)([\w|\s|)(,._-]+)"> (?s)([\w|\s|)(,._-]+)`)
@@ -70,6 +74,26 @@ func (p *NTT) FetchNodes() map[string]string {
 	return nodes
 }
 
+// MatchNodes returns the candidate nodes for the query
+func (p *NTT) MatchNodes(query string) []string {
+	return matchNodes(p.Nodes, nil, query)
+}
+
+// CompletionCandidates returns the loaded nodes which match the prefix
+func (p *NTT) CompletionCandidates(prefix string) []string {
+	return completionCandidates(p.Nodes, prefix)
+}
+
+// Get returns the host and the ip version which are set
+func (p *NTT) Get() (string, string) {
+	return p.Host, p.IPv
+}
+
+// GetNode returns the current node
+func (p *NTT) GetNode() string {
+	return p.Node
+}
+
 // ChangeNode set new requested node
 func (p *NTT) ChangeNode(node string) bool {
 	// Validate
@@ -84,8 +108,8 @@ func (p *NTT) ChangeNode(node string) bool {
 
 // GetDefaultNode returns NTT default node
 func (p *NTT) GetDefaultNode() string {
-	println("please check NTT Com LG terms of use first at https://www.us.ntt.net/support/looking-glass/")
-	return NTTDefaultNode
+	getLogger().Info("please check NTT Com LG terms of use first at https://www.us.ntt.net/support/looking-glass/")
+	return defaultNode("ntt", NTTDefaultNode)
 }
 
 // Ping tries to connect NTT's ping looking glass through HTTP
@@ -93,10 +117,10 @@ func (p *NTT) GetDefaultNode() string {
 func (p *NTT) Ping() (string, error) {
 	// Basic validate
 	if p.Node == "NA" || len(p.Host) < 5 {
-		print("Invalid node or host/ip address")
+		getLogger().Error("Invalid node or host/ip address")
 		return "", errors.New("error")
 	}
-	resp, err := http.PostForm("https://ssp.pme.gin.ntt.net/lg/lg.cgi",
+	resp, err := postFormContext(withLabels(context.Background(), "ntt", p.Node, "ping"), httpClient, "https://ssp.pme.gin.ntt.net/lg/lg.cgi",
 		url.Values{"query": {"ping"}, "protocol": {p.IPv}, "addrFQDN": {p.Host}, "router": {p.Node}, "sourceIP": {"FQDN"}})
 	if err != nil {
 		return "", err
@@ -112,24 +136,24 @@ func (p *NTT) Ping() (string, error) {
 	r, _ := regexp.Compile(`<CODE>(?s)(.*?)</CODE>`)
 	b := r.FindStringSubmatch(string(body))
 	if len(b) > 0 {
+		observePing("ntt", p.Node, b[1])
 		return b[1], nil
 	}
-	println(string(body))
+	getLogger().Debug("ntt: unexpected response %s", body)
 	return "", errors.New("error")
 }
 
 // Trace gets traceroute information from NTT
-func (p *NTT) Trace() chan string {
+func (p *NTT) Trace() (chan string, error) {
 	c := make(chan string)
-	sigCh := make(chan os.Signal, 1)
-
-	signal.Notify(sigCh, os.Interrupt)
-
-	resp, err := http.PostForm("https://ssp.pme.gin.ntt.net/lg/lg.cgi",
+	resp, err := postFormContext(withLabels(context.Background(), "ntt", p.Node, "trace"), httpClient, "https://ssp.pme.gin.ntt.net/lg/lg.cgi",
 		url.Values{"query": {"trace"}, "protocol": {p.IPv}, "addrFQDN": {p.Host}, "router": {p.Node}, "sourceIP": {"FQDN"}})
 	if err != nil {
-		println(err)
+		return nil, err
 	}
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+
 	go func() {
 		defer resp.Body.Close()
 		scanner := bufio.NewScanner(resp.Body)
@@ -138,7 +162,7 @@ func (p *NTT) Trace() chan string {
 			l := scanner.Text()
 			m, _ := regexp.MatchString(`(?i)^(tracing|traceroute|\s*\d{1,2})`, l)
 			if m {
-				l = replaceASNTrace(l)
+				l = cleanLine(replaceASNTrace(l))
 				select {
 				case <-sigCh:
 					break LOOP
@@ -149,7 +173,16 @@ func (p *NTT) Trace() chan string {
 		signal.Stop(sigCh)
 		close(c)
 	}()
-	return c
+	return c, nil
+}
+
+// TraceStructured is like Trace but it parses each hop
+func (p *NTT) TraceStructured() (chan TraceHop, error) {
+	lines, err := p.Trace()
+	if err != nil {
+		return nil, err
+	}
+	return traceHops(lines), nil
 }
 
 // BGP gets bgp information from NTT
@@ -158,13 +191,15 @@ func (p *NTT) BGP() chan string {
 
 	_, _, err := net.ParseCIDR(p.Host)
 	if err == nil {
-		println("Only IP addresses are allowed for NTT Looking Glass BGP Queries")
+		getLogger().Info("Only IP addresses are allowed for NTT Looking Glass BGP Queries")
 	}
 
-	resp, err := http.PostForm("https://ssp.pme.gin.ntt.net/lg/lg.cgi",
+	resp, err := postFormContext(withLabels(context.Background(), "ntt", p.Node, "bgp"), httpClient, "https://ssp.pme.gin.ntt.net/lg/lg.cgi",
 		url.Values{"query": {"bgp"}, "protocol": {p.IPv}, "addr": {p.Host}, "router": {p.Node}, "sourceIP": {"IP"}})
 	if err != nil {
-		println(err)
+		getLogger().Error("%s", err)
+		close(c)
+		return c
 	}
 	// IP addresses are allowed parameters for BGP Queries
 	go func() {
@@ -191,3 +226,8 @@ func (p *NTT) BGP() chan string {
 	}()
 	return c
 }
+
+// SupportedCommands returns the commands which NTT offers
+func (p *NTT) SupportedCommands() []Command {
+	return []Command{CmdPing, CmdTrace, CmdBGP}
+}
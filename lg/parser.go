@@ -0,0 +1,170 @@
+package lg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CogentParser holds the patterns which the Cogent responses are parsed
+// by. once the looking glass pages change, a pattern can be overridden
+// by its name w/o rebuilding mylg through the config file, e.g.
+//
+//	{"cogent_patterns": {"node": "(?is)Option\\(\"([^\"]+)\",\"(\\w+)\""}}
+//
+// the names are output, trace_line, bgp_entry, bgp_path, node, source
+// and nodes_split. Validate reports the patterns which don't match a
+// recorded page (see Recorder) so the format drift is detectable. the
+// form field names are scraped from the looking glass form by FetchNodes
+type CogentParser struct {
+	// Output matches the command output block of ping, trace and bgp,
+	// the first group is the output
+	Output *regexp.Regexp
+	// TraceLine matches the trace header and hop lines
+	TraceLine *regexp.Regexp
+	// BGPEntry matches the bgp routing table entry line (detail
+	// format), the first group is the prefix
+	BGPEntry *regexp.Regexp
+	// BGPPath matches the bgp as-path line (detail format), the
+	// first group is the as-path or Local
+	BGPPath *regexp.Regexp
+	// Node matches the node options, the groups are the name and the code
+	Node *regexp.Regexp
+	// Source matches the probe source options, the groups are
+	// the name and the value
+	Source *regexp.Regexp
+	// NodesSplit matches the boundary of the bgp nodes (before)
+	// and the ping and trace nodes (after)
+	NodesSplit *regexp.Regexp
+
+	mu     sync.RWMutex
+	fields formFields
+}
+
+// cogentLinePatterns are matched against each output line
+var cogentLinePatterns = map[string]bool{"trace_line": true, "bgp_entry": true, "bgp_path": true}
+
+// cogentPatternGroups are the min capture groups of the patterns which
+// the parsers index, e.g. the node name and code are the groups 1 and 2
+var cogentPatternGroups = map[string]int{"output": 1, "bgp_entry": 1, "bgp_path": 1, "node": 2, "source": 2}
+
+// cogentParser is the parser of the Cogent w/o Parser
+var cogentParser = DefaultCogentParser()
+
+// DefaultCogentParser returns the built-in Cogent patterns
+func DefaultCogentParser() *CogentParser {
+	return &CogentParser{
+		Output:     preRe,
+		TraceLine:  regexp.MustCompile(`^(traceroute|\s*\d{1,2})`),
+		BGPEntry:   bgpEntryRe,
+		BGPPath:    bgpASPathRe,
+		Node:       cogentOptionRe,
+		Source:     cogentSourceRe,
+		NodesSplit: regexp.MustCompile(`default:`),
+	}
+}
+
+// SetCogentParser replaces the parser of the Cogent w/o Parser,
+// the default parser is restored if it's nil
+func SetCogentParser(cp *CogentParser) {
+	if cp == nil {
+		cp = DefaultCogentParser()
+	}
+	cogentParser = cp
+}
+
+// patterns returns the parser patterns by name
+func (cp *CogentParser) patterns() map[string]**regexp.Regexp {
+	return map[string]**regexp.Regexp{
+		"output":      &cp.Output,
+		"trace_line":  &cp.TraceLine,
+		"bgp_entry":   &cp.BGPEntry,
+		"bgp_path":    &cp.BGPPath,
+		"node":        &cp.Node,
+		"source":      &cp.Source,
+		"nodes_split": &cp.NodesSplit,
+	}
+}
+
+// Override replaces the named pattern w/ the compiled expression
+func (cp *CogentParser) Override(name, expr string) error {
+	re, ok := cp.patterns()[name]
+	if !ok {
+		return fmt.Errorf("unknown cogent pattern %q", name)
+	}
+	c, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("cogent pattern %s: %v", name, err)
+	}
+	if n := cogentPatternGroups[name]; c.NumSubexp() < n {
+		return fmt.Errorf("cogent pattern %s: it should have %d capture group(s) but it has %d", name, n, c.NumSubexp())
+	}
+	*re = c
+	return nil
+}
+
+// Validate runs the patterns against the page and returns the sorted
+// names of the patterns w/o any match. the page of one command doesn't
+// have all of the patterns e.g. the nodes page has no trace lines
+func (cp *CogentParser) Validate(page string) []string {
+	var (
+		lines    = strings.Split(page, "\n")
+		outputs  = cp.Output.FindAllStringSubmatch(page, -1)
+		zeroHits []string
+	)
+	if len(outputs) > 0 {
+		lines = nil
+		for _, m := range outputs {
+			lines = append(lines, strings.Split(m[len(m)-1], "\n")...)
+		}
+	}
+	for name, re := range cp.patterns() {
+		if !cogentLinePatterns[name] {
+			if !(*re).MatchString(page) {
+				zeroHits = append(zeroHits, name)
+			}
+			continue
+		}
+		var n int
+		for _, l := range lines {
+			if (*re).MatchString(cleanLine(l)) {
+				n++
+			}
+		}
+		if n == 0 {
+			zeroHits = append(zeroHits, name)
+		}
+	}
+	sort.Strings(zeroHits)
+	return zeroHits
+}
+
+// ValidateFile is like Validate but the page is read from the
+// file, it can be a recording of the Recorder or the raw page
+func (cp *CogentParser) ValidateFile(file string) ([]string, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var rec recording
+	if json.Unmarshal(b, &rec) == nil && len(rec.Body) > 0 {
+		body, err := rec.text()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		return cp.Validate(body), nil
+	}
+	return cp.Validate(string(b)), nil
+}
+
+// parser returns the Cogent parser or the package one
+func (p *Cogent) parser() *CogentParser {
+	if p.Parser != nil {
+		return p.Parser
+	}
+	return cogentParser
+}
@@ -0,0 +1,70 @@
+package lg_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestCogentParserValidate(t *testing.T) {
+	cp := lg.DefaultCogentParser()
+	tests := []struct {
+		name, page string
+		want       []string
+	}{
+		{"nodes", cogentNodesFixture, []string{"bgp_entry", "bgp_path", "output", "source", "trace_line"}},
+		{"trace", cogentTraceFixture, []string{"bgp_entry", "bgp_path", "node", "nodes_split", "source"}},
+	}
+	for _, tt := range tests {
+		if got := cp.Validate(tt.page); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %q but got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCogentParserOverride(t *testing.T) {
+	const page = `<script>
+	LOC.options[0] = new Option('US - Denver','denv');
+</script>`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(page))
+	}))
+	defer ts.Close()
+
+	cp := lg.DefaultCogentParser()
+	want := []string{"bgp_entry", "bgp_path", "node", "nodes_split", "output", "source", "trace_line"}
+	if got := cp.Validate(page); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q but got %q", want, got)
+	}
+	if err := cp.Override("node", `Option\('([^']+)','(\w+)'`); err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.Override("nodes_split", `^`); err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.Override("foo", `.`); err == nil {
+		t.Error("expected unknown pattern error")
+	}
+	path := cp.BGPPath
+	for name, expr := range map[string]string{"bgp_path": `Path: .*`, "node": `Option\('([^']+)',`, "output": `<pre>`} {
+		if err := cp.Override(name, expr); err == nil || !strings.Contains(err.Error(), "capture group") {
+			t.Errorf("%s: expected the capture groups error but got %v", name, err)
+		}
+	}
+	if cp.BGPPath != path {
+		t.Error("expected the invalid override isn't applied")
+	}
+	c := &lg.Cogent{BaseURL: ts.URL, DisableRetry: true, Parser: cp}
+	nodes, _, err := c.FetchNodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(nodes, map[string]string{"US - Denver": "denv"}) {
+		t.Error("unexpected nodes", nodes)
+	}
+}
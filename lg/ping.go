@@ -0,0 +1,162 @@
+package lg
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mehrdadrad/mylg/stats"
+)
+
+// PingStats represents the looking glass ping statistics
+type PingStats struct {
+	PacketsSent     int
+	PacketsReceived int
+	LossPercent     float64
+	Min             time.Duration
+	Avg             time.Duration
+	Max             time.Duration
+
+	// P50, P90, P99 and Jitter (the mean deviation of the consecutive
+	// rtts) need the replies rtts, they're nil if the output only
+	// has the summary
+	P50    *time.Duration
+	P90    *time.Duration
+	P99    *time.Duration
+	Jitter *time.Duration
+
+	// Replies are the reply lines, see ParsePingReplies
+	Replies []PingReply
+}
+
+// PingReply represents a ping reply line, Lost is set for the timeout
+// and the unreachable lines. Seq is the previous one plus one if the
+// line doesn't have it and TTL and RTT are zero if they're missing.
+// the upper bound rtts (e.g. time<1ms) are recorded as the half of
+// the bound (0.5ms) so they're below the bound like the real rtts
+type PingReply struct {
+	Seq  int
+	TTL  int
+	RTT  time.Duration
+	Lost bool
+}
+
+// PingResult represents a node ping statistics or its error
+type PingResult struct {
+	PingStats
+	Err error
+}
+
+var (
+	pingPacketsRe = regexp.MustCompile(`(\d+)\s+packets\s+transmitted,\s+(\d+)\s+(?:packets\s+)?received`)
+	pingLossRe    = regexp.MustCompile(`([\d.]+)%\s+packet\s+loss`)
+	pingRTTRe     = regexp.MustCompile(`(?:rtt|round-trip)\s+min/avg/max(?:/\w+)?\s*=\s*([\d.]+)/([\d.]+)/([\d.]+)`)
+	pingReplyRe   = regexp.MustCompile(`(?i)(bytes|reply)\s+from\s`)
+	pingLostRe    = regexp.MustCompile(`(?i)(request\s+time(d\s+)?out|no\s+answer\s+yet|unreachable|time\s+to\s+live\s+exceeded)`)
+	pingSeqRe     = regexp.MustCompile(`(?i)\b(?:icmp_)?seq(?:=|\s+)(\d+)`)
+	pingTTLRe     = regexp.MustCompile(`(?i)\bttl=(\d+)`)
+	pingTimeRe    = regexp.MustCompile(`(?i)\btime\s*([=<])\s*([\d.]+)\s*(ms|us|µs|μs)?`)
+)
+
+// ParsePing parses the ping output statistics, the rtt
+// values are zero if there isn't any reply
+func ParsePing(raw string) (PingStats, error) {
+	var s PingStats
+
+	m := pingPacketsRe.FindStringSubmatch(raw)
+	if len(m) != 3 {
+		return s, errors.New("ping statistics not found")
+	}
+	s.PacketsSent, _ = strconv.Atoi(m[1])
+	s.PacketsReceived, _ = strconv.Atoi(m[2])
+	if m = pingLossRe.FindStringSubmatch(raw); len(m) == 2 {
+		s.LossPercent, _ = strconv.ParseFloat(m[1], 64)
+	} else if s.PacketsSent > 0 {
+		s.LossPercent = float64(s.PacketsSent-s.PacketsReceived) * 100 / float64(s.PacketsSent)
+	}
+	if m = pingRTTRe.FindStringSubmatch(raw); len(m) == 4 {
+		s.Min, s.Avg, s.Max = msToDuration(m[1]), msToDuration(m[2]), msToDuration(m[3])
+	}
+	s.Replies = ParsePingReplies(raw)
+	// the rtts are in ns so the percentiles are the exact rtts
+	var rtts []float64
+	for _, r := range s.Replies {
+		if !r.Lost && r.RTT > 0 {
+			rtts = append(rtts, float64(r.RTT))
+		}
+	}
+	if len(rtts) > 0 {
+		s.P50, s.P90, s.P99 = percentile(rtts, 50), percentile(rtts, 90), percentile(rtts, 99)
+	}
+	if len(rtts) > 1 {
+		j := time.Duration(stats.Jitter(rtts))
+		s.Jitter = &j
+	}
+	return s, nil
+}
+
+// ParsePingReplies parses the reply lines of the ping output, e.g.
+// 64 bytes from 8.8.8.8: icmp_seq=1 ttl=57 time=12.3 ms, the timeout
+// and the unreachable lines (e.g. Request timed out) are lost replies
+func ParsePingReplies(raw string) []PingReply {
+	var replies []PingReply
+	for _, l := range strings.Split(raw, "\n") {
+		var r PingReply
+		switch {
+		case pingLostRe.MatchString(l):
+			r.Lost = true
+		case pingReplyRe.MatchString(l):
+			if m := pingTTLRe.FindStringSubmatch(l); m != nil {
+				r.TTL, _ = strconv.Atoi(m[1])
+			}
+			if m := pingTimeRe.FindStringSubmatch(l); m != nil {
+				unit, ok := traceRTTUnits[strings.ToLower(m[3])]
+				if !ok {
+					unit = time.Millisecond
+				}
+				v, _ := strconv.ParseFloat(m[2], 64)
+				if m[1] == "<" {
+					v /= 2
+				}
+				r.RTT = time.Duration(v * float64(unit))
+			}
+		default:
+			continue
+		}
+		if m := pingSeqRe.FindStringSubmatch(l); m != nil {
+			r.Seq, _ = strconv.Atoi(m[1])
+		} else if len(replies) > 0 {
+			r.Seq = replies[len(replies)-1].Seq + 1
+		}
+		replies = append(replies, r)
+	}
+	return replies
+}
+
+// percentile returns the nearest-rank percentile (0-100) of the rtts
+func percentile(rtts []float64, p float64) *time.Duration {
+	d := time.Duration(stats.Percentile(rtts, p))
+	return &d
+}
+
+// Percentiles returns the p50/p90/p99 and the jitter line,
+// it's empty if the percentiles aren't available
+func (s PingStats) Percentiles() string {
+	if s.P50 == nil {
+		return ""
+	}
+	l := fmt.Sprintf("p50/p90/p99 = %.3f/%.3f/%.3f ms", durationToMs(*s.P50), durationToMs(*s.P90), durationToMs(*s.P99))
+	if s.Jitter != nil {
+		l += fmt.Sprintf(", jitter = %.3f ms", durationToMs(*s.Jitter))
+	}
+	return l
+}
+
+// msToDuration converts milliseconds string to time.Duration
+func msToDuration(ms string) time.Duration {
+	f, _ := strconv.ParseFloat(ms, 64)
+	return time.Duration(f * float64(time.Millisecond))
+}
@@ -0,0 +1,176 @@
+package lg_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+const pingNoReplyOutput = `PING 192.0.2.1 (192.0.2.1) 56(84) bytes of data.
+
+--- 192.0.2.1 ping statistics ---
+5 packets transmitted, 0 received, 100% packet loss, time 4001ms
+`
+
+const pingRepliesOutput = `PING 8.8.8.8 (8.8.8.8) 56(84) bytes of data.
+64 bytes from 8.8.8.8: icmp_seq=1 ttl=57 time=1.0 ms
+64 bytes from 8.8.8.8: icmp_seq=2 ttl=57 time=3.0 ms
+64 bytes from 8.8.8.8: icmp_seq=3 ttl=57 time=2.0 ms
+64 bytes from 8.8.8.8: icmp_seq=4 ttl=57 time=5.0 ms
+64 bytes from 8.8.8.8: icmp_seq=5 ttl=57 time=4.0 ms
+
+--- 8.8.8.8 ping statistics ---
+5 packets transmitted, 5 received, 0% packet loss, time 4005ms
+rtt min/avg/max/mdev = 1.000/3.000/5.000/1.414 ms
+`
+
+const pingPartialOutput = `PING 8.8.8.8 (8.8.8.8): 56 data bytes
+
+--- 8.8.8.8 ping statistics ---
+4 packets transmitted, 3 packets received, 25.0% packet loss
+round-trip min/avg/max/stddev = 1.100/1.200/1.400/0.100 ms
+`
+
+func TestParsePing(t *testing.T) {
+	d := func(d time.Duration) *time.Duration { return &d }
+	tests := []struct {
+		name string
+		raw  string
+		want lg.PingStats
+	}{
+		{
+			name: "success",
+			raw:  cogentPingOutput,
+			want: lg.PingStats{
+				PacketsSent:     1,
+				PacketsReceived: 1,
+				Min:             1210 * time.Microsecond,
+				Avg:             1210 * time.Microsecond,
+				Max:             1210 * time.Microsecond,
+				P50:             d(1210 * time.Microsecond),
+				P90:             d(1210 * time.Microsecond),
+				P99:             d(1210 * time.Microsecond),
+				Replies:         []lg.PingReply{{Seq: 1, TTL: 57, RTT: 1210 * time.Microsecond}},
+			},
+		},
+		{
+			name: "replies",
+			raw:  pingRepliesOutput,
+			want: lg.PingStats{
+				PacketsSent:     5,
+				PacketsReceived: 5,
+				Min:             time.Millisecond,
+				Avg:             3 * time.Millisecond,
+				Max:             5 * time.Millisecond,
+				P50:             d(3 * time.Millisecond),
+				P90:             d(5 * time.Millisecond),
+				P99:             d(5 * time.Millisecond),
+				Jitter:          d(1750 * time.Microsecond),
+				Replies: []lg.PingReply{
+					{Seq: 1, TTL: 57, RTT: time.Millisecond},
+					{Seq: 2, TTL: 57, RTT: 3 * time.Millisecond},
+					{Seq: 3, TTL: 57, RTT: 2 * time.Millisecond},
+					{Seq: 4, TTL: 57, RTT: 5 * time.Millisecond},
+					{Seq: 5, TTL: 57, RTT: 4 * time.Millisecond},
+				},
+			},
+		},
+		{
+			name: "no reply",
+			raw:  pingNoReplyOutput,
+			want: lg.PingStats{PacketsSent: 5, LossPercent: 100},
+		},
+		{
+			name: "partial",
+			raw:  pingPartialOutput,
+			want: lg.PingStats{
+				PacketsSent:     4,
+				PacketsReceived: 3,
+				LossPercent:     25,
+				Min:             1100 * time.Microsecond,
+				Avg:             1200 * time.Microsecond,
+				Max:             1400 * time.Microsecond,
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, err := lg.ParsePing(tt.raw)
+		if err != nil {
+			t.Error(tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %+v but got %+v", tt.name, tt.want, got)
+		}
+	}
+	s, _ := lg.ParsePing(pingRepliesOutput)
+	if p := s.Percentiles(); p != "p50/p90/p99 = 3.000/5.000/5.000 ms, jitter = 1.750 ms" {
+		t.Error("unexpected percentiles", p)
+	}
+	if s, _ = lg.ParsePing(pingPartialOutput); s.Percentiles() != "" {
+		t.Error("expected no percentiles w/o the replies but got", s.Percentiles())
+	}
+	if _, err := lg.ParsePing("<html>error</html>"); err == nil {
+		t.Error("expected error for output w/o statistics")
+	}
+}
+
+func TestParsePingReplies(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name string
+		raw  string
+		want []lg.PingReply
+	}{
+		{
+			name: "linux",
+			raw: `PING 8.8.8.8 (8.8.8.8) 56(84) bytes of data.
+64 bytes from 8.8.8.8: icmp_seq=1 ttl=57 time=12.3 ms
+no answer yet for icmp_seq=2
+From 192.0.2.1 icmp_seq=3 Destination Host Unreachable
+64 bytes from 8.8.8.8: icmp_seq=5 ttl=58 time=11 ms
+64 bytes from 8.8.8.8: icmp_seq=4 ttl=57 time=980 us
+
+--- 8.8.8.8 ping statistics ---
+5 packets transmitted, 3 received, +1 errors, 40% packet loss, time 4005ms`,
+			want: []lg.PingReply{
+				{Seq: 1, TTL: 57, RTT: 12300 * time.Microsecond},
+				{Seq: 2, Lost: true},
+				{Seq: 3, Lost: true},
+				{Seq: 5, TTL: 58, RTT: 11 * ms},
+				{Seq: 4, TTL: 57, RTT: 980 * time.Microsecond},
+			},
+		},
+		{
+			name: "bsd",
+			raw: `PING 8.8.8.8 (8.8.8.8): 56 data bytes
+64 bytes from 8.8.8.8: icmp_seq=0 ttl=57 time=1.210 ms
+Request timeout for icmp_seq 1
+64 bytes from 8.8.8.8: icmp_seq=2 ttl=57 time=1.310 ms`,
+			want: []lg.PingReply{
+				{Seq: 0, TTL: 57, RTT: 1210 * time.Microsecond},
+				{Seq: 1, Lost: true},
+				{Seq: 2, TTL: 57, RTT: 1310 * time.Microsecond},
+			},
+		},
+		{
+			name: "lg",
+			raw: `Reply from 8.8.8.8: bytes=32 time=12ms TTL=117
+Request timed out.
+Reply from 8.8.8.8: bytes=32 time<1ms TTL=117
+64 bytes from 8.8.8.8: seq=7 time=2.5 ms`,
+			want: []lg.PingReply{
+				{Seq: 0, TTL: 117, RTT: 12 * ms},
+				{Seq: 1, Lost: true},
+				{Seq: 2, TTL: 117, RTT: ms / 2},
+				{Seq: 7, RTT: 2500 * time.Microsecond},
+			},
+		},
+	}
+	for _, tt := range tests {
+		if got := lg.ParsePingReplies(tt.raw); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %+v but got %+v", tt.name, tt.want, got)
+		}
+	}
+}
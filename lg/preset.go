@@ -0,0 +1,165 @@
+package lg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NodeCoder is implemented by the providers which have the
+// stable location codes of the nodes
+type NodeCoder interface {
+	NodeCode(name string) (string, bool)
+	NodeByCode(code string) (string, bool)
+}
+
+var _ NodeCoder = (*Cogent)(nil)
+
+// Preset represents a saved looking glass query, Node is the location
+// code if the provider has the codes (see NodeCoder) so the preset
+// survives the node renames, otherwise it's the node name. Host is
+// the default host of RunPreset
+type Preset struct {
+	Name      string `json:"name"`
+	Provider  string `json:"provider"`
+	Node      string `json:"node,omitempty"`
+	IPVersion string `json:"ip_version,omitempty"`
+	Command   string `json:"command"`
+	Host      string `json:"host,omitempty"`
+}
+
+// NodeByCode returns the node name of the location code, the
+// ping/trace nodes take priority over the bgp nodes
+func (p *Cogent) NodeByCode(code string) (string, bool) {
+	n := loadedNodes()
+	for _, nodes := range []map[string]string{n.Nodes, n.BGPNodes} {
+		for name, c := range nodes {
+			if strings.EqualFold(c, code) {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// NewPreset returns the preset of the provider current node and ip version
+func NewPreset(name, provider string, p LookingGlass, cmd Command, host string) Preset {
+	_, version := p.Get()
+	node := p.GetNode()
+	if nc, ok := p.(NodeCoder); ok {
+		if code, ok := nc.NodeCode(node); ok {
+			node = code
+		}
+	}
+	return Preset{Name: name, Provider: provider, Node: node, IPVersion: version, Command: cmd.String(), Host: host}
+}
+
+// validate checks the preset values
+func (ps Preset) validate() error {
+	if ps.Name == "" || strings.ContainsAny(ps.Name, " \t") {
+		return fmt.Errorf("preset name %q should be a single word", ps.Name)
+	}
+	if _, err := New(ps.Provider); err != nil {
+		return fmt.Errorf("preset %s: %v", ps.Name, err)
+	}
+	if _, err := ParseCommand(ps.Command); err != nil {
+		return fmt.Errorf("preset %s: %v", ps.Name, err)
+	}
+	switch ps.IPVersion {
+	case "", "ipv4", "ipv6":
+	default:
+		return fmt.Errorf("preset %s: ip_version should be ipv4 or ipv6 but it's %q", ps.Name, ps.IPVersion)
+	}
+	return nil
+}
+
+// Presets returns the saved presets sorted by the name
+func Presets() []Preset {
+	configMu.RLock()
+	presets := append([]Preset(nil), userConfig.Presets...)
+	configMu.RUnlock()
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Name < presets[j].Name })
+	return presets
+}
+
+// SavePreset adds the preset to the config file, the
+// preset w/ the same name is replaced
+func SavePreset(ps Preset) error {
+	if err := ps.validate(); err != nil {
+		return err
+	}
+	configMu.RLock()
+	c := userConfig
+	configMu.RUnlock()
+
+	c.Presets = append([]Preset(nil), c.Presets...)
+	for i := range c.Presets {
+		if c.Presets[i].Name == ps.Name {
+			c.Presets[i] = ps
+			return SaveConfig(c)
+		}
+	}
+	c.Presets = append(c.Presets, ps)
+	return SaveConfig(c)
+}
+
+// RunPreset runs the saved preset through a new looking glass of its
+// provider, the host replaces the preset host if it's not empty
+func RunPreset(ctx context.Context, name, host string) (Result, error) {
+	for _, ps := range Presets() {
+		if ps.Name != name {
+			continue
+		}
+		p, err := New(ps.Provider)
+		if err != nil {
+			return Result{}, err
+		}
+		return ps.Run(ctx, p, host)
+	}
+	return Result{}, fmt.Errorf("preset %s not found", name)
+}
+
+// Run runs the preset through the looking glass, the node is
+// looked up by its location code if the provider has the codes
+func (ps Preset) Run(ctx context.Context, p LookingGlass, host string) (Result, error) {
+	cmd, err := ParseCommand(ps.Command)
+	if err != nil {
+		return Result{}, err
+	}
+	if host == "" {
+		host = ps.Host
+	}
+	if host == "" {
+		return Result{}, errors.New("the preset has no host, it should be given")
+	}
+	if ps.Node != "" {
+		node := ps.Node
+		if nc, ok := p.(NodeCoder); ok {
+			p.GetNodes()
+			if name, ok := nc.NodeByCode(node); ok {
+				node = name
+			}
+		}
+		if !p.ChangeNode(node) {
+			return Result{}, fmt.Errorf("preset %s: the node %s isn't available", ps.Name, ps.Node)
+		}
+	}
+	return runContext(ctx, p, cmd, host, ps.IPVersion)
+}
+
+// String returns the preset summary
+func (ps Preset) String() string {
+	s := fmt.Sprintf("%s: %s %s", ps.Name, ps.Provider, ps.Command)
+	if ps.Node != "" {
+		s += " @ " + ps.Node
+	}
+	if ps.IPVersion != "" {
+		s += " " + ps.IPVersion
+	}
+	if ps.Host != "" {
+		s += " " + ps.Host
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+package lg_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestPreset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	lg.ConfigFile = filepath.Join(dir, "mylg", "lg.json")
+	defer lg.SaveConfig(lg.Config{})
+
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+	if !c.ChangeNode("US - Atlanta") {
+		t.Fatal("ChangeNode failed")
+	}
+	c.IPv = "ipv6"
+
+	ps := lg.NewPreset("atl", "cogent", c, lg.CmdPing, "example.com")
+	want := lg.Preset{Name: "atl", Provider: "cogent", Node: "atla", IPVersion: "ipv6", Command: "ping", Host: "example.com"}
+	if ps != want {
+		t.Errorf("expected %+v but got %+v", want, ps)
+	}
+	if err := lg.SavePreset(ps); err != nil {
+		t.Fatal(err)
+	}
+	ps.Host = "example.org"
+	if err := lg.SavePreset(ps); err != nil {
+		t.Fatal(err)
+	}
+	if err := lg.SavePreset(lg.Preset{Name: "bad", Provider: "cogent", Command: "mtr"}); err == nil {
+		t.Error("expected the invalid command error")
+	}
+	if _, err := lg.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if presets := lg.Presets(); len(presets) != 1 || presets[0] != ps {
+		t.Error("expected the replaced preset but got", presets)
+	}
+
+	var loc, cmd string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loc, cmd = r.FormValue("LOC"), r.FormValue("CMD")
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+
+	q := &lg.Cogent{BaseURL: ts.URL, CacheFile: c.CacheFile}
+	r, err := ps.Run(context.Background(), q, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Node != "US - Atlanta" || q.Host != "example.org" || q.IPv != "ipv6" {
+		t.Errorf("unexpected preset query %s %s %s", q.Node, q.Host, q.IPv)
+	}
+	if loc != "atla" || cmd != "P6" || r.Stats == nil {
+		t.Errorf("unexpected request %s %s %+v", loc, cmd, r)
+	}
+
+	if _, err := lg.RunPreset(context.Background(), "missing", ""); err == nil {
+		t.Error("expected the missing preset error")
+	}
+	ps.Node = "gone"
+	if _, err := ps.Run(context.Background(), q, ""); err == nil {
+		t.Error("expected the unavailable node error")
+	}
+}
@@ -0,0 +1,70 @@
+package lg
+
+import (
+	"context"
+	"fmt"
+)
+
+// FetchStage represents a coarse step of the nodes fetch
+type FetchStage int
+
+// the nodes fetch stages
+const (
+	StageConnecting FetchStage = iota
+	StageDownloading
+	StageParsing
+	StageDone
+)
+
+// String returns the stage name
+func (s FetchStage) String() string {
+	switch s {
+	case StageConnecting:
+		return "connecting"
+	case StageDownloading:
+		return "downloading"
+	case StageParsing:
+		return "parsing"
+	case StageDone:
+		return "done"
+	}
+	return fmt.Sprintf("FetchStage(%d)", int(s))
+}
+
+// ProgressEvent represents the nodes fetch progress
+type ProgressEvent struct {
+	Provider string
+	Stage    FetchStage
+}
+
+// String returns the status line of the event
+func (e ProgressEvent) String() string {
+	return fmt.Sprintf("%s nodes: %s", e.Provider, e.Stage)
+}
+
+// FetchResult represents the nodes fetch result
+type FetchResult struct {
+	Nodes    map[string]string
+	BGPNodes map[string]string
+	Err      error
+}
+
+// FetchNodesProgress is like FetchNodesContext but the fetch runs in the
+// background and the progress is reported, the both channels are closed
+// once it's done. the events are buffered so the progress can be ignored
+func (p *Cogent) FetchNodesProgress(ctx context.Context) (<-chan ProgressEvent, <-chan FetchResult) {
+	var (
+		events = make(chan ProgressEvent, int(StageDone)+1)
+		result = make(chan FetchResult, 1)
+	)
+	go func() {
+		defer close(result)
+		defer close(events)
+		c, err := p.fetchNodes(ctx, func(s FetchStage) {
+			events <- ProgressEvent{Provider: "cogent", Stage: s}
+		})
+		events <- ProgressEvent{Provider: "cogent", Stage: StageDone}
+		result <- FetchResult{Nodes: c.Nodes, BGPNodes: c.BGPNodes, Err: err}
+	}()
+	return events, result
+}
@@ -0,0 +1,58 @@
+package lg
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// proxyClients keeps the proxy clients, so the
+// connections are reused across the requests
+var proxyClients sync.Map
+
+type proxyKey struct {
+	client *http.Client
+	proxy  string
+}
+
+// ParseProxy validates the proxy url, the supported
+// schemes are http, https, socks5 and socks5h
+func ParseProxy(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %v", proxy, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy %q: the scheme should be http, https or socks5", proxy)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q: the host is missing", proxy)
+	}
+	return u, nil
+}
+
+// proxyClient returns a copy of the client w/ its transport
+// routed through the proxy
+func proxyClient(c *http.Client, proxy string) (*http.Client, error) {
+	key := proxyKey{c, proxy}
+	if pc, ok := proxyClients.Load(key); ok {
+		return pc.(*http.Client), nil
+	}
+	u, err := ParseProxy(proxy)
+	if err != nil {
+		return nil, err
+	}
+	t, err := deriveTransport(c.Transport, func(t *http.Transport) {
+		t.Proxy = http.ProxyURL(u)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: %v", proxy, err)
+	}
+	pc := *c
+	pc.Transport = t
+	v, _ := proxyClients.LoadOrStore(key, &pc)
+	return v.(*http.Client), nil
+}
@@ -0,0 +1,154 @@
+package lg
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PTRResolver resolves an IP address to its PTR name
+type PTRResolver interface {
+	LookupPTR(ip string) (string, error)
+}
+
+// DNSResolver resolves the PTR names through the DNS, the results
+// (also the failures) are cached and it's safe for concurrent use
+type DNSResolver struct {
+	// Resolver is used for the PTR lookups, net.DefaultResolver
+	// is used if it's nil
+	Resolver *net.Resolver
+	// Timeout is the per lookup timeout (default 1s)
+	Timeout time.Duration
+	// Private enables the lookups of the private and
+	// the documentation addresses
+	Private bool
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+// ptrResolver annotates the trace hops w/ the PTR names if it's set
+var ptrResolver PTRResolver
+
+// SetPTRResolver enables the reverse DNS of the trace hops,
+// it's disabled if the resolver is nil
+func SetPTRResolver(r PTRResolver) {
+	ptrResolver = r
+}
+
+// NewDNSResolver returns a PTR resolver w/ the system resolver
+func NewDNSResolver() *DNSResolver {
+	return &DNSResolver{cache: make(map[string]string)}
+}
+
+// LookupPTR returns the PTR name w/o the trailing dot, the private
+// addresses return empty name w/o any lookup unless Private is set
+func (r *DNSResolver) LookupPTR(ip string) (string, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return "", fmt.Errorf("invalid ip address: %s", ip)
+	}
+	if !r.Private && IsBogon(addr) {
+		return "", nil
+	}
+	r.mu.Lock()
+	if r.cache == nil {
+		r.cache = make(map[string]string)
+	}
+	name, ok := r.cache[ip]
+	r.mu.Unlock()
+	if ok {
+		return name, nil
+	}
+
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = time.Second
+	}
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	names, err := resolver.LookupAddr(ctx, ip)
+	if err == nil && len(names) > 0 {
+		name = strings.TrimSuffix(names[0], ".")
+	}
+	r.mu.Lock()
+	r.cache[ip] = name
+	r.mu.Unlock()
+	return name, err
+}
+
+// resolveHop sets the hop PTR name if the reverse DNS is enabled
+func resolveHop(hop *TraceHop) {
+	r := ptrResolver
+	if r == nil || hop.IP == "" {
+		return
+	}
+	name, err := r.LookupPTR(hop.IP)
+	if err != nil {
+		getLogger().Debug("ptr: lookup %s failed: %v", hop.IP, err)
+		return
+	}
+	hop.PTR = name
+}
+
+// annotatePTR rewrites the bare hop IP address as host (ip) if the
+// reverse DNS is enabled, the line is kept if the lookup fails
+func annotatePTR(l string) string {
+	r := ptrResolver
+	if r == nil {
+		return l
+	}
+	m := traceHopRe.FindStringSubmatchIndex(l)
+	if m == nil {
+		return l
+	}
+	rest := l[m[4]:]
+	if traceHostRe.MatchString(rest) {
+		return l
+	}
+	ip := traceIPRe.FindStringSubmatchIndex(rest)
+	if ip == nil {
+		return l
+	}
+	addr := rest[ip[2]:ip[3]]
+	name, err := r.LookupPTR(addr)
+	if err != nil || name == "" {
+		return l
+	}
+	return l[:m[4]] + rest[:ip[2]] + fmt.Sprintf("%s (%s)", name, addr) + rest[ip[3]:]
+}
+
+// ResolveTrace rewrites the bare hop IP addresses of the trace lines
+// as host (ip) if the reverse DNS is enabled, the lookups are
+// concurrent and the lines are sent in the traceroute order
+func ResolveTrace(lines chan string) chan string {
+	if ptrResolver == nil {
+		return lines
+	}
+	c := make(chan string)
+	pending := make(chan chan string, 32)
+	go func() {
+		for l := range lines {
+			r := make(chan string, 1)
+			pending <- r
+			go func(l string) {
+				r <- annotatePTR(l)
+			}(l)
+		}
+		close(pending)
+	}()
+	go func() {
+		for r := range pending {
+			c <- <-r
+		}
+		close(c)
+	}()
+	return c
+}
@@ -0,0 +1,69 @@
+package lg_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+// fakePTR resolves the IPs from the map
+type fakePTR map[string]string
+
+func (f fakePTR) LookupPTR(ip string) (string, error) {
+	return f[ip], nil
+}
+
+func TestTracePTR(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, cogentTraceHopsFixture)
+	}))
+	defer ts.Close()
+
+	lg.SetPTRResolver(fakePTR{"154.54.44.85": "be2931.example.net", "72.14.236.69": "google.example.net"})
+	defer lg.SetPTRResolver(nil)
+
+	c := &lg.Cogent{BaseURL: ts.URL}
+	c.Set("8.8.8.8", "ipv4")
+	hops, err := c.TraceStructured()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for hop := range hops {
+		got = append(got, fmt.Sprintf("%d:%s", hop.Index, hop.PTR))
+	}
+	if want := "1:be2931.example.net 2: 3:google.example.net"; strings.Join(got, " ") != want {
+		t.Errorf("expected %q but got %q", want, got)
+	}
+
+	lines, err := c.Trace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text []string
+	for l := range lg.ResolveTrace(lines) {
+		text = append(text, l)
+	}
+	if len(text) != 4 || !strings.HasPrefix(text[3], " 3  google.example.net (72.14.236.69)  1.5 ms") {
+		t.Errorf("unexpected trace lines %q", text)
+	}
+	if !strings.Contains(text[1], "be2931.ccr21.lax01.atlas.cogentco.com (154.54.44.85)") {
+		t.Error("expected the hop w/ host kept but got", text[1])
+	}
+}
+
+func TestDNSResolverPrivate(t *testing.T) {
+	r := lg.NewDNSResolver()
+	for _, ip := range []string{"10.1.1.1", "172.16.0.1", "192.0.2.1", "2001:db8::1"} {
+		if name, err := r.LookupPTR(ip); name != "" || err != nil {
+			t.Errorf("expected no lookup for %s but got %q %v", ip, name, err)
+		}
+	}
+	if _, err := r.LookupPTR("x"); err == nil {
+		t.Error("expected error for invalid ip address")
+	}
+}
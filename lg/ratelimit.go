@@ -0,0 +1,131 @@
+package lg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned once the looking glass asks to
+// slow down, the RateLimitError has the suggested retry delay
+var ErrRateLimited = errors.New("looking glass rate limit exceeded")
+
+// RateLimitError represents a rate limited request
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+// defaultRetryAfter is suggested if the looking glass doesn't say
+const defaultRetryAfter = time.Minute
+
+var (
+	rateLimitedRe = regexp.MustCompile(`(?i)(too many (requests|queries)|slow down|rate limit|exceeded the (maximum|allowed) number of)`)
+	retryAfterRe  = regexp.MustCompile(`(?i)(\d+)\s*(second|sec|minute|min)`)
+)
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s, please retry after %s", ErrRateLimited, e.RetryAfter)
+}
+
+// Unwrap makes errors.Is(err, ErrRateLimited) true
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// rateLimitStatus returns a RateLimitError if the status is 429,
+// the Retry-After header is suggested if it's in seconds
+func rateLimitStatus(resp *http.Response) error {
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+	d := defaultRetryAfter
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		d = time.Duration(s) * time.Second
+	}
+	return &RateLimitError{RetryAfter: d}
+}
+
+// rateLimitedPage returns a RateLimitError if the page asks to slow
+// down, the wait time of the page (e.g. 30 seconds) is suggested
+func rateLimitedPage(body string) error {
+	if !rateLimitedRe.MatchString(body) {
+		return nil
+	}
+	d := defaultRetryAfter
+	if m := retryAfterRe.FindStringSubmatch(body); len(m) == 3 {
+		n, _ := strconv.Atoi(m[1])
+		if n > 0 {
+			d = time.Duration(n) * time.Second
+			if m[2][0] == 'm' || m[2][0] == 'M' {
+				d = time.Duration(n) * time.Minute
+			}
+		}
+	}
+	return &RateLimitError{RetryAfter: d}
+}
+
+// rateLimiter is a token bucket w/ one token capacity,
+// the requests wait for the next token
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// cogentLimiter is shared by all of the Cogent instances
+var cogentLimiter = newRateLimiter(1)
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	l := &rateLimiter{}
+	l.setRate(perSecond)
+	return l
+}
+
+// SetRateLimit changes the max Cogent requests per second (default 1),
+// zero or negative value disables the rate limit
+func SetRateLimit(perSecond float64) {
+	cogentLimiter.setRate(perSecond)
+}
+
+func (l *rateLimiter) setRate(perSecond float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.interval = 0
+	if perSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / perSecond)
+	}
+}
+
+// wait blocks until a token is available or ctx is done
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	if l.interval == 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	t := l.next
+	if t.Before(now) {
+		t = now
+	}
+	l.next = t.Add(l.interval)
+	l.mu.Unlock()
+
+	d := t.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,41 @@
+package lg_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestMain(m *testing.M) {
+	// the fake servers don't need the rate limit
+	lg.SetRateLimit(0)
+	os.Exit(m.Run())
+}
+
+func TestSetRateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer ts.Close()
+
+	lg.SetRateLimit(20)
+	defer lg.SetRateLimit(0)
+
+	// the instances share the limiter
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		c := &lg.Cogent{BaseURL: ts.URL}
+		c.Set("8.8.8.8", "ipv4")
+		if _, err := c.Ping(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Error("expected 4 requests at 20/s to take at least 150ms but took", d)
+	}
+}
@@ -0,0 +1,61 @@
+package lg
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// RawRecorder is implemented by the providers which can keep
+// the last raw response, it's useful once the parsing fails
+type RawRecorder interface {
+	LastRaw() string
+}
+
+// maxRawSize caps the kept raw response
+const maxRawSize = 1 << 20
+
+// rawBuffer keeps up to maxRawSize bytes of a response
+type rawBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write keeps the data up to maxRawSize, the
+// rest is dropped w/o error
+func (b *rawBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if n := maxRawSize - b.buf.Len(); n < len(p) {
+		b.buf.Write(p[:n])
+	} else {
+		b.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+func (b *rawBuffer) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
+func (b *rawBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// newRawWriter returns the writer which the next response is kept by,
+// the buffer is reset and it's ioutil.Discard if keep isn't set
+func newRawWriter(raw **rawBuffer, keep bool) io.Writer {
+	if !keep {
+		return ioutil.Discard
+	}
+	if *raw == nil {
+		*raw = &rawBuffer{}
+	}
+	(*raw).reset()
+	return *raw
+}
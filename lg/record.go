@@ -0,0 +1,197 @@
+package lg
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// the environment variables which turn on the record or the replay
+// mode, they're set to the recordings directory
+const (
+	RecordEnv = "MYLG_RECORD"
+	ReplayEnv = "MYLG_REPLAY"
+)
+
+// ErrNoRecording is returned in the replay mode if the
+// request wasn't recorded
+var ErrNoRecording = errors.New("no recording")
+
+// Recorder is a http transport which writes the looking glass requests
+// and responses to Dir, or serves them from Dir w/o hitting the network
+// once Replay is set
+type Recorder struct {
+	Dir    string
+	Replay bool
+	// Transport makes the requests in the record mode,
+	// http.DefaultTransport is used if it's nil
+	Transport http.RoundTripper
+}
+
+// recording represents a request/response pair on disk, Body is the
+// raw response body (base64 in json) so the encoded bodies e.g. gzip
+// are replayed as they're received
+type recording struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Request string      `json:"request,omitempty"`
+	Status  int         `json:"status"`
+	Header  http.Header `json:"header"`
+	Body    []byte      `json:"body"`
+}
+
+// recordKeyFields are the form fields of the command, host and node
+// which the recordings are named by (cogent and telia forms)
+var recordKeyFields = [][]string{{"CMD", "query"}, {"DST", "addr"}, {"LOC", "router"}}
+
+var recordNameRe = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// RecordClient returns a copy of the client w/ its transport
+// wrapped by a recorder of the dir
+func RecordClient(c *http.Client, dir string, replay bool) *http.Client {
+	rc := *c
+	rc.Transport = &Recorder{Dir: dir, Replay: replay, Transport: c.Transport}
+	return &rc
+}
+
+// RecordFromEnv wraps the package http client by a recorder if
+// MYLG_RECORD or MYLG_REPLAY is set, the replay has priority
+func RecordFromEnv() error {
+	dir, replay := os.Getenv(RecordEnv), false
+	if d := os.Getenv(ReplayEnv); d != "" {
+		dir, replay = d, true
+	}
+	if dir == "" {
+		return nil
+	}
+	if replay {
+		if _, err := os.Stat(dir); err != nil {
+			return fmt.Errorf("replay: %v", err)
+		}
+	}
+	SetHTTPClient(RecordClient(httpClient, dir, replay))
+	return nil
+}
+
+// RoundTrip records the request/response pair or replays it
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+	file := filepath.Join(r.Dir, recordName(req, body))
+	if r.Replay {
+		return r.replay(req, file)
+	}
+
+	t := r.Transport
+	if t == nil {
+		t = http.DefaultTransport
+	}
+	resp, err := t.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+	rec := recording{
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Request: string(body),
+		Status:  resp.StatusCode,
+		Header:  resp.Header,
+		Body:    b,
+	}
+	if err := writeRecording(file, rec); err != nil {
+		getLogger().Warn("record: %s", err)
+	}
+	return resp, nil
+}
+
+// replay returns the recorded response of the request
+func (r *Recorder) replay(req *http.Request, file string) (*http.Response, error) {
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s %s (%s)", ErrNoRecording, req.Method, req.URL, filepath.Base(file))
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rec recording
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, fmt.Errorf("replay %s: %v", file, err)
+	}
+	getLogger().Debug("replay: %s %s from %s", req.Method, req.URL, file)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(rec.Body)),
+		ContentLength: int64(len(rec.Body)),
+		Request:       req,
+	}, nil
+}
+
+// text returns the recorded body, it's decoded by its Content-Encoding
+func (rec recording) text() (string, error) {
+	resp := &http.Response{Header: rec.Header.Clone(), Body: ioutil.NopCloser(bytes.NewReader(rec.Body))}
+	decodeBody(resp)
+	b, err := ioutil.ReadAll(resp.Body)
+	return string(b), err
+}
+
+func writeRecording(file string, rec recording) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, b, 0600)
+}
+
+// recordName returns the recording file name of the request, it's named
+// by the looking glass host, command, target host and node, e.g.
+// lg.cogentco.com_P4_8.8.8.8_US_LAX-1f2e3d4c.json, the hash of the
+// whole request keeps the different options apart
+func recordName(req *http.Request, body []byte) string {
+	parts := []string{req.URL.Hostname()}
+	form, _ := url.ParseQuery(string(body))
+	for _, fields := range recordKeyFields {
+		for _, f := range fields {
+			if v := form.Get(f); v != "" {
+				parts = append(parts, v)
+				break
+			}
+		}
+	}
+	if len(parts) == 1 {
+		parts = append(parts, req.Method, req.URL.Path)
+	}
+	name := strings.Trim(recordNameRe.ReplaceAllString(strings.Join(parts, "_"), "_"), "_")
+	h := sha1.Sum([]byte(req.Method + " " + req.URL.String() + "\n" + string(body)))
+	return fmt.Sprintf("%s-%x.json", name, h[:4])
+}
@@ -0,0 +1,116 @@
+package lg_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestCogentRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+
+	c := &lg.Cogent{BaseURL: ts.URL, Client: lg.RecordClient(ts.Client(), dir, false)}
+	c.Set("8.8.8.8", "ipv4")
+	if _, err := c.Ping(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	ts.Close()
+	if files, _ := filepath.Glob(filepath.Join(dir, "*_P4_8.8.8.8*.json")); len(files) != 1 {
+		t.Errorf("expected a recording per command, host and node but got %q", files)
+	}
+
+	// the server is closed, the response should be replayed
+	c = &lg.Cogent{BaseURL: ts.URL, Client: lg.RecordClient(http.DefaultClient, dir, true), DisableRetry: true}
+	c.Set("8.8.8.8", "ipv4")
+	r, err := c.Ping()
+	if err != nil {
+		t.Fatal("unexpected replay error:", err)
+	}
+	if r != cogentPingOutput {
+		t.Error("expected the recorded ping output but got", r)
+	}
+
+	c.Set("8.8.4.4", "ipv4")
+	if _, err := c.Ping(); !errors.Is(err, lg.ErrNoRecording) {
+		t.Error("expected no recording error but got", err)
+	}
+}
+
+func TestCogentRecordReplayGzip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		fmt.Fprint(zw, cogentPingFixture)
+		zw.Close()
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(buf.Bytes())
+	}))
+
+	c := &lg.Cogent{BaseURL: ts.URL, Client: lg.RecordClient(ts.Client(), dir, false)}
+	c.Set("8.8.8.8", "ipv4")
+	if _, err := c.Ping(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	ts.Close()
+
+	c = &lg.Cogent{BaseURL: ts.URL, Client: lg.RecordClient(http.DefaultClient, dir, true), DisableRetry: true}
+	c.Set("8.8.8.8", "ipv4")
+	r, err := c.Ping()
+	if err != nil {
+		t.Fatal("unexpected replay error:", err)
+	}
+	if r != cogentPingOutput {
+		t.Error("expected the recorded gzip ping output but got", r)
+	}
+}
+
+func TestCogentRecordProxy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		fmt.Fprint(w, cogentPingFixture)
+	}))
+	defer proxy.Close()
+
+	c := &lg.Cogent{
+		BaseURL:  "http://lg.example.com/lookingglass.php",
+		ProxyURL: proxy.URL,
+		Client:   lg.RecordClient(&http.Client{Transport: &http.Transport{}}, dir, false),
+	}
+	c.Set("8.8.8.8", "ipv4")
+	if _, err := c.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if proxied != "http://lg.example.com/lookingglass.php" {
+		t.Error("expected the request through the proxy but got", proxied)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(files) != 1 {
+		t.Error("expected a recording but got", files)
+	}
+}
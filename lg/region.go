@@ -0,0 +1,63 @@
+package lg
+
+import (
+	"sort"
+	"strings"
+)
+
+// NodeGrouper is implemented by the providers which
+// can group the nodes by region
+type NodeGrouper interface {
+	GetNodesByRegion() map[string][]string
+}
+
+// OtherRegion holds the nodes w/o a known region prefix
+const OtherRegion = "Other"
+
+// regions maps the node name prefixes (country codes) to the regions
+var regions = map[string]string{
+	"US": "US",
+	"CA": "North America", "MX": "North America",
+	"AR": "South America", "BR": "South America", "CL": "South America",
+	"CO": "South America", "PE": "South America",
+	"AT": "Europe", "BE": "Europe", "BG": "Europe", "CH": "Europe",
+	"CZ": "Europe", "DE": "Europe", "DK": "Europe", "EE": "Europe",
+	"ES": "Europe", "FI": "Europe", "FR": "Europe", "GR": "Europe",
+	"HR": "Europe", "HU": "Europe", "IE": "Europe", "IT": "Europe",
+	"LT": "Europe", "LU": "Europe", "LV": "Europe", "NL": "Europe",
+	"NO": "Europe", "PL": "Europe", "PT": "Europe", "RO": "Europe",
+	"RS": "Europe", "SE": "Europe", "SI": "Europe", "SK": "Europe",
+	"UA": "Europe", "UK": "Europe", "GB": "Europe",
+	"CN": "Asia", "HK": "Asia", "ID": "Asia", "IN": "Asia",
+	"JP": "Asia", "KR": "Asia", "MY": "Asia", "PH": "Asia",
+	"SG": "Asia", "TH": "Asia", "TW": "Asia", "VN": "Asia",
+	"AE": "Middle East", "IL": "Middle East", "QA": "Middle East",
+	"SA": "Middle East", "TR": "Middle East",
+	"AU": "Oceania", "NZ": "Oceania",
+	"EG": "Africa", "KE": "Africa", "NG": "Africa", "ZA": "Africa",
+}
+
+// nodeRegion returns the region of the node name, e.g. Asia for JP - Tokyo
+func nodeRegion(name string) string {
+	i := strings.Index(name, " - ")
+	if i < 0 {
+		return OtherRegion
+	}
+	if r, ok := regions[strings.ToUpper(strings.TrimSpace(name[:i]))]; ok {
+		return r
+	}
+	return OtherRegion
+}
+
+// groupByRegion returns the sorted node names of each region
+func groupByRegion(names []string) map[string][]string {
+	groups := make(map[string][]string)
+	for _, name := range names {
+		r := nodeRegion(name)
+		groups[r] = append(groups[r], name)
+	}
+	for _, g := range groups {
+		sort.Strings(g)
+	}
+	return groups
+}
@@ -0,0 +1,49 @@
+package lg
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]func() LookingGlass{}
+)
+
+// Register makes a looking glass provider available by name,
+// the providers register themselves in their init function
+func Register(name string, factory func() LookingGlass) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if factory == nil {
+		panic("lg: Register factory is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("lg: Register called twice for provider " + name)
+	}
+	registry[name] = factory
+}
+
+// Providers returns the sorted registered provider names
+func Providers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns a new looking glass of the provider
+func New(name string) (LookingGlass, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown looking glass provider %q", name)
+	}
+	return factory(), nil
+}
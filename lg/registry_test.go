@@ -0,0 +1,28 @@
+package lg_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestRegistry(t *testing.T) {
+	want := []string{"cogent", "kpn", "level3", "ntt", "telia"}
+	if got := lg.Providers(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q providers but got %q", want, got)
+	}
+	p, err := lg.New("cogent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(*lg.Cogent); !ok {
+		t.Errorf("expected *lg.Cogent but got %T", p)
+	}
+	if a, _ := lg.New("cogent"); a == p {
+		t.Error("expected a new instance per New")
+	}
+	if _, err := lg.New("unknown"); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
@@ -0,0 +1,29 @@
+package lg
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// defaultRetries is the max retries on the transient failures
+const defaultRetries = 3
+
+// retryBackoff is the first retry delay, it doubles on each retry
+var retryBackoff = 100 * time.Millisecond
+
+// retryable returns true if the request failed because of
+// a network error or a server error (5xx)
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, errRedirect)
+	}
+	return resp.StatusCode >= 500
+}
+
+// backoff returns the exponential delay w/ up to 20% jitter
+func backoff(attempt int) time.Duration {
+	d := retryBackoff << uint(attempt)
+	return d + time.Duration(rand.Int63n(int64(d)/5+1))
+}
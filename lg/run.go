@@ -0,0 +1,178 @@
+package lg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Command represents a looking glass command
+type Command int
+
+// the looking glass commands
+const (
+	CmdPing Command = iota
+	CmdTrace
+	CmdBGP
+)
+
+// Result represents a command result, Text (and Stats if it could be
+// parsed) is set for ping and Lines streams the trace and bgp output.
+// Err is the ping parse error if Stats is nil or the command error of
+// CompareProviders
+type Result struct {
+	Command Command
+	Text    string
+	Stats   *PingStats
+	Lines   chan string
+	Err     error
+
+	// Cancel tears down the stream, Lines is closed once it's
+	// canceled. the callers must call it when they stop reading
+	// e.g. on interrupt, it can be called more than once
+	Cancel func()
+}
+
+// ContextStreamer is implemented by the looking glasses which bind
+// the trace and bgp streams (and their http requests) to a context
+type ContextStreamer interface {
+	TraceContext(ctx context.Context) (chan string, error)
+	BGPContext(ctx context.Context) chan string
+}
+
+// String returns the command name
+func (c Command) String() string {
+	switch c {
+	case CmdPing:
+		return "ping"
+	case CmdTrace:
+		return "trace"
+	case CmdBGP:
+		return "bgp"
+	}
+	return fmt.Sprintf("Command(%d)", int(c))
+}
+
+// ParseCommand returns the command of ping, trace or bgp
+func ParseCommand(s string) (Command, error) {
+	for _, c := range []Command{CmdPing, CmdTrace, CmdBGP} {
+		if strings.EqualFold(s, c.String()) {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnsupportedCommand, s)
+}
+
+// CheckCommand returns ErrUnsupportedCommand w/ the supported
+// commands if the provider doesn't offer the command
+func CheckCommand(p LookingGlass, cmd Command) error {
+	var names []string
+	for _, c := range p.SupportedCommands() {
+		if c == cmd {
+			return nil
+		}
+		names = append(names, c.String())
+	}
+	return fmt.Errorf("%w: %s, the supported commands are %s", ErrUnsupportedCommand, cmd, strings.Join(names, ", "))
+}
+
+// Run sets the host and runs the command through any of the providers,
+// so the commands are dispatched and validated the same way
+func Run(p LookingGlass, cmd Command, host string) (Result, error) {
+	return RunContext(context.Background(), p, cmd, host)
+}
+
+// RunContext is like Run but the stream is bound to ctx, see Result.Cancel.
+// the stream of a provider w/o ContextStreamer is drained once it's canceled
+func RunContext(ctx context.Context, p LookingGlass, cmd Command, host string) (Result, error) {
+	return runContext(ctx, p, cmd, host, "")
+}
+
+// runContext is like RunContext w/ the ip version, the configured
+// one is used if it's empty
+func runContext(ctx context.Context, p LookingGlass, cmd Command, host, version string) (Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	r := Result{Command: cmd, Cancel: cancel}
+	if err := CheckCommand(p, cmd); err != nil {
+		cancel()
+		return r, err
+	}
+	if host == "" {
+		cancel()
+		return r, errors.New("invalid host: empty host")
+	}
+	p.Set(host, version)
+
+	cs, ok := p.(ContextStreamer)
+	switch cmd {
+	case CmdPing:
+		defer cancel()
+		out, err := p.Ping()
+		if err != nil {
+			return r, err
+		}
+		r.Text = out
+		if s, err := ParsePing(out); err == nil {
+			r.Stats = &s
+		} else {
+			r.Err = err
+		}
+	case CmdTrace:
+		var (
+			lines chan string
+			err   error
+		)
+		if ok {
+			lines, err = cs.TraceContext(ctx)
+		} else {
+			lines, err = p.Trace()
+			lines = cancelable(ctx, lines)
+		}
+		if err != nil {
+			cancel()
+			return r, err
+		}
+		r.Lines = lines
+	case CmdBGP:
+		if ok {
+			r.Lines = cs.BGPContext(ctx)
+		} else {
+			r.Lines = cancelable(ctx, p.BGP())
+		}
+	default:
+		cancel()
+		return r, fmt.Errorf("%w: %s", ErrUnsupportedCommand, cmd)
+	}
+	return r, nil
+}
+
+// cancelable relays the lines until ctx is canceled, the rest of
+// the lines are drained so the producer goroutine isn't leaked
+func cancelable(ctx context.Context, lines chan string) chan string {
+	if lines == nil {
+		return nil
+	}
+	c := make(chan string)
+	go func() {
+		defer close(c)
+		for {
+			select {
+			case l, ok := <-lines:
+				if !ok {
+					return
+				}
+				select {
+				case c <- l:
+					continue
+				case <-ctx.Done():
+				}
+			case <-ctx.Done():
+			}
+			for range lines {
+			}
+			return
+		}
+	}()
+	return c
+}
@@ -0,0 +1,206 @@
+package lg_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+// runLG is a looking glass which echoes the host
+type runLG struct {
+	host string
+	cmds []lg.Command
+}
+
+func (r *runLG) Set(host, version string)    { r.host = host }
+func (r *runLG) Get() (string, string)       { return r.host, "" }
+func (r *runLG) GetDefaultNode() string      { return "paris" }
+func (r *runLG) GetNodes() []string          { return []string{"paris"} }
+func (r *runLG) GetNode() string             { return "paris" }
+func (r *runLG) ChangeNode(node string) bool { return node == "paris" }
+func (r *runLG) Ping() (string, error)       { return cogentPingOutput, nil }
+func (r *runLG) Trace() (chan string, error) { return runLines("trace " + r.host), nil }
+func (r *runLG) BGP() chan string            { return runLines("bgp " + r.host) }
+
+func (r *runLG) SupportedCommands() []lg.Command {
+	if r.cmds != nil {
+		return r.cmds
+	}
+	return []lg.Command{lg.CmdPing, lg.CmdTrace, lg.CmdBGP}
+}
+
+func runLines(l ...string) chan string {
+	c := make(chan string, len(l))
+	for _, s := range l {
+		c <- s
+	}
+	close(c)
+	return c
+}
+
+func TestRun(t *testing.T) {
+	p := &runLG{}
+	r, err := lg.Run(p, lg.CmdPing, "8.8.8.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Text != cogentPingOutput || r.Stats == nil || r.Stats.PacketsReceived != 1 {
+		t.Errorf("unexpected ping result %+v", r)
+	}
+	for _, cmd := range []lg.Command{lg.CmdTrace, lg.CmdBGP} {
+		r, err := lg.Run(p, cmd, "8.8.8.8")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for l := range r.Lines {
+			got = append(got, l)
+		}
+		if want := []string{cmd.String() + " 8.8.8.8"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %q but got %q", want, got)
+		}
+	}
+	if _, err := lg.Run(p, lg.CmdPing, ""); err == nil {
+		t.Error("expected error for empty host")
+	}
+	if _, err := lg.Run(p, lg.Command(9), "8.8.8.8"); !errors.Is(err, lg.ErrUnsupportedCommand) {
+		t.Errorf("expected unsupported command error but got %v", err)
+	}
+	p.cmds = []lg.Command{lg.CmdPing, lg.CmdTrace}
+	_, err = lg.Run(p, lg.CmdBGP, "8.8.8.8")
+	if !errors.Is(err, lg.ErrUnsupportedCommand) || !strings.Contains(err.Error(), "supported commands are ping, trace") {
+		t.Errorf("expected unsupported bgp error but got %v", err)
+	}
+}
+
+// rawLG is a looking glass w/ the unparsable ping output
+type rawLG struct {
+	runLG
+}
+
+func (r *rawLG) Ping() (string, error) { return "no replies", nil }
+
+func TestRunPingParseError(t *testing.T) {
+	r, err := lg.Run(&rawLG{}, lg.CmdPing, "8.8.8.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Text != "no replies" || r.Stats != nil || r.Err == nil {
+		t.Errorf("expected the raw text w/ the parse error but got %+v", r)
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	if c, err := lg.ParseCommand("Trace"); err != nil || c != lg.CmdTrace {
+		t.Errorf("expected trace but got %v, %v", c, err)
+	}
+	if _, err := lg.ParseCommand("dig"); !errors.Is(err, lg.ErrUnsupportedCommand) {
+		t.Errorf("expected unsupported command error but got %v", err)
+	}
+}
+
+func TestCompareProviders(t *testing.T) {
+	r := lg.CompareProviders(lg.CmdTrace, "8.8.8.8", map[string]lg.LookingGlass{"echo": &runLG{}})
+	if r["echo"].Err != nil || r["echo"].Text != "trace 8.8.8.8" || r["echo"].Lines != nil {
+		t.Errorf("unexpected trace result %+v", r)
+	}
+	r = lg.CompareProviders(lg.CmdTrace, "8.8.8.8", map[string]lg.LookingGlass{"echo": &runLG{cmds: []lg.Command{lg.CmdPing}}})
+	if !errors.Is(r["echo"].Err, lg.ErrUnsupportedCommand) {
+		t.Error("expected unsupported command error but got", r["echo"].Err)
+	}
+
+	c, cleanup := newCogentFixture(t)
+	defer cleanup()
+	if !c.ChangeNode("US - Atlanta") {
+		t.Fatal("ChangeNode failed")
+	}
+	c.Set("2001:db8::1", "ipv6")
+	lg.CompareProviders(lg.CmdPing, "8.8.8.8", map[string]lg.LookingGlass{"cogent": c})
+	if c.Node != "US - Atlanta" {
+		t.Error("expected the restored node but got", c.Node)
+	}
+	if host, version := c.Get(); host != "2001:db8::1" || version != "ipv6" {
+		t.Error("expected the restored host but got", host, version)
+	}
+}
+
+// streamLG is a looking glass w/ the endless trace
+type streamLG struct {
+	runLG
+	done chan struct{}
+}
+
+func (s *streamLG) Trace() (chan string, error) {
+	c := make(chan string)
+	go func() {
+		defer close(s.done)
+		defer close(c)
+		for i := 0; i < 100; i++ {
+			c <- "hop"
+		}
+	}()
+	return c, nil
+}
+
+func TestRunContextCancel(t *testing.T) {
+	p := &streamLG{done: make(chan struct{})}
+	r, err := lg.RunContext(context.Background(), p, lg.CmdTrace, "8.8.8.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-r.Lines
+	r.Cancel()
+	r.Cancel()
+	for range r.Lines {
+	}
+	select {
+	case <-p.done:
+	case <-time.After(time.Second):
+		t.Error("expected the producer to be drained after cancel")
+	}
+}
+
+func TestReadTargets(t *testing.T) {
+	in := "# hosts\n8.8.8.8\n\n  example.com  # dns\n#1.1.1.1\n2001:db8::1\n"
+	targets, err := lg.ReadTargets(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"8.8.8.8", "example.com", "2001:db8::1"}; !reflect.DeepEqual(targets, want) {
+		t.Errorf("expected %q but got %q", want, targets)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	var (
+		s   lg.BatchSummary
+		got = map[string]string{}
+	)
+	for r := range lg.Batch(context.Background(), &runLG{}, lg.CmdTrace, []string{"8.8.8.8", "1.1.1.1"}, 2) {
+		s.Add(r)
+		got[r.Target] = r.Text
+	}
+	if want := map[string]string{"8.8.8.8": "trace 8.8.8.8", "1.1.1.1": "trace 1.1.1.1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q but got %q", want, got)
+	}
+	if s.Succeeded != 2 || s.Failed != 0 {
+		t.Errorf("unexpected summary %+v", s)
+	}
+
+	s = lg.BatchSummary{}
+	p := &runLG{cmds: []lg.Command{lg.CmdPing}}
+	for r := range lg.Batch(context.Background(), p, lg.CmdBGP, []string{"8.8.8.8"}, 0) {
+		s.Add(r)
+		if !errors.Is(r.Err, lg.ErrUnsupportedCommand) {
+			t.Error("expected unsupported command error but got", r.Err)
+		}
+	}
+	if s.String() != "1 targets, 0 succeeded, 1 failed" {
+		t.Error("unexpected summary", s)
+	}
+}
@@ -0,0 +1,102 @@
+package lg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// carrierASNs maps the carriers ASNs to their looking glass providers
+var carrierASNs = map[int]string{
+	174:  "cogent",
+	286:  "kpn",
+	1299: "telia",
+	2914: "ntt",
+	3356: "level3",
+	3549: "level3",
+}
+
+// ProviderSelection represents the provider which is selected for the
+// target, Matched is false if it's the default provider. Reason explains
+// the selection e.g. to print it before the query
+type ProviderSelection struct {
+	Provider string `json:"provider"`
+	Target   string `json:"target"`
+	IP       string `json:"ip,omitempty"`
+	ASN      int    `json:"asn,omitempty"`
+	Holder   string `json:"holder,omitempty"`
+	Matched  bool   `json:"matched"`
+	Reason   string `json:"reason"`
+}
+
+// ProviderSelector selects the looking glass of the carrier which
+// originates the target, the default provider is the fallback
+type ProviderSelector struct {
+	// Origin returns the origin ASes of the address,
+	// the ASNLookup origins are used if it's nil
+	Origin func(ctx context.Context, ip string) ([]ASNInfo, error)
+	// Default is the fallback provider, DefaultProvider
+	// is used if it's empty
+	Default string
+}
+
+// SelectProvider selects the provider of the target w/ the default selector
+func SelectProvider(ctx context.Context, target string) ProviderSelection {
+	return (&ProviderSelector{}).Select(ctx, target)
+}
+
+// Select resolves the target, looks up its origin ASNs and returns the
+// registered provider of the origin carrier. the default provider is
+// returned w/ the reason if the lookups fail or none of the origins is
+// a known carrier
+func (s *ProviderSelector) Select(ctx context.Context, target string) ProviderSelection {
+	sel := ProviderSelection{Provider: s.fallback(), Target: target}
+	ips, err := ResolveHost(ctx, target, "")
+	if err != nil {
+		sel.Reason = fmt.Sprintf("%s, the default provider %s is used", err, sel.Provider)
+		return sel
+	}
+	sel.IP = ips[0]
+	origins, err := s.origin()(ctx, sel.IP)
+	if err != nil {
+		sel.Reason = fmt.Sprintf("the origin AS lookup of %s failed (%s), the default provider %s is used", sel.IP, err, sel.Provider)
+		return sel
+	}
+	if len(origins) == 0 {
+		sel.Reason = fmt.Sprintf("%s isn't announced, the default provider %s is used", sel.IP, sel.Provider)
+		return sel
+	}
+	sel.ASN, sel.Holder = origins[0].ASN, origins[0].holder()
+	registered := map[string]bool{}
+	for _, name := range Providers() {
+		registered[name] = true
+	}
+	var asns []string
+	for _, o := range origins {
+		asns = append(asns, fmt.Sprintf("AS%d", o.ASN))
+		if name, ok := carrierASNs[o.ASN]; ok && registered[name] {
+			sel.Provider, sel.ASN, sel.Holder, sel.Matched = name, o.ASN, o.holder(), true
+			sel.Reason = fmt.Sprintf("%s (%s) is originated by AS%d %s, the %s looking glass is selected",
+				target, sel.IP, o.ASN, sel.Holder, name)
+			return sel
+		}
+	}
+	sel.Reason = fmt.Sprintf("%s (%s) is originated by %s which has no looking glass provider, the default provider %s is used",
+		target, sel.IP, strings.Join(asns, ", "), sel.Provider)
+	return sel
+}
+
+// fallback returns the default provider of the selector
+func (s *ProviderSelector) fallback() string {
+	if s.Default != "" {
+		return s.Default
+	}
+	return DefaultProvider()
+}
+
+func (s *ProviderSelector) origin() func(context.Context, string) ([]ASNInfo, error) {
+	if s.Origin != nil {
+		return s.Origin
+	}
+	return NewASNLookup().Origins
+}
@@ -0,0 +1,51 @@
+package lg_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestProviderSelector(t *testing.T) {
+	origins := map[string][]lg.ASNInfo{
+		"192.0.2.1":   {{ASN: 64500, Name: "EXAMPLE"}, {ASN: 174, Name: "COGENT-174"}},
+		"192.0.2.2":   {{ASN: 64500, Name: "EXAMPLE"}},
+		"192.0.2.3":   nil,
+		"2001:db8::1": {{ASN: 1299, Name: "TWELVE99"}},
+	}
+	s := &lg.ProviderSelector{
+		Default: "telia",
+		Origin: func(ctx context.Context, ip string) ([]lg.ASNInfo, error) {
+			o, ok := origins[ip]
+			if !ok {
+				return nil, errors.New("timeout")
+			}
+			return o, nil
+		},
+	}
+	tests := []struct {
+		target   string
+		provider string
+		asn      int
+		matched  bool
+		reason   string
+	}{
+		{"192.0.2.1", "cogent", 174, true, "originated by AS174 COGENT-174, the cogent looking glass is selected"},
+		{"192.0.2.2", "telia", 64500, false, "AS64500 which has no looking glass provider"},
+		{"192.0.2.3", "telia", 0, false, "isn't announced"},
+		{"192.0.2.4", "telia", 0, false, "lookup of 192.0.2.4 failed (timeout)"},
+		{"2001:db8::1", "telia", 1299, true, "the telia looking glass is selected"},
+	}
+	for _, tt := range tests {
+		sel := s.Select(context.Background(), tt.target)
+		if sel.Provider != tt.provider || sel.ASN != tt.asn || sel.Matched != tt.matched || sel.IP != tt.target {
+			t.Errorf("%s: unexpected selection %+v", tt.target, sel)
+		}
+		if !strings.Contains(sel.Reason, tt.reason) {
+			t.Errorf("%s: expected reason %q but got %q", tt.target, tt.reason, sel.Reason)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package lg
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+//go:generate go run gen_cogent_nodes.go -o cogent_nodes.json
+
+// cogentSnapshot is the Cogent nodes snapshot which is bundled w/ the
+// binary, it's regenerated from a live fetch by go generate
+//
+//go:embed cogent_nodes.json
+var cogentSnapshot []byte
+
+// ErrStaleNodes is returned w/ the nodes of the bundled snapshot once the
+// disk cache and the live fetch fail, the snapshot may not reflect the
+// current PoPs
+var ErrStaleNodes = errors.New("offline nodes: the bundled snapshot is used, it may be stale")
+
+// readNodesSnapshot returns the bundled nodes snapshot
+func readNodesSnapshot(b []byte) (nodesCache, error) {
+	var c nodesCache
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("invalid nodes snapshot: %v", err)
+	}
+	if len(c.Nodes) < 1 {
+		return c, errors.New("nodes snapshot is empty")
+	}
+	return c, nil
+}
+
+// snapshot returns the nodes of the SnapshotFile or the bundled snapshot
+func (p *Cogent) snapshot() (nodesCache, error) {
+	if p.SnapshotFile == "" {
+		return readNodesSnapshot(cogentSnapshot)
+	}
+	b, err := ioutil.ReadFile(p.SnapshotFile)
+	if err != nil {
+		return nodesCache{}, err
+	}
+	return readNodesSnapshot(b)
+}
@@ -4,13 +4,12 @@ package lg
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"os"
-	"os/signal"
 	"regexp"
 	"sort"
 )
@@ -21,22 +20,39 @@ type Telia struct {
 	IPv   string
 	Node  string
 	Nodes []string
+	// BaseURL is the looking glass address, teliaLGURL
+	// is used if it's empty
+	BaseURL string
+	// Client is used for the looking glass requests, the
+	// package http client (see SetHTTPClient) is used if it's nil
+	Client *http.Client
 }
 
+const teliaLGURL = "http://looking-glass.telia.net/"
+
+var (
+	_ LookingGlass    = (*Telia)(nil)
+	_ ContextStreamer = (*Telia)(nil)
+)
+
 var teliaDefaultNode = "Los Angeles"
 
+func init() {
+	Register("telia", func() LookingGlass { return new(Telia) })
+}
+
 // Set configures host and ip version
 func (p *Telia) Set(host, version string) {
 	p.Host = host
-	p.IPv = version
+	p.IPv = ipVersion(version)
 	if p.Node == "" {
-		p.Node = teliaDefaultNode
+		p.Node = defaultNode("telia", teliaDefaultNode)
 	}
 }
 
 // GetDefaultNode returns telia default node
 func (p *Telia) GetDefaultNode() string {
-	return teliaDefaultNode
+	return defaultNode("telia", teliaDefaultNode)
 }
 
 // GetNodes returns all Telia nodes (US and International)
@@ -54,14 +70,32 @@ func (p *Telia) GetNodes() []string {
 	return nodes
 }
 
-// ChangeNode set new requested node
+// MatchNodes returns the candidate nodes for the query
+func (p *Telia) MatchNodes(query string) []string {
+	return matchNodes(p.Nodes, nil, query)
+}
+
+// CompletionCandidates returns the loaded nodes which match the prefix
+func (p *Telia) CompletionCandidates(prefix string) []string {
+	return completionCandidates(p.Nodes, prefix)
+}
+
+// Get returns the host and the ip version which are set
+func (p *Telia) Get() (string, string) {
+	return p.Host, p.IPv
+}
+
+// GetNode returns the current node
+func (p *Telia) GetNode() string {
+	return p.Node
+}
+
+// ChangeNode set new requested node, the node can be a
+// part of the name as long as it matches only one node
 func (p *Telia) ChangeNode(node string) bool {
-	// Validate
-	for _, n := range p.Nodes {
-		if node == n {
-			p.Node = node
-			return true
-		}
+	if m := p.MatchNodes(node); len(m) == 1 {
+		p.Node = m[0]
+		return true
 	}
 	return false
 }
@@ -69,44 +103,55 @@ func (p *Telia) ChangeNode(node string) bool {
 // Ping tries to connect Telia's ping looking glass through HTTP
 // Returns the result
 func (p *Telia) Ping() (string, error) {
-	// Basic validate
-	if p.Node == "NA" || len(p.Host) < 5 {
-		print("Invalid node or host/ip address")
-		return "", errors.New("error")
+	if p.Node == "NA" {
+		return "", errors.New("invalid node")
 	}
-	resp, err := http.PostForm("http://looking-glass.telia.net/",
-		url.Values{"query": {"ping"}, "protocol": {p.IPv}, "addr": {p.Host}, "router": {p.Node}})
+	host, err := normalizeHost(p.Host)
 	if err != nil {
 		return "", err
 	}
-	if resp.StatusCode != 200 {
-		return "", errors.New("error: telia looking glass is not available")
+	resp, err := p.postForm(context.Background(), url.Values{"query": {"ping"}, "protocol": {p.IPv}, "addr": {host}, "router": {p.Node}})
+	if err != nil {
+		return "", fmt.Errorf("telia looking glass ping failed: %w", err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("telia looking glass is not available (%s)", resp.Status)
+	}
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("telia looking glass read failed: %w", err)
 	}
 	r, _ := regexp.Compile(`<CODE>(?s)(.*?)</CODE>`)
 	b := r.FindStringSubmatch(string(body))
 	if len(b) > 0 {
+		observePing("telia", p.Node, b[1])
 		return b[1], nil
 	}
-	return "", errors.New("error")
+	return "", fmt.Errorf("%w: telia ping output not found", ErrUnexpectedResponse)
 }
 
 // Trace gets traceroute information from Telia
-func (p *Telia) Trace() chan string {
-	c := make(chan string)
-	sigCh := make(chan os.Signal, 1)
-
-	signal.Notify(sigCh, os.Interrupt)
+func (p *Telia) Trace() (chan string, error) {
+	return p.TraceContext(context.Background())
+}
 
-	resp, err := http.PostForm("http://looking-glass.telia.net/",
-		url.Values{"query": {"trace"}, "protocol": {p.IPv}, "addr": {p.Host}, "router": {p.Node}})
+// TraceContext is like Trace but the request is bound to ctx, the
+// stream is closed once ctx is done
+func (p *Telia) TraceContext(ctx context.Context) (chan string, error) {
+	host, err := normalizeHost(p.Host)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.postForm(ctx, url.Values{"query": {"trace"}, "protocol": {p.IPv}, "addr": {host}, "router": {p.Node}})
 	if err != nil {
-		println(err)
+		return nil, fmt.Errorf("telia looking glass trace failed: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("telia looking glass is not available (%s)", resp.Status)
 	}
+	c := make(chan string)
 	go func() {
 		defer resp.Body.Close()
 		scanner := bufio.NewScanner(resp.Body)
@@ -115,27 +160,48 @@ func (p *Telia) Trace() chan string {
 			l := scanner.Text()
 			m, _ := regexp.MatchString(`^(traceroute|\s*\d{1,2})`, l)
 			if m {
-				l = replaceASNTrace(l)
+				l = cleanLine(replaceASNTrace(l))
 				select {
-				case <-sigCh:
+				case <-ctx.Done():
 					break LOOP
 				case c <- l:
 				}
 			}
 		}
-		signal.Stop(sigCh)
 		close(c)
 	}()
-	return c
+	return c, nil
+}
+
+// TraceStructured is like Trace but it parses each hop
+func (p *Telia) TraceStructured() (chan TraceHop, error) {
+	lines, err := p.Trace()
+	if err != nil {
+		return nil, err
+	}
+	return traceHops(lines), nil
 }
 
 // BGP gets bgp information from Telia
 func (p *Telia) BGP() chan string {
+	return p.BGPContext(context.Background())
+}
+
+// BGPContext is like BGP but the request is bound to ctx, the
+// stream is closed once ctx is done
+func (p *Telia) BGPContext(ctx context.Context) chan string {
 	c := make(chan string)
-	resp, err := http.PostForm("http://looking-glass.telia.net/",
-		url.Values{"query": {"bgp"}, "protocol": {p.IPv}, "addr": {p.Host}, "router": {p.Node}})
+	host, err := normalizePrefix(p.Host)
+	if err != nil {
+		getLogger().Error("%s", err)
+		close(c)
+		return c
+	}
+	resp, err := p.postForm(ctx, url.Values{"query": {"bgp"}, "protocol": {p.IPv}, "addr": {host}, "router": {p.Node}})
 	if err != nil {
-		println(err)
+		getLogger().Error("telia looking glass bgp failed: %s", err)
+		close(c)
+		return c
 	}
 	go func() {
 		var (
@@ -144,6 +210,7 @@ func (p *Telia) BGP() chan string {
 		)
 		defer resp.Body.Close()
 		scanner := bufio.NewScanner(resp.Body)
+	LOOP:
 		for scanner.Scan() {
 			l := scanner.Text()
 			l = sanitize(l)
@@ -154,7 +221,11 @@ func (p *Telia) BGP() chan string {
 			if !parse || (l == last) {
 				continue
 			}
-			c <- l
+			select {
+			case <-ctx.Done():
+				break LOOP
+			case c <- l:
+			}
 			last = l
 		}
 		close(c)
@@ -162,18 +233,39 @@ func (p *Telia) BGP() chan string {
 	return c
 }
 
+// baseURL returns the configured looking glass address or the default one
+func (p *Telia) baseURL() string {
+	if p.BaseURL != "" {
+		return p.BaseURL
+	}
+	return teliaLGURL
+}
+
+// client returns the configured http client or the package one
+func (p *Telia) client() *http.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	return httpClient
+}
+
+// postForm sends the form to the looking glass
+func (p *Telia) postForm(ctx context.Context, form url.Values) (*http.Response, error) {
+	return postFormContext(withLabels(ctx, "telia", p.Node, form.Get("query")), p.client(), p.baseURL(), form)
+}
+
 //FetchNodes returns all available nodes through HTTP
 func (p *Telia) FetchNodes() map[string]string {
 	var nodes = make(map[string]string, 100)
-	resp, err := http.Get("http://looking-glass.telia.net/")
+	resp, err := getContext(withLabels(context.Background(), "telia", p.Node, "nodes"), p.client(), p.baseURL())
 	if err != nil {
-		println("error: telia looking glass unreachable (1) ")
+		getLogger().Warn("telia looking glass unreachable (1)")
 		return map[string]string{}
 	}
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		println("error: telia looking glass unreachable (2)" + err.Error())
+		getLogger().Warn("telia looking glass unreachable (2) %s", err)
 		return map[string]string{}
 	}
 	r, _ := regexp.Compile(`(?i)<option value="(?s)([\w|\s|)(._-]+)"> (?s)([\w|\s|)(._-]+)`)
@@ -187,10 +279,16 @@ func (p *Telia) FetchNodes() map[string]string {
 //[GOOGLE (ARIN)" HREF="http://www.arin.net/cgi-bin/whois.pl?queryinput=15169" TARGET=_lookup>15169</A>]  1.261 ms 72.14.236.69 (72.14.236.69) [AS  <A title="GOOGLE (ARIN)" HREF="http://www.arin.net/cgi-bin/whois.pl?queryinput=15169" TARGET=_lookup>15169</A>]
 // replaceASNTrace
 func replaceASNTrace(l string) string {
-	m, _ := regexp.MatchString(`\[AS\s+`, l)
+	m, _ := regexp.MatchString(`\[AS\s*`, l)
 	if !m {
 		return l
 	}
+	return replaceASNLink(annotateASN(l))
+}
+
+// replaceASNLink replaces the whois link of the ASN w/ the holder
+// and the ASN, the AS names aren't looked up
+func replaceASNLink(l string) string {
 	r := regexp.MustCompile(`(?i)\[AS\s+<A\s+title="([a-z|\d|\s|\(\)_,-]+)"\s+HREF="[a-z|\/|:.-]+\?\w+=\d+"\s+\w+=_lookup>(\d+)</A>]`)
 	asn := r.FindStringSubmatch(l)
 	if len(asn) == 3 {
@@ -198,3 +296,8 @@ func replaceASNTrace(l string) string {
 	}
 	return l
 }
+
+// SupportedCommands returns the commands which Telia offers
+func (p *Telia) SupportedCommands() []Command {
+	return []Command{CmdPing, CmdTrace, CmdBGP}
+}
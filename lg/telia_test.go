@@ -0,0 +1,175 @@
+package lg_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+const teliaNodesFixture = `<select name="router">
+<option value="Los Angeles"> Los Angeles</option>
+<option value="Stockholm"> Stockholm</option>
+</select>`
+
+const teliaPingFixture = `<html><body>
+<CODE>PING 8.8.8.8: 56 data bytes
+5 packets transmitted, 5 packets received, 0.0% packet loss</CODE>
+</body></html>`
+
+const teliaTraceFixture = `<html><body>
+traceroute to 8.8.8.8 (8.8.8.8), 30 hops max
+ 1  lax-b21-link.telia.net (62.115.49.1)  0.345 ms
+</body></html>`
+
+func TestTelia(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, teliaNodesFixture)
+			return
+		}
+		if r.FormValue("query") == "trace" {
+			fmt.Fprint(w, teliaTraceFixture)
+			return
+		}
+		fmt.Fprint(w, teliaPingFixture)
+	}))
+	defer ts.Close()
+
+	p := &lg.Telia{BaseURL: ts.URL}
+	if nodes := p.GetNodes(); !reflect.DeepEqual(nodes, []string{"Los Angeles", "Stockholm"}) {
+		t.Error("unexpected nodes", nodes)
+	}
+	if !p.ChangeNode("Stockholm") {
+		t.Error("expected Stockholm node")
+	}
+	p.Set("8.8.8.8", "ipv4")
+
+	out, err := p.Ping()
+	if err != nil {
+		t.Error(err)
+	}
+	if s, err := lg.ParsePing(out); err != nil || s.PacketsReceived != 5 {
+		t.Error("unexpected ping output", out)
+	}
+
+	lines, err := p.Trace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var trace []string
+	for l := range lines {
+		trace = append(trace, l)
+	}
+	if len(trace) != 2 {
+		t.Error("unexpected trace", trace)
+	}
+}
+
+func TestTeliaBGPUnreachable(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	ts.Close()
+
+	p := &lg.Telia{BaseURL: ts.URL}
+	p.Set("8.8.8.8", "ipv4")
+	for l := range p.BGP() {
+		t.Error("unexpected bgp line", l)
+	}
+}
+
+func TestTeliaChangeNodePartial(t *testing.T) {
+	p := &lg.Telia{Nodes: []string{"Los Angeles", "Stockholm", "Stockholm 2"}}
+	if !p.ChangeNode("los") || p.Node != "Los Angeles" {
+		t.Error("expected Los Angeles node, got", p.Node)
+	}
+	if p.ChangeNode("stock") {
+		t.Error("expected ambiguous node to be refused")
+	}
+	if p.ChangeNode("Paris") {
+		t.Error("expected unknown node to be refused")
+	}
+}
+
+func TestTeliaPingUnexpectedResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><body>maintenance</body></html>")
+	}))
+	defer ts.Close()
+
+	p := &lg.Telia{BaseURL: ts.URL, Node: "Stockholm"}
+	p.Set("8.8.8.8", "ipv4")
+	if _, err := p.Ping(); !errors.Is(err, lg.ErrUnexpectedResponse) {
+		t.Error("expected ErrUnexpectedResponse, got", err)
+	}
+}
+
+func TestTeliaBGPContextCanceled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Telia Carrier")
+		for i := 0; i < 1000; i++ {
+			fmt.Fprintf(w, "route %d\n", i)
+		}
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &lg.Telia{BaseURL: ts.URL, Node: "Stockholm"}
+	p.Set("8.8.8.0/24", "ipv4")
+	c := p.BGPContext(ctx)
+	if l, ok := <-c; !ok || l != "route 0" {
+		t.Fatal("unexpected bgp line", l)
+	}
+	cancel()
+	n := 0
+	for range c {
+		n++
+	}
+	if n > 1 {
+		t.Error("expected the stream to be closed once ctx is canceled, got", n)
+	}
+}
+
+func TestTeliaMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, teliaNodesFixture)
+			return
+		}
+		if r.FormValue("addr") == "down.example.com" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, teliaPingFixture)
+	}))
+	defer ts.Close()
+
+	m := &fakeMetrics{}
+	lg.SetMetrics(m)
+	defer lg.SetMetrics(nil)
+
+	p := &lg.Telia{BaseURL: ts.URL, Node: "Stockholm"}
+	p.FetchNodes()
+	p.Set("8.8.8.8", "ipv4")
+	if _, err := p.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	p.Set("down.example.com", "ipv4")
+	p.Ping()
+
+	expected := []string{
+		"telia|Stockholm|nodes|",
+		"telia|Stockholm|ping|",
+		"telia|Stockholm|ping|http",
+	}
+	if !reflect.DeepEqual(m.requests, expected) {
+		t.Errorf("expected requests %q but got %q", expected, m.requests)
+	}
+	if len(m.rtts) != 1 {
+		t.Error("expected the ping rtt but got", m.rtts)
+	}
+}
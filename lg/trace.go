@@ -0,0 +1,155 @@
+package lg
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TraceHop represents a parsed traceroute hop
+type TraceHop struct {
+	Index int
+	Host  string
+	IP    string
+	ASN   string
+	RTTs  []time.Duration
+	// Timeouts is the number of the probes w/o reply (*)
+	Timeouts int
+	// Geo is the hop location, it's set once
+	// the enrichment is enabled (see SetGeoLocator)
+	Geo *GeoInfo
+	// PTR is the hop reverse DNS name, it's set once
+	// the reverse DNS is enabled (see SetPTRResolver)
+	PTR string
+}
+
+var (
+	traceHopRe  = regexp.MustCompile(`^\s*(\d{1,2})\s+(.*)$`)
+	traceHostRe = regexp.MustCompile(`([^\s(\[]+)\s+\(([\da-fA-F.:]+)\)`)
+	traceIPRe   = regexp.MustCompile(`^\s*([\d.]+|[\da-fA-F:]*:[\da-fA-F:.]+)(\s|$)`)
+	traceASNRe  = regexp.MustCompile(`(?i)\[(?:AS\s*(\d+)[^\]]*|[^\]]*\((\d+)\))\]`)
+)
+
+// traceRTTUnits are the rtt units of the traceroute lines
+var traceRTTUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond, // micro sign
+	"μs": time.Microsecond, // greek mu
+}
+
+// parseTraceHop parses a traceroute line, it returns false if the line
+// isn't a hop line (e.g. the traceroute header). the providers already
+// annotated the line so only the ASN links are replaced
+func parseTraceHop(l string) (TraceHop, bool) {
+	var hop TraceHop
+
+	m := traceHopRe.FindStringSubmatch(replaceASNLink(l))
+	if len(m) != 3 {
+		return hop, false
+	}
+	hop.Index, _ = strconv.Atoi(m[1])
+	rest := m[2]
+
+	if h := traceHostRe.FindStringSubmatch(rest); len(h) == 3 {
+		hop.Host, hop.IP = h[1], h[2]
+	} else if ip := traceIPRe.FindStringSubmatch(rest); len(ip) > 1 {
+		hop.Host, hop.IP = ip[1], ip[1]
+	}
+	if a := traceASNRe.FindStringSubmatch(rest); len(a) == 3 {
+		hop.ASN = a[1] + a[2]
+		rest = traceASNRe.ReplaceAllString(rest, "")
+	}
+	fields := strings.Fields(rest)
+	for i, f := range fields {
+		if f == "*" {
+			hop.Timeouts++
+			continue
+		}
+		next := ""
+		if i+1 < len(fields) {
+			next = fields[i+1]
+		}
+		if rtt, ok := parseRTT(f, next); ok {
+			hop.RTTs = append(hop.RTTs, rtt)
+		}
+	}
+	return hop, true
+}
+
+// parseRTT parses the rtt of the field and its unit which is the next field
+// or the field suffix e.g. 1.5 ms or 750us, it returns false if it isn't a rtt
+func parseRTT(field, next string) (time.Duration, bool) {
+	unit, ok := traceRTTUnits[next]
+	if !ok {
+		for u, d := range traceRTTUnits {
+			if strings.HasSuffix(field, u) {
+				field, unit, ok = strings.TrimSuffix(field, u), d, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(field, 64)
+	if err != nil || v < 0 {
+		return 0, false
+	}
+	return time.Duration(v * float64(unit)), true
+}
+
+// AvgRTT returns the average rtt of the replies, it's zero w/o any reply
+func (h TraceHop) AvgRTT() time.Duration {
+	if len(h.RTTs) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, rtt := range h.RTTs {
+		sum += rtt
+	}
+	return sum / time.Duration(len(h.RTTs))
+}
+
+// traceHops parses the traceroute lines as they arrive, the hops are
+// enriched concurrently and they're sent in the traceroute order
+func traceHops(lines chan string) chan TraceHop {
+	c := make(chan TraceHop)
+	pending := make(chan chan TraceHop, 32)
+	go func() {
+		for l := range lines {
+			if hop, ok := parseTraceHop(l); ok {
+				r := make(chan TraceHop, 1)
+				pending <- r
+				go func(hop TraceHop) {
+					locateHop(&hop)
+					resolveHop(&hop)
+					r <- hop
+				}(hop)
+			}
+		}
+		close(pending)
+	}()
+	go func() {
+		for r := range pending {
+			c <- <-r
+		}
+		close(c)
+	}()
+	return c
+}
+
+// locateHop sets the hop location if the enrichment is enabled
+func locateHop(hop *TraceHop) {
+	g := geoLocator
+	if g == nil || hop.IP == "" {
+		return
+	}
+	info, err := g.Locate(hop.IP)
+	if err != nil {
+		getLogger().Debug("geo: locate %s failed: %s", hop.IP, err)
+		return
+	}
+	hop.Geo = &info
+}
@@ -3,29 +3,39 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/briandowns/spinner"
 
 	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/dhcp"
 	"github.com/mehrdadrad/mylg/disc"
 	"github.com/mehrdadrad/mylg/http/ping"
 	"github.com/mehrdadrad/mylg/icmp"
 	"github.com/mehrdadrad/mylg/lg"
+	"github.com/mehrdadrad/mylg/netif"
 	"github.com/mehrdadrad/mylg/nms"
 	"github.com/mehrdadrad/mylg/ns"
 	"github.com/mehrdadrad/mylg/packet"
 	"github.com/mehrdadrad/mylg/peeringdb"
+	"github.com/mehrdadrad/mylg/report"
+	"github.com/mehrdadrad/mylg/ripe"
 	"github.com/mehrdadrad/mylg/scan"
 	"github.com/mehrdadrad/mylg/services/httpd"
+	"github.com/mehrdadrad/mylg/sniff"
 	"github.com/mehrdadrad/mylg/speedtest"
 	"github.com/mehrdadrad/mylg/whois"
 )
@@ -34,17 +44,6 @@ const (
 	version = "0.2.7"
 )
 
-// Provider represents looking glass
-type Provider interface {
-	Set(host, version string)
-	GetDefaultNode() string
-	GetNodes() []string
-	ChangeNode(node string) bool
-	Ping() (string, error)
-	Trace() chan string
-	BGP() chan string
-}
-
 var (
 	pNames    = providerNames()
 	req       = make(chan string, 1)
@@ -60,26 +59,34 @@ var (
 	nsr       *ns.Request
 	c         *cli.Readline
 
-	// register looking glass hosts
-	providers = map[string]Provider{
-		"telia":  new(lg.Telia),
-		"level3": new(lg.Level3),
-		"cogent": new(lg.Cogent),
-		"ntt":    new(lg.NTT),
-		"kpn":    new(lg.KPN),
-	}
+	// nodesTimeout bounds the looking glass nodes fetch of the console
+	nodesTimeout = 5 * time.Second
+
+	// registered looking glass hosts
+	providers = lgProviders()
 
 	// map cmd to function
 	cmdFunc = map[string]func(){
 		"web":       web,          // web dashboard
+		"serve":     serve,        // looking glass api server
 		"dump":      dump,         // dump traffic
+		"sniff":     sniffPackets, // packets summary / conversations
 		"disc":      discovery,    // network discovery
+		"dhcp":      dhcpDiscover, // dhcp servers discovery
+		"ifaces":    ifaces,       // local interfaces and gateways
 		"scan":      scanPorts,    // network scan
+		"banner":    banner,       // tcp banner grab
+		"cert":      cert,         // tls certificate chain
 		"mode":      mode,         // editor mode
 		"ping":      pingQuery,    // ping
 		"trace":     trace,        // trace route
+		"pmtu":      pmtu,         // path mtu discovery
+		"check":     check,        // quick connectivity report
+		"batch":     batch,        // looking glass batch run
+		"preset":    preset,       // looking glass query presets
 		"bgp":       BGP,          // BGP
 		"whois":     whoisLookup,  // whois / dns lookup
+		"asn":       asnLookup,    // AS name and prefixes
 		"peering":   peeringDB,    // peering DB
 		"hping":     hping,        // hping
 		"dig":       dig,          // dig
@@ -103,6 +110,14 @@ var (
 func init() {
 	// load configuration
 	cfg = cli.LoadConfig()
+	lg.SetUserAgent("mylg/" + version)
+	if _, err := lg.LoadConfig(); err != nil {
+		println(err.Error())
+	}
+	// record or replay the looking glass responses (MYLG_RECORD / MYLG_REPLAY)
+	if err := lg.RecordFromEnv(); err != nil {
+		println(err.Error())
+	}
 	// initialize name server
 	nsr = ns.NewRequest()
 	go nsr.Init()
@@ -127,6 +142,7 @@ func init() {
 func main() {
 	// command line w/o interface
 	if noIf {
+		setLogLevel()
 		cmd := eArgs[1]
 		args = strings.Join(eArgs[2:], " ")
 		if f, ok := cmdFunc[cmd]; ok {
@@ -167,6 +183,16 @@ LOOP:
 	}
 }
 
+// lgProviders returns the registered looking glasses
+func lgProviders() map[string]lg.LookingGlass {
+	providers := map[string]lg.LookingGlass{}
+	for _, name := range lg.Providers() {
+		p, _ := lg.New(name)
+		providers[name] = p
+	}
+	return providers
+}
+
 // providerName
 func providerNames() []string {
 	pNames := []string{}
@@ -191,8 +217,28 @@ func validateProvider(p string) (string, error) {
 // node handles node cmd
 func node() {
 	switch {
+	case strings.HasPrefix(prompt, "lg") && args == "":
+		printNodes(providers[cPName])
+	case strings.HasPrefix(prompt, "lg") && strings.Contains(args, "-nearest"):
+		nearestNode(providers[cPName])
+	case strings.HasPrefix(prompt, "lg") && strings.Contains(args, "-json"):
+		printNodeCatalog(providers[cPName])
 	case strings.HasPrefix(prompt, "lg"):
 		if _, ok := providers[cPName]; ok {
+			if m, ok := providers[cPName].(lg.NodeMatcher); ok {
+				nodes := m.MatchNodes(args)
+				if len(nodes) > 1 {
+					println("the specified node is ambiguous, please select one of the below nodes:")
+					for _, n := range nodes {
+						println(n)
+					}
+					return
+				}
+				if len(nodes) == 1 && providers[cPName].ChangeNode(nodes[0]) {
+					c.UpdatePromptN(nodes[0], 3)
+					return
+				}
+			}
 			if providers[cPName].ChangeNode(args) {
 				c.UpdatePromptN(args, 3)
 				return
@@ -213,9 +259,96 @@ func node() {
 	}
 }
 
+// nearestNode selects the node w/ the lowest rtt to the host (node
+// -nearest [host]), the host is pinged through the sampled nodes
+func nearestNode(p lg.LookingGlass) {
+	n, ok := p.(lg.NearestNodeSelector)
+	if !ok {
+		println("nearest node doesn't support")
+		return
+	}
+	host, flag := cli.Flag(args)
+	if h, ok := flag["nearest"].(string); ok {
+		host = h
+	}
+	spin.Prefix = "please wait "
+	spin.Start()
+	node, rtt, err := n.NearestNode(host)
+	spin.Stop()
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	fmt.Printf("selected %s, the lowest average rtt %.2f ms of the sampled nodes (one per region)\n", node, ms(rtt))
+	c.UpdatePromptN(node, 3)
+}
+
+// getNodes returns the provider nodes, the fetch is bounded
+// by nodesTimeout so the console doesn't hang on a bad network
+func getNodes(p lg.LookingGlass) ([]string, error) {
+	n, ok := p.(lg.NodesContext)
+	if !ok {
+		return p.GetNodes(), nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), nodesTimeout)
+	defer cancel()
+	return n.GetNodesContext(ctx)
+}
+
+// printNodes lists the provider nodes, grouped by region if it's supported
+func printNodes(p lg.LookingGlass) {
+	nodes, err := getNodes(p)
+	if err != nil {
+		println(err.Error())
+	}
+	g, ok := p.(lg.NodeGrouper)
+	if !ok || len(nodes) == 0 {
+		for _, n := range nodes {
+			println(n)
+		}
+		return
+	}
+	groups := g.GetNodesByRegion()
+	var regions []string
+	for r := range groups {
+		if r != lg.OtherRegion {
+			regions = append(regions, r)
+		}
+	}
+	sort.Strings(regions)
+	if _, ok := groups[lg.OtherRegion]; ok {
+		regions = append(regions, lg.OtherRegion)
+	}
+	for _, r := range regions {
+		fmt.Printf("%s:\n", r)
+		for _, n := range groups[r] {
+			fmt.Printf("  %s\n", n)
+		}
+	}
+}
+
+// printNodeCatalog prints the provider nodes w/ their location
+// codes, regions and capabilities in json format
+func printNodeCatalog(p lg.LookingGlass) {
+	if _, err := getNodes(p); err != nil {
+		println(err.Error())
+	}
+	nc, ok := p.(lg.NodeCataloger)
+	if !ok {
+		println("node catalog doesn't support")
+		return
+	}
+	printJSON(nc.NodeCatalog())
+}
+
 // dig gets dig info
 func dig() {
-	if ok := nsr.SetOptions(args, prompt); ok {
+	ok, err := nsr.SetOptions(args, prompt)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	if ok {
 		nsr.Dig()
 	}
 }
@@ -235,6 +368,44 @@ func web() {
 
 }
 
+// setLogLevel applies the leading -v (debug) or -q (errors only)
+// flags to the looking glass logs and removes them from the args
+func setLogLevel() {
+	for len(eArgs) > 2 && (eArgs[1] == "-v" || eArgs[1] == "-q") {
+		level := lg.LevelDebug
+		if eArgs[1] == "-q" {
+			level = lg.LevelError
+		}
+		lg.SetLogger(lg.NewStdLogger(os.Stderr, level))
+		eArgs = append(eArgs[:1], eArgs[2:]...)
+	}
+}
+
+// serve runs the web service w/ the looking glass api
+// until it gets SIGTERM or interrupt
+func serve() {
+	_, flag := cli.Flag(args)
+	addr := cli.SetFlag(flag, "a", fmt.Sprintf("%s:%d", cfg.Web.Address, cfg.Web.Port)).(string)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	fmt.Printf("listening on %s ...\n", addr)
+	if err := httpd.Serve(ctx, addr, cfg); err != nil {
+		println(err.Error())
+	}
+}
+
 // dump provides decoding packets
 func dump() {
 	p, err := packet.NewPacket(args)
@@ -247,6 +418,21 @@ func dump() {
 	}
 }
 
+// sniffPackets prints a summary per packet or the conversations
+func sniffPackets() {
+	s, err := sniff.New(args)
+	if s == nil || err != nil {
+		if err != nil {
+			println(err.Error())
+		}
+		return
+	}
+	println(s.Banner())
+	if err := s.Run(); err != nil {
+		println(err.Error())
+	}
+}
+
 // connect handles connect cmd
 func connect() {
 	var (
@@ -254,6 +440,16 @@ func connect() {
 		err   error
 	)
 	switch {
+	case strings.HasPrefix(prompt, "lg") && strings.HasPrefix(args, "auto "):
+		sel := lg.SelectProvider(context.Background(), strings.TrimSpace(strings.TrimPrefix(args, "auto ")))
+		println(sel.Reason)
+		if _, ok := providers[sel.Provider]; !ok {
+			println("it doesn't support")
+			return
+		}
+		cPName = sel.Provider
+		c.UpdatePromptN(cPName+"/"+providers[cPName].GetDefaultNode(), 2)
+		go updateNodeCompleter(providers[cPName])
 	case strings.HasPrefix(prompt, "lg"):
 		if pName, err = validateProvider(args); err != nil {
 			println("provider not available")
@@ -263,9 +459,7 @@ func connect() {
 		cPName = pName
 		if _, ok := providers[cPName]; ok {
 			c.UpdatePromptN(cPName+"/"+providers[cPName].GetDefaultNode(), 2)
-			go func() {
-				c.UpdateCompleter("node", providers[cPName].GetNodes())
-			}()
+			go updateNodeCompleter(providers[cPName])
 		} else {
 			println("it doesn't support")
 		}
@@ -313,10 +507,62 @@ func trace() {
 		}
 		trace.Print()
 	case strings.HasPrefix(prompt, "lg"):
+		host, flag := cli.Flag(args)
+		if !lgSupports(lg.CmdTrace) || !allowTarget(host, flag) {
+			break
+		}
+		if cli.SetFlag(flag, "ptr", false).(bool) {
+			lg.SetPTRResolver(lg.NewDNSResolver())
+			defer lg.SetPTRResolver(nil)
+		}
+		if cli.SetFlag(flag, "asname", false).(bool) {
+			lg.SetASNResolver(lg.NewCymruResolver())
+			defer lg.SetASNResolver(nil)
+		}
+		dry, err := setDryRun(flag)
+		if err != nil {
+			println(err.Error())
+			break
+		}
+		defer setRawDump(flag)()
+		defer setFailover(flag)()
+		defer setSource(flag)()
+		defer setLocalAddr(flag)()
+		defer setResolve(flag)()
+		reset, err := setTraceLimits(flag)
+		if err != nil {
+			println(err.Error())
+			break
+		}
+		defer reset()
+		if cli.SetFlag(flag, "mtr", false).(bool) && !dry {
+			traceMTR(host, flag)
+			break
+		}
+		if cli.SetFlag(flag, "dot", false).(bool) && !dry {
+			traceDOT(host, cli.SetFlag(flag, "c", 1).(int))
+			break
+		}
+		if format := outputFormat(flag); format != "" && !dry {
+			if cli.SetFlag(flag, "geo", false).(bool) {
+				lg.SetGeoLocator(lg.NewGeoClient())
+				defer lg.SetGeoLocator(nil)
+			}
+			traceStructured(host, format)
+			break
+		}
+		ctx, stop := interruptContext()
+		defer stop()
 		spin.Prefix = "please wait "
 		spin.Start()
-		providers[cPName].Set(args, "ipv4")
-		for l := range providers[cPName].Trace() {
+		r, err := lg.RunContext(ctx, providers[cPName], lg.CmdTrace, host)
+		if err != nil {
+			spin.Stop()
+			println(err.Error())
+			break
+		}
+		defer r.Cancel()
+		for l := range lg.ResolveTrace(r.Lines) {
 			if spin.Prefix != "" {
 				spin.Stop()
 				spin.Prefix = ""
@@ -329,6 +575,313 @@ func trace() {
 	}
 }
 
+// lgSupports prints an error if the current provider doesn't offer the command
+func lgSupports(cmd lg.Command) bool {
+	if err := lg.CheckCommand(providers[cPName], cmd); err != nil {
+		println(err.Error())
+		return false
+	}
+	return true
+}
+
+// allowTarget warns if the looking glass target is a bogon, it's
+// queried anyway w/ the -force flag
+func allowTarget(host string, flag map[string]interface{}) bool {
+	err := lg.CheckBogon(host)
+	if err == nil {
+		return true
+	}
+	if cli.SetFlag(flag, "force", false).(bool) {
+		println("warning: " + err.Error())
+		return true
+	}
+	println("warning: " + err.Error() + ", try -force to query it anyway")
+	return false
+}
+
+// setDryRun applies the -dryrun flag to the current provider, the
+// requests are printed instead of being sent to the looking glass
+func setDryRun(flag map[string]interface{}) (bool, error) {
+	dry := cli.SetFlag(flag, "dryrun", false).(bool)
+	p, ok := providers[cPName].(*lg.Cogent)
+	if !ok {
+		if dry {
+			return false, errors.New("dry run doesn't support")
+		}
+		return false, nil
+	}
+	p.DryRun = dry
+	return dry, nil
+}
+
+// setRawDump applies the -debugdump flag, the returned func prints
+// the last raw response of the current provider to stderr
+func setRawDump(flag map[string]interface{}) func() {
+	dump := cli.SetFlag(flag, "debugdump", false).(bool)
+	p, ok := providers[cPName].(*lg.Cogent)
+	if !ok {
+		if dump {
+			println("debug dump doesn't support")
+		}
+		return func() {}
+	}
+	p.KeepRaw = dump
+	if !dump {
+		return func() {}
+	}
+	return func() {
+		fmt.Fprintf(os.Stderr, "--- raw response ---\n%s\n", p.LastRaw())
+	}
+}
+
+// setFailover applies the -failover flag, the request is sent through the
+// next healthy node of the region if the current node is unhealthy, the
+// returned func prints the node which served the request
+func setFailover(flag map[string]interface{}) func() {
+	failover := cli.SetFlag(flag, "failover", false).(bool)
+	p, ok := providers[cPName].(*lg.Cogent)
+	if !ok {
+		if failover {
+			println("failover doesn't support")
+		}
+		return func() {}
+	}
+	p.Failover = failover
+	if !failover {
+		return func() {}
+	}
+	return func() {
+		if n := p.ServedNode(); n != "" && n != p.Node {
+			fmt.Printf("%s is unhealthy, the request is served by %s\n", p.Node, n)
+		}
+	}
+}
+
+// setSource applies the -source flag (probe source) to the current
+// provider, it returns a func to reset it
+func setSource(flag map[string]interface{}) func() {
+	source := cli.SetFlag(flag, "source", "").(string)
+	p, ok := providers[cPName].(*lg.Cogent)
+	if !ok {
+		if source != "" {
+			println("source selection doesn't support")
+		}
+		return func() {}
+	}
+	p.Source = source
+	return func() { p.Source = "" }
+}
+
+// setLocalAddr applies the -laddr flag (the local source address of
+// the looking glass requests) to the current provider
+func setLocalAddr(flag map[string]interface{}) func() {
+	addr := fmt.Sprint(cli.SetFlag(flag, "laddr", ""))
+	p, ok := providers[cPName].(*lg.Cogent)
+	if !ok {
+		if addr != "" {
+			println("local address selection doesn't support")
+		}
+		return func() {}
+	}
+	p.LocalAddr = addr
+	return func() { p.LocalAddr = "" }
+}
+
+// setResolve applies the -resolve flag to the current provider, the
+// hostname is resolved locally and -resolve n picks the nth address
+func setResolve(flag map[string]interface{}) func() {
+	v, ok := flag["resolve"]
+	if !ok {
+		return func() {}
+	}
+	p, ok := providers[cPName].(*lg.Cogent)
+	if !ok {
+		println("local resolution doesn't support")
+		return func() {}
+	}
+	p.ResolveLocal = true
+	if i, ok := v.(int); ok {
+		p.ResolveIndex = i
+	}
+	return func() { p.ResolveLocal, p.ResolveIndex = false, 0 }
+}
+
+// setTraceLimits applies the -m (max hops) and -deadline flags to
+// the current provider, it returns a func to reset them
+func setTraceLimits(flag map[string]interface{}) (func(), error) {
+	maxHops, ok := cli.SetFlag(flag, "m", 0).(int)
+	if !ok {
+		return nil, errors.New("invalid max hops")
+	}
+	var wait time.Duration
+	switch v := cli.SetFlag(flag, "deadline", "0s").(type) {
+	case int:
+		wait = time.Duration(v) * time.Second
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deadline: %v", err)
+		}
+		wait = d
+	}
+	p, ok := providers[cPName].(*lg.Cogent)
+	if !ok {
+		if maxHops != 0 || wait != 0 {
+			println("max hops and deadline don't support")
+		}
+		return func() {}, nil
+	}
+	p.MaxHops, p.WaitTime = maxHops, wait
+	return func() { p.MaxHops, p.WaitTime = 0, 0 }, nil
+}
+
+// traceMTR re-runs the lg trace and repaints the hops statistics
+// until it's interrupted or the requested cycles (-c) are done
+func traceMTR(host string, flag map[string]interface{}) {
+	t, ok := providers[cPName].(lg.StructuredTracer)
+	if !ok {
+		println("mtr doesn't support")
+		return
+	}
+	interval, err := time.ParseDuration(cli.SetFlag(flag, "i", "2s").(string))
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	cycles := cli.SetFlag(flag, "c", 0).(int)
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	providers[cPName].Set(host, "")
+	for stats := range lg.MTR(ctx, t, interval, cycles) {
+		// clear the screen and repaint
+		fmt.Print("\033[H\033[2J")
+		fmt.Printf("%s via %s\n\n", host, cPName)
+		fmt.Printf("%-4s %-40s %6s %5s %8s %8s %8s %8s\n", "Hop", "Host", "Loss%", "Snt", "Last", "Avg", "Best", "Wrst")
+		for _, s := range stats {
+			ips := strings.Join(s.IPs, ", ")
+			if ips == "" {
+				ips = "???"
+			}
+			fmt.Printf("%-4d %-40s %6.1f %5d %8.1f %8.1f %8.1f %8.1f\n", s.Index, ips, s.Loss, s.Sent,
+				ms(s.Last), ms(s.Avg), ms(s.Best), ms(s.Worst))
+		}
+	}
+}
+
+// interruptContext returns a context which is canceled on interrupt so
+// the in-flight looking glass stream is torn down and the prompt is back,
+// stop should be called once the command is done
+func interruptContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(sigCh)
+		cancel()
+	}
+}
+
+// ms returns the duration in milliseconds
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// traceStructured prints the lg trace hops in json or csv format
+func traceStructured(host, format string) {
+	t, ok := providers[cPName].(lg.StructuredTracer)
+	if !ok {
+		println(format + " output doesn't support")
+		return
+	}
+	spin.Prefix = "please wait "
+	spin.Start()
+	providers[cPName].Set(host, "")
+	hops, err := t.TraceStructured()
+	if err != nil {
+		spin.Stop()
+		println(err.Error())
+		return
+	}
+	r := []lg.TraceHop{}
+	for hop := range hops {
+		r = append(r, hop)
+	}
+	spin.Stop()
+	printStructured(format, r)
+}
+
+// traceDOT prints the Graphviz DOT graph of the repeated lg traces
+func traceDOT(host string, runs int) {
+	t, ok := providers[cPName].(lg.StructuredTracer)
+	if !ok {
+		println("dot output doesn't support")
+		return
+	}
+	spin.Prefix = "please wait "
+	spin.Start()
+	providers[cPName].Set(host, "")
+	var traces [][]lg.TraceHop
+	for i := 0; i < runs; i++ {
+		hops, err := t.TraceStructured()
+		if err != nil {
+			spin.Stop()
+			println(err.Error())
+			return
+		}
+		var r []lg.TraceHop
+		for hop := range hops {
+			r = append(r, hop)
+		}
+		traces = append(traces, r)
+	}
+	spin.Stop()
+	fmt.Print(string(lg.ToDOT(traces...)))
+}
+
+// outputFormat returns the requested structured output format (-json / -csv)
+func outputFormat(flag map[string]interface{}) string {
+	switch {
+	case cli.SetFlag(flag, "json", false).(bool):
+		return "json"
+	case cli.SetFlag(flag, "csv", false).(bool):
+		return "csv"
+	}
+	return ""
+}
+
+// printStructured prints the lg results in the requested format
+func printStructured(format string, v interface{}) {
+	if format == "csv" {
+		b, err := lg.ToCSV(v)
+		if err != nil {
+			println(err.Error())
+			return
+		}
+		fmt.Print(string(b))
+		return
+	}
+	printJSON(v)
+}
+
+// printJSON prints the lg results in json format
+func printJSON(v interface{}) {
+	b, err := lg.ToJSON(v)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	fmt.Println(string(b))
+}
+
 // hping tries to ping a web server by http
 func hping() {
 	// it should work at local mode
@@ -354,20 +907,127 @@ func pingQuery() {
 
 // pingLG tries to ping through a looking glass
 func pingLG() {
+	host, flag := cli.Flag(args)
+	if !lgSupports(lg.CmdPing) || !allowTarget(host, flag) {
+		return
+	}
+	dry, err := setDryRun(flag)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	defer setRawDump(flag)()
+	defer setFailover(flag)()
+	defer setSource(flag)()
+	defer setLocalAddr(flag)()
+	defer setResolve(flag)()
+	if cli.SetFlag(flag, "dual", false).(bool) && !dry {
+		pingDual(host, outputFormat(flag))
+		return
+	}
+	if cli.SetFlag(flag, "compare", false).(bool) && !dry {
+		pingCompare(host, outputFormat(flag))
+		return
+	}
 	spin.Prefix = "please wait "
 	spin.Start()
-	providers[cPName].Set(args, "ipv4")
-	m, err := providers[cPName].Ping()
+	r, err := lg.Run(providers[cPName], lg.CmdPing, host)
 	spin.Stop()
 	if err != nil {
 		println(err.Error())
-	} else {
-		println(m)
+		return
+	}
+	if format := outputFormat(flag); format != "" && !dry {
+		if r.Stats == nil {
+			println(r.Err.Error())
+			return
+		}
+		printStructured(format, *r.Stats)
+		return
+	}
+	println(r.Text)
+	if r.Stats != nil && r.Stats.Percentiles() != "" {
+		println(r.Stats.Percentiles())
+	}
+}
+
+// pingDual pings the host over the both address families
+func pingDual(host, format string) {
+	p, ok := providers[cPName].(*lg.Cogent)
+	if !ok {
+		println("dual-stack ping doesn't support")
+		return
+	}
+	spin.Prefix = "please wait "
+	spin.Start()
+	r := p.PingBoth(host)
+	spin.Stop()
+	if format == "json" {
+		printJSON(r)
+		return
+	}
+	for _, f := range []struct {
+		name string
+		ping lg.FamilyPing
+	}{{"IPv4", r.IPv4}, {"IPv6", r.IPv6}} {
+		switch {
+		case f.ping.Note != "":
+			fmt.Printf("%s: %s\n", f.name, f.ping.Note)
+		case f.ping.Err != nil:
+			fmt.Printf("%s: %s\n", f.name, f.ping.Err)
+		default:
+			fmt.Printf("%s:\n%s\n", f.name, f.ping.Text)
+		}
+	}
+}
+
+// pingCompare pings the host through all of the looking glasses
+func pingCompare(host, format string) {
+	list := map[string]lg.LookingGlass{}
+	for _, name := range lg.Providers() {
+		if p, ok := providers[name]; ok {
+			list[name] = p
+		}
+	}
+	spin.Prefix = "please wait "
+	spin.Start()
+	results := lg.CompareProviders(lg.CmdPing, host, list)
+	spin.Stop()
+	if format == "json" {
+		stats := map[string]interface{}{}
+		for name, r := range results {
+			if r.Stats != nil {
+				stats[name] = r.Stats
+			} else if r.Err != nil {
+				stats[name] = map[string]string{"error": r.Err.Error()}
+			}
+		}
+		printJSON(stats)
+		return
+	}
+	for _, name := range lg.Providers() {
+		r, ok := results[name]
+		switch {
+		case !ok:
+		case r.Err != nil:
+			fmt.Printf("%s: %s\n", name, r.Err)
+		case r.Stats != nil:
+			st := r.Stats
+			fmt.Printf("%s: %d/%d received, %.1f%% loss, min/avg/max %v/%v/%v\n",
+				name, st.PacketsReceived, st.PacketsSent, st.LossPercent, st.Min, st.Avg, st.Max)
+		default:
+			fmt.Printf("%s:\n%s\n", name, r.Text)
+		}
 	}
 }
 
 // pingLocal tries to ping from local source ip
 func pingLocal() {
+	until, ok, err := icmp.ParseUntil(args)
+	if err != nil {
+		println(err.Error())
+		return
+	}
 	p, err := icmp.NewPing(args, cfg)
 	if err != nil {
 		println(err.Error())
@@ -375,6 +1035,13 @@ func pingLocal() {
 	if p == nil {
 		return
 	}
+	if ok && !p.IsCIDR() {
+		r := p.PrintUntil(until)
+		if noIf {
+			os.Exit(r.Status.ExitCode())
+		}
+		return
+	}
 	if !p.IsCIDR() {
 		resp := p.Run()
 		p.PrintPretty(resp)
@@ -406,18 +1073,124 @@ func scanPorts() {
 	}
 }
 
+// banner grabs the tcp service banner
+func banner() {
+	b, err := scan.NewBanner(args)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	if b == nil {
+		return
+	}
+	ctx, stop := interruptContext()
+	defer stop()
+	r, err := b.Run(ctx)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	r.PrintPretty(b.Hex())
+}
+
+// cert prints the tls certificate chain of the host
+func cert() {
+	c, err := scan.NewCert(args)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	if c == nil {
+		return
+	}
+	ctx, stop := interruptContext()
+	defer stop()
+	chain, err := c.Run(ctx)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	if c.JSON() {
+		printJSON(chain)
+		return
+	}
+	fmt.Println(chain)
+}
+
 // BGP tries to get BGP lookup from a LG
 func BGP() {
 	if cPName == "local" {
 		println("no provider selected")
 		return
 	}
-	providers[cPName].Set(args, "ipv4")
-	for l := range providers[cPName].BGP() {
+	host, flag := cli.Flag(args)
+	if !lgSupports(lg.CmdBGP) || !allowTarget(host, flag) {
+		return
+	}
+	dry, err := setDryRun(flag)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	defer setRawDump(flag)()
+	defer setLocalAddr(flag)()
+	if node, ok := flag["diff"].(string); ok && !dry {
+		bgpDiff(host, node, outputFormat(flag))
+		return
+	}
+	if format := outputFormat(flag); format != "" && !dry {
+		providers[cPName].Set(host, "")
+		b, ok := providers[cPName].(lg.BGPRouter)
+		if !ok {
+			println(format + " output doesn't support")
+			return
+		}
+		routes, err := b.BGPRoutes()
+		if err != nil {
+			println(err.Error())
+			return
+		}
+		if cli.SetFlag(flag, "communities", false).(bool) {
+			lg.DescribeCommunities(routes)
+		}
+		printStructured(format, routes)
+		return
+	}
+	ctx, stop := interruptContext()
+	defer stop()
+	r, err := lg.RunContext(ctx, providers[cPName], lg.CmdBGP, host)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	defer r.Cancel()
+	for l := range r.Lines {
 		println(l)
 	}
 }
 
+// bgpDiff compares the prefix routes from the current node and the node
+func bgpDiff(prefix, node, format string) {
+	p, ok := providers[cPName].(*lg.Cogent)
+	if !ok {
+		println("bgp diff doesn't support")
+		return
+	}
+	spin.Prefix = "please wait "
+	spin.Start()
+	c, err := p.BGPDiff(p.Node, node, prefix)
+	spin.Stop()
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	if format == "json" {
+		printJSON(c)
+		return
+	}
+	fmt.Println(c)
+}
+
 // discovery handles disc command
 func discovery() {
 	var (
@@ -455,6 +1228,262 @@ func discovery() {
 	d.PrintPretty()
 }
 
+// pmtu discovers the path mtu through the don't fragment pings
+func pmtu() {
+	p, err := icmp.NewPMTU(args)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	if p == nil {
+		return
+	}
+	spin.Prefix = "please wait "
+	spin.Start()
+	r, err := p.Run()
+	spin.Stop()
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	r.PrintPretty()
+}
+
+// check prints the quick connectivity report of the host, the
+// trace is through the looking glass in the lg mode
+func check() {
+	host, flag := cli.Flag(args)
+	if host == "" {
+		println("usage: check ip address / domain name [-json]")
+		return
+	}
+	checks := report.DefaultChecks(cfg)
+	if t, ok := providers[cPName].(lg.StructuredTracer); ok && strings.HasPrefix(prompt, "lg") {
+		checks.Trace = func(ip string) ([]report.Hop, error) {
+			providers[cPName].Set(ip, "")
+			hops, err := t.TraceStructured()
+			if err != nil {
+				return nil, err
+			}
+			var r []report.Hop
+			for h := range hops {
+				ms := float64(h.AvgRTT()) / float64(time.Millisecond)
+				r = append(r, report.Hop{Index: h.Index, IP: h.IP, Host: h.Host, RTT: ms})
+			}
+			return r, nil
+		}
+	}
+	spin.Prefix = "please wait "
+	spin.Start()
+	r := report.Run(host, checks)
+	spin.Stop()
+	if cli.SetFlag(flag, "json", false).(bool) {
+		printJSON(r)
+		return
+	}
+	fmt.Println(r)
+}
+
+// batch runs the looking glass command against the targets of the file
+// (-file) or stdin, the results are labeled by the target and the summary
+// of the succeeded and failed targets is printed at the end
+func batch() {
+	name, flag := cli.Flag(longFlags(args))
+	cmd, err := lg.ParseCommand(name)
+	if err != nil {
+		println("usage: batch ping|trace|bgp [-file path] [-w workers] [-lg provider]")
+		return
+	}
+	pName := cPName
+	if !strings.HasPrefix(prompt, "lg") {
+		pName = cli.SetFlag(flag, "lg", "cogent").(string)
+	}
+	p, ok := providers[pName]
+	if !ok {
+		println("provider not available")
+		return
+	}
+	if err := lg.CheckCommand(p, cmd); err != nil {
+		println(err.Error())
+		return
+	}
+
+	var in io.Reader = os.Stdin
+	if path := cli.SetFlag(flag, "file", "").(string); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			println(err.Error())
+			return
+		}
+		defer f.Close()
+		in = f
+	} else if !noIf {
+		println("the targets file (-file) is required at the console")
+		return
+	}
+	targets, err := lg.ReadTargets(in)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	workers, ok := cli.SetFlag(flag, "w", 0).(int)
+	if !ok {
+		println("invalid workers")
+		return
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	var s lg.BatchSummary
+	for r := range lg.Batch(ctx, p, cmd, targets, workers) {
+		s.Add(r)
+		switch {
+		case r.Err != nil:
+			fmt.Printf("%s: %s\n", r.Target, r.Err)
+		case r.Stats != nil:
+			st := r.Stats
+			fmt.Printf("%s: %d/%d received, %.1f%% loss, min/avg/max %v/%v/%v\n",
+				r.Target, st.PacketsReceived, st.PacketsSent, st.LossPercent, st.Min, st.Avg, st.Max)
+		default:
+			for _, l := range strings.Split(r.Text, "\n") {
+				fmt.Printf("%s: %s\n", r.Target, l)
+			}
+		}
+	}
+	fmt.Println(s)
+}
+
+// preset saves, runs and lists the looking glass query presets
+func preset() {
+	rest, flag := cli.Flag(longFlags(args))
+	fields := strings.Fields(rest)
+	switch {
+	case len(fields) == 0 || fields[0] == "list":
+		presets := lg.Presets()
+		if len(presets) == 0 {
+			println("no preset saved")
+		}
+		for _, ps := range presets {
+			fmt.Println(ps)
+		}
+	case fields[0] == "save" && len(fields) >= 3 && len(fields) <= 4:
+		savePreset(fields[1], fields[2], strings.Join(fields[3:], ""), flag)
+	case fields[0] == "run" && len(fields) >= 2 && len(fields) <= 3:
+		runPreset(fields[1], strings.Join(fields[2:], ""))
+	default:
+		println("usage: preset [list] | save name ping|trace|bgp [host] [-lg provider] [-node node] [-4|-6] | run name [host]")
+	}
+}
+
+// savePreset saves the query w/ the current provider, node and ip version,
+// the provider, node and ip version flags replace them
+func savePreset(name, command, host string, flag map[string]interface{}) {
+	cmd, err := lg.ParseCommand(command)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	pName := cPName
+	if !strings.HasPrefix(prompt, "lg") {
+		pName = cli.SetFlag(flag, "lg", lg.DefaultProvider()).(string)
+	}
+	p, ok := providers[pName]
+	if !ok {
+		println("provider not available")
+		return
+	}
+	if err := lg.CheckCommand(p, cmd); err != nil {
+		println(err.Error())
+		return
+	}
+	if node := fmt.Sprint(cli.SetFlag(flag, "node", "")); node != "" {
+		// the current provider node isn't changed
+		if p, err = lg.New(pName); err != nil {
+			println(err.Error())
+			return
+		}
+		p.GetNodes()
+		if !p.ChangeNode(node) {
+			println("the specified node doesn't support")
+			return
+		}
+	}
+	ps := lg.NewPreset(name, pName, p, cmd, host)
+	switch {
+	case cli.SetFlag(flag, "4", false).(bool):
+		ps.IPVersion = "ipv4"
+	case cli.SetFlag(flag, "6", false).(bool):
+		ps.IPVersion = "ipv6"
+	}
+	if err := lg.SavePreset(ps); err != nil {
+		println(err.Error())
+		return
+	}
+	fmt.Println("saved", ps)
+}
+
+// runPreset runs the preset, the host replaces the preset host
+func runPreset(name, host string) {
+	ctx, stop := interruptContext()
+	defer stop()
+	spin.Prefix = "please wait "
+	spin.Start()
+	r, err := lg.RunPreset(ctx, name, host)
+	spin.Stop()
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	if r.Lines == nil {
+		println(r.Text)
+		if r.Stats != nil && r.Stats.Percentiles() != "" {
+			println(r.Stats.Percentiles())
+		}
+		return
+	}
+	defer r.Cancel()
+	for l := range r.Lines {
+		fmt.Println(l)
+	}
+}
+
+// ifaces prints the local interfaces and the default gateways
+func ifaces() {
+	_, flag := cli.Flag(args)
+	n, err := netif.Get()
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	if cli.SetFlag(flag, "json", false).(bool) {
+		printJSON(n)
+		return
+	}
+	n.PrintPretty()
+}
+
+// dhcpDiscover handles dhcp command
+func dhcpDiscover() {
+	d, err := dhcp.New(args)
+	if d == nil || err != nil {
+		if err != nil {
+			println(err.Error())
+		}
+		return
+	}
+	spin.Prefix = "please wait "
+	spin.Start()
+	err = d.Run()
+	spin.Stop()
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	println("\nDHCP Servers Discovery")
+	d.PrintPretty()
+}
+
 // nms
 func setNMS() {
 	items := []string{"interface", "config"}
@@ -500,12 +1529,71 @@ func show() {
 
 // setLG set lg prompt and completer
 func setLG() {
-	cPName = "telia"
+	if noIf && strings.HasPrefix(args, "auto") {
+		lgAuto()
+		return
+	}
+	if noIf {
+		lgNodes()
+		return
+	}
+	cPName = lg.DefaultProvider()
 	c.UpdateCompleter("connect", pNames)
 	c.SetPrompt("lg/" + cPName + "/" + providers[cPName].GetDefaultNode())
-	go func() {
-		c.UpdateCompleter("node", providers[cPName].GetNodes())
-	}()
+	go updateNodeCompleter(providers[cPName])
+}
+
+// lgAuto prints the provider of the target origin AS w/o the
+// interface, e.g. mylg lg auto 8.8.8.8 -json
+func lgAuto() {
+	host, flag := cli.Flag(longFlags(strings.TrimPrefix(args, "auto")))
+	if host == "" {
+		println("usage: mylg lg auto host [-json]")
+		return
+	}
+	sel := lg.SelectProvider(context.Background(), host)
+	if cli.SetFlag(flag, "json", false).(bool) {
+		printJSON(sel)
+		return
+	}
+	fmt.Println(sel.Provider)
+	fmt.Println(sel.Reason)
+}
+
+// lgNodes prints the provider nodes w/o the interface,
+// e.g. mylg lg cogent nodes -json
+func lgNodes() {
+	fields := strings.Fields(longFlags(args))
+	if len(fields) < 2 || fields[1] != "nodes" {
+		println("usage: mylg lg provider nodes [-json]")
+		return
+	}
+	p, ok := providers[strings.ToLower(fields[0])]
+	if !ok {
+		println("provider not available")
+		return
+	}
+	if _, flag := cli.Flag(strings.Join(fields[2:], " ")); cli.SetFlag(flag, "json", false).(bool) {
+		printNodeCatalog(p)
+		return
+	}
+	printNodes(p)
+}
+
+// longFlags accepts the --flag form of the flags
+func longFlags(args string) string {
+	return strings.TrimSpace(strings.Replace(" "+args, " --", " -", -1))
+}
+
+// updateNodeCompleter loads the provider nodes and updates the node completer,
+// the candidates are matched on demand if the provider supports it
+func updateNodeCompleter(p lg.LookingGlass) {
+	nodes, _ := getNodes(p)
+	if nc, ok := p.(lg.NodeCompleter); ok {
+		c.UpdateDynamicCompleter("node", nc.CompletionCandidates)
+		return
+	}
+	c.UpdateCompleter("node", nodes)
 }
 
 // setNS set ns prompt and update completers
@@ -522,7 +1610,40 @@ func peeringDB() {
 
 // whoisLookup gets ANS/Prefix info
 func whoisLookup() {
-	whois.Lookup(args)
+	target, flag := cli.Flag(args)
+	if !cli.SetFlag(flag, "json", false).(bool) {
+		whois.Lookup(args)
+		return
+	}
+	var (
+		v   interface{}
+		err error
+	)
+	if ripe.IsASN(strings.TrimPrefix(strings.ToUpper(target), "AS")) {
+		v, err = ripe.LookupASN(target)
+	} else {
+		v, err = ripe.LookupPrefix(target)
+	}
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	printJSON(v)
+}
+
+// asnLookup resolves the AS name, country and announced prefixes
+func asnLookup() {
+	target, flag := cli.Flag(args)
+	info, err := lg.NewASNLookup().Lookup(target)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	if format := outputFormat(flag); format != "" {
+		printStructured(format, info)
+		return
+	}
+	fmt.Println(info)
 }
 
 // local set prompts to local
@@ -551,31 +1672,61 @@ func help() {
 		h := `
               ***** TRY IT WITHOUT ANYTHING TO HAVE INTERFACE *****
         Usage:
-              mylg [command] [args...]
+              mylg [-v|-q] [command] [args...]
 
               Available commands:
 
               ping                        ping ip address or domain name
               trace                       trace ip address or domain name (real-time w/ -r option)
+              pmtu                        discover the path MTU to ip address or domain name
+              check                       quick connectivity report: ping, trace, reverse DNS and origin AS (-json)
+              lg                          looking glass nodes (lg provider nodes), their codes and capabilities w/ -json
+                                          or the provider of the target origin AS (lg auto host)
+              batch                       run ping/trace/bgp through a looking glass against the targets of stdin or -file
+              preset                      save (preset save name ping|trace|bgp [host]), list and run (preset run name [host]) the looking glass queries
               dig                         name server looking up
               whois                       resolve AS number/IP/CIDR to holder (provides by ripe ncc)
+              asn                         resolve AS number to name, country and announced prefixes (-json/-csv)
               hping                       Ping through HTTP/HTTPS w/ GET/HEAD methods
               scan                        scan tcp ports (you can provide range >scan host minport maxport)
+              banner                      grab the tcp service banner w/ the TLS certificate details (-hex for binary)
+              cert                        inspect the TLS certificate chain, flags the expired certificates (-json)
               dump                        prints out a description of the contents of packets on a network interface
+              sniff                       prints out a summary per packet or the conversations (-conv) on a network interface
               disc                        discover all the devices on a LAN
+              dhcp                        discover the DHCP servers on a LAN
+              ifaces                      local interfaces, addresses and default gateways (-json)
               peering                     peering information (provides by peeringdb.com)
+              serve                       runs the looking glass api and /metrics (-a listen address)
               version                     shows mylg version
 
         Example:
               mylg trace freebsd.org -r
+              mylg ping 10.0.0.1 -up 3 -max 10m
               mylg whois 8.8.8.8
+              mylg asn AS174
               mylg scan 127.0.0.1
               mylg dig google.com +trace
+              mylg serve -a 127.0.0.1:8080
+              cat hosts.txt | mylg batch ping -lg cogent
+              mylg lg cogent nodes --json
+              mylg lg auto 8.8.8.8
+
+        Environment:
+              MYLG_RECORD=dir             records the looking glass responses to the dir
+              MYLG_REPLAY=dir             replays the recorded responses w/o the network
 		`
 		fmt.Println(h)
 	} else {
 		// with command line interface
 
 		c.Help()
+		if p, ok := providers[cPName]; ok && strings.HasPrefix(prompt, "lg") {
+			var cmds []string
+			for _, cmd := range p.SupportedCommands() {
+				cmds = append(cmds, cmd.String())
+			}
+			fmt.Printf("%s looking glass commands: %s\n", cPName, strings.Join(cmds, ", "))
+		}
 	}
 }
@@ -0,0 +1,199 @@
+// Package netif provides the local interfaces and default gateways
+package netif
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// Interface represents a local interface
+type Interface struct {
+	Name  string   `json:"name"`
+	MAC   string   `json:"mac"`
+	MTU   int      `json:"mtu"`
+	Up    bool     `json:"up"`
+	IPv4  []string `json:"ipv4"`
+	IPv6  []string `json:"ipv6"`
+	Flags string   `json:"flags"`
+}
+
+// Gateway represents a default gateway
+type Gateway struct {
+	Family    string `json:"family"`
+	Gateway   string `json:"gateway"`
+	Interface string `json:"interface"`
+}
+
+// Network represents the local interfaces and default gateways
+type Network struct {
+	Interfaces []Interface `json:"interfaces"`
+	Gateways   []Gateway   `json:"gateways"`
+}
+
+// Get returns the local interfaces and the default gateways,
+// the gateways are empty if they can't be found
+func Get() (Network, error) {
+	var n Network
+	ifs, err := Interfaces()
+	if err != nil {
+		return n, err
+	}
+	n.Interfaces = ifs
+	n.Gateways, _ = DefaultGateways()
+	return n, nil
+}
+
+// Interfaces returns the local interfaces w/ their addresses
+func Interfaces() ([]Interface, error) {
+	ifs, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var r []Interface
+	for _, i := range ifs {
+		iface := Interface{
+			Name:  i.Name,
+			MAC:   i.HardwareAddr.String(),
+			MTU:   i.MTU,
+			Up:    i.Flags&net.FlagUp != 0,
+			Flags: i.Flags.String(),
+		}
+		addrs, _ := i.Addrs()
+		for _, addr := range addrs {
+			ip, _, err := net.ParseCIDR(addr.String())
+			if err != nil {
+				continue
+			}
+			if ip.To4() != nil {
+				iface.IPv4 = append(iface.IPv4, addr.String())
+			} else {
+				iface.IPv6 = append(iface.IPv6, addr.String())
+			}
+		}
+		r = append(r, iface)
+	}
+	return r, nil
+}
+
+// DefaultGateways returns the IPv4 and IPv6 default gateways, they're
+// read from /proc on linux and from netstat on the other systems
+func DefaultGateways() ([]Gateway, error) {
+	if runtime.GOOS != "linux" {
+		out, err := exec.Command("netstat", "-rn").Output()
+		if err != nil {
+			return nil, err
+		}
+		return ParseNetstat(strings.NewReader(string(out))), nil
+	}
+	var gws []Gateway
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return nil, err
+	}
+	gws = append(gws, ParseProcRoute(f)...)
+	f.Close()
+	if f, err := os.Open("/proc/net/ipv6_route"); err == nil {
+		gws = append(gws, ParseProcIPv6Route(f)...)
+		f.Close()
+	}
+	return gws, nil
+}
+
+// ParseProcRoute returns the default gateways of /proc/net/route
+func ParseProcRoute(r io.Reader) []Gateway {
+	var gws []Gateway
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		f := strings.Fields(scanner.Text())
+		// Iface Destination Gateway Flags RefCnt Use Metric Mask ...
+		if len(f) < 8 || f[1] != "00000000" || f[7] != "00000000" {
+			continue
+		}
+		b, err := hex.DecodeString(f[2])
+		if err != nil || len(b) != 4 {
+			continue
+		}
+		// the address is in the host (little endian) order
+		ip := net.IPv4(b[3], b[2], b[1], b[0])
+		gws = append(gws, Gateway{Family: "ipv4", Gateway: ip.String(), Interface: f[0]})
+	}
+	return gws
+}
+
+// ParseProcIPv6Route returns the default gateways of /proc/net/ipv6_route
+func ParseProcIPv6Route(r io.Reader) []Gateway {
+	var (
+		gws  []Gateway
+		zero = strings.Repeat("0", 32)
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		f := strings.Fields(scanner.Text())
+		// dst dst_len src src_len next_hop metric refcnt use flags iface
+		if len(f) < 10 || f[0] != zero || f[1] != "00" || f[4] == zero {
+			continue
+		}
+		b, err := hex.DecodeString(f[4])
+		if err != nil || len(b) != net.IPv6len {
+			continue
+		}
+		gws = append(gws, Gateway{Family: "ipv6", Gateway: net.IP(b).String(), Interface: f[9]})
+	}
+	return gws
+}
+
+// ParseNetstat returns the default gateways of netstat -rn (BSD/macOS)
+func ParseNetstat(r io.Reader) []Gateway {
+	var gws []Gateway
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		f := strings.Fields(scanner.Text())
+		// Destination Gateway Flags Netif Expire
+		if len(f) < 4 || f[0] != "default" {
+			continue
+		}
+		// the link-local gateways have the zone e.g. fe80::1%en0
+		gw := strings.SplitN(f[1], "%", 2)[0]
+		ip := net.ParseIP(gw)
+		if ip == nil {
+			continue
+		}
+		family := "ipv6"
+		if ip.To4() != nil {
+			family = "ipv4"
+		}
+		gws = append(gws, Gateway{Family: family, Gateway: gw, Interface: f[3]})
+	}
+	return gws
+}
+
+// PrintPretty prints the interfaces and the default gateways
+func (n Network) PrintPretty() {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Name", "MAC", "Status", "MTU", "IPv4", "IPv6"})
+	for _, i := range n.Interfaces {
+		status := "DOWN"
+		if i.Up {
+			status = "UP"
+		}
+		table.Append([]string{i.Name, i.MAC, status, fmt.Sprintf("%d", i.MTU),
+			strings.Join(i.IPv4, "\n"), strings.Join(i.IPv6, "\n")})
+	}
+	table.Render()
+	if len(n.Gateways) == 0 {
+		println("default gateway not found")
+		return
+	}
+	for _, gw := range n.Gateways {
+		fmt.Printf("default gateway (%s): %s via %s\n", gw.Family, gw.Gateway, gw.Interface)
+	}
+}
@@ -0,0 +1,50 @@
+package netif_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/netif"
+)
+
+const procRoute = `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+eth0	00000000	0101A8C0	0003	0	0	100	00000000	0	0	0
+eth0	0001A8C0	00000000	0001	0	0	100	00FFFFFF	0	0	0
+`
+
+const procIPv6Route = `20010db8000000000000000000000000 20 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     eth0
+00000000000000000000000000000000 00 00000000000000000000000000000000 00 fe800000000000000000000000000001 00000400 00000001 00000000 00000003     eth0
+`
+
+const netstatRoutes = `Routing tables
+
+Internet:
+Destination        Gateway            Flags           Netif Expire
+default            192.168.1.1        UGScg             en0
+127                127.0.0.1          UCS               lo0
+
+Internet6:
+Destination                             Gateway                                 Flags           Netif Expire
+default                                 fe80::1%en0                             UGcg              en0
+`
+
+func TestDefaultGateways(t *testing.T) {
+	tests := []struct {
+		name string
+		got  []netif.Gateway
+		want []netif.Gateway
+	}{
+		{"proc", netif.ParseProcRoute(strings.NewReader(procRoute)), []netif.Gateway{{"ipv4", "192.168.1.1", "eth0"}}},
+		{"proc6", netif.ParseProcIPv6Route(strings.NewReader(procIPv6Route)), []netif.Gateway{{"ipv6", "fe80::1", "eth0"}}},
+		{"netstat", netif.ParseNetstat(strings.NewReader(netstatRoutes)), []netif.Gateway{
+			{"ipv4", "192.168.1.1", "en0"},
+			{"ipv6", "fe80::1", "en0"},
+		}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: expected %v but got %v", tt.name, tt.want, tt.got)
+		}
+	}
+}
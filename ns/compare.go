@@ -0,0 +1,158 @@
+package ns
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/olekukonko/tablewriter"
+)
+
+// DefaultResolvers are the well-known public resolvers
+// which are compared if no resolver is specified
+var DefaultResolvers = []string{
+	"8.8.8.8",        // Google
+	"1.1.1.1",        // Cloudflare
+	"9.9.9.9",        // Quad9
+	"208.67.222.222", // OpenDNS
+	"64.6.64.6",      // Verisign
+}
+
+// DigTimeout is the per resolver query timeout of DigAll
+var DigTimeout = 2 * time.Second
+
+// digWorkers is the max concurrent queries of DigAll
+const digWorkers = 4
+
+// DigAll queries the resolvers concurrently and returns each resolver's
+// sorted answer set w/o the TTLs, the failed resolvers have a single
+// ";; error" entry. the default resolvers are used if it's empty and
+// it returns an error if the qtype is unknown
+func DigAll(domain string, qtype string, resolvers []string) (map[string][]string, error) {
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+		r  = make(map[string][]string)
+		ch = make(chan string)
+	)
+
+	t, ok := dns.StringToType[strings.ToUpper(qtype)]
+	if !ok {
+		return nil, fmt.Errorf("unknown query type %q", qtype)
+	}
+	if len(resolvers) == 0 {
+		resolvers = DefaultResolvers
+	}
+
+	for i := 0; i < digWorkers && i < len(resolvers); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for resolver := range ch {
+				answers := digAnswers(domain, t, resolver)
+				mu.Lock()
+				r[resolver] = answers
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, resolver := range resolvers {
+		ch <- resolver
+	}
+	close(ch)
+	wg.Wait()
+	return r, nil
+}
+
+// digAnswers returns the sorted answer set of the resolver
+func digAnswers(domain string, t uint16, resolver string) []string {
+	c := &dns.Client{Timeout: DigTimeout}
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(domain), t)
+	m.RecursionDesired = true
+
+	r, _, err := c.Exchange(m, serverAddr(resolver))
+	if err != nil {
+		return []string{";; error: " + err.Error()}
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		return []string{";; " + dns.RcodeToString[r.Rcode]}
+	}
+	answers := []string{}
+	for _, rr := range r.Answer {
+		answers = append(answers, strings.TrimPrefix(rr.String(), rr.Header().String()))
+	}
+	sort.Strings(answers)
+	return answers
+}
+
+// Mismatches returns the resolvers which their answer
+// set is different from the most common answer set
+func Mismatches(answers map[string][]string) map[string][]string {
+	var (
+		count = make(map[string]int)
+		r     = make(map[string][]string)
+		major string
+	)
+	for _, a := range answers {
+		count[strings.Join(a, "\n")]++
+	}
+	for k, n := range count {
+		if n > count[major] || n == count[major] && k < major {
+			major = k
+		}
+	}
+	for resolver, a := range answers {
+		if strings.Join(a, "\n") != major {
+			r[resolver] = a
+		}
+	}
+	return r
+}
+
+// RunCompare queries the default resolvers (or the requested server)
+// and prints the answers, the mismatched answers are marked w/ *
+func (d *Request) RunCompare() {
+	resolvers := DefaultResolvers
+	if d.Host != "" && d.City == "" && !contains(DefaultResolvers, d.Host) {
+		resolvers = append([]string{d.Host}, DefaultResolvers...)
+	}
+	// the public resolvers may refuse the any queries
+	qtype := dns.TypeToString[d.Type]
+	if d.Type == dns.TypeANY {
+		qtype = "A"
+	}
+	answers, err := DigAll(d.Target, qtype, resolvers)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	mismatches := Mismatches(answers)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Resolver", "Answer", ""})
+	for _, resolver := range resolvers {
+		mark := ""
+		if _, ok := mismatches[resolver]; ok {
+			mark = "*"
+		}
+		table.Append([]string{resolver, strings.Join(answers[resolver], "\n"), mark})
+	}
+	table.Render()
+	if len(mismatches) > 0 {
+		fmt.Printf("%d of %d resolvers returned different answers\n", len(mismatches), len(answers))
+	}
+}
+
+func contains(s []string, v string) bool {
+	for _, i := range s {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package ns
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// DefaultDoHURL is the default RFC 8484 DNS over HTTPS endpoint
+const DefaultDoHURL = "https://cloudflare-dns.com/dns-query"
+
+var dohClient = &http.Client{Timeout: 5 * time.Second}
+
+// ExchangeDoH sends the dns message to the DNS over HTTPS endpoint
+// through POST method and returns the wire format response
+func ExchangeDoH(endpoint string, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+	q := m.Copy()
+	// RFC 8484 recommends id zero for the cache friendliness
+	q.Id = 0
+	b, err := q.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(b))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	ts := time.Now()
+	resp, err := dohClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("doh: %s returned %s", endpoint, resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	rtt := time.Since(ts)
+	r := new(dns.Msg)
+	if err := r.Unpack(body); err != nil {
+		return nil, 0, fmt.Errorf("doh: invalid response: %s", err)
+	}
+	r.Id = m.Id
+	return r, rtt, nil
+}
@@ -9,6 +9,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -24,6 +25,11 @@ const (
 	publicDNSNodesPath = "/nameservers.csv"
 )
 
+// dohURLRe matches the -doh option w/ the endpoint, the endpoint is
+// extracted before the flags since it has the characters (e.g. -)
+// which the option values can't have
+var dohURLRe = regexp.MustCompile(`(?i)\s-doh[=\s]+(https?://\S+)`)
+
 // A Host represents a name server host
 type Host struct {
 	IP      string
@@ -41,25 +47,53 @@ type Request struct {
 	Host         string
 	Hosts        []Host
 	TraceEnabled bool
+	DoH          bool
+	DoHURL       string
+	DNSSEC       bool
+	Compare      bool
+	JSON         bool
+	WatchEnabled bool
+	Interval     time.Duration
+	Count        int
 }
 
 // NewRequest creates a new dns request object
 func NewRequest() *Request {
-	return &Request{Host: ""}
+	return &Request{Host: "", DoHURL: DefaultDoHURL}
 }
 
-// SetOptions passes arguments to appropriate variable
-func (d *Request) SetOptions(args, prompt string) bool {
+// SetOptions passes arguments to appropriate variable, it returns
+// false if the help is shown or the options aren't valid
+func (d *Request) SetOptions(args, prompt string) (bool, error) {
 	d.Host = ""
 	d.TraceEnabled = false
 	d.Type = dns.TypeANY
+	d.DoHURL = DefaultDoHURL
+	if m := dohURLRe.FindStringSubmatch(" " + args); m != nil {
+		d.DoHURL = m[1]
+		args = strings.Replace(" "+args, m[0], " -doh", 1)
+	}
 
 	nArgs, flag := cli.Flag(args)
-
+	d.DoH = cli.SetFlag(flag, "doh", false).(bool)
+	d.JSON = cli.SetFlag(flag, "json", false).(bool)
+	d.DNSSEC = false
+	d.Compare = false
+	d.WatchEnabled = false
+	d.Count = cli.SetFlag(flag, "c", 0).(int)
+	switch v := cli.SetFlag(flag, "i", "0s").(type) {
+	case int:
+		d.Interval = time.Duration(v) * time.Second
+	case string:
+		var err error
+		if d.Interval, err = time.ParseDuration(v); err != nil {
+			return false, fmt.Errorf("interval options is not valid")
+		}
+	}
 	// show help
 	if _, ok := flag["help"]; ok || len(nArgs) < 1 {
 		help()
-		return false
+		return false, nil
 	}
 
 	for _, a := range strings.Fields(nArgs) {
@@ -76,9 +110,26 @@ func (d *Request) SetOptions(args, prompt string) bool {
 			d.TraceEnabled = true
 			continue
 		}
+		if a == "+dnssec" {
+			d.DNSSEC = true
+			continue
+		}
+		if a == "+compare" {
+			d.Compare = true
+			continue
+		}
+		if a == "+watch" {
+			d.WatchEnabled = true
+			continue
+		}
 		d.Target = a
 	}
 
+	// the ttl of any records is watched per type
+	if d.WatchEnabled && d.Type == dns.TypeANY {
+		d.Type = dns.TypeA
+	}
+
 	p := strings.Split(prompt, "/")
 
 	if d.Host == "" {
@@ -90,7 +141,7 @@ func (d *Request) SetOptions(args, prompt string) bool {
 			d.ChkNode(p[2])
 		}
 	}
-	return true
+	return true, nil
 }
 
 // Init configure dns command and fetch name servers
@@ -160,7 +211,13 @@ func (d *Request) Local() {
 
 // Dig looks up name server w/ trace feature
 func (d *Request) Dig() {
-	if !d.TraceEnabled {
+	if d.Compare {
+		d.RunCompare()
+	} else if d.WatchEnabled {
+		d.RunWatch()
+	} else if d.JSON {
+		d.printJSON()
+	} else if !d.TraceEnabled {
 		d.RunDig()
 	} else {
 		d.RunDigTrace()
@@ -169,51 +226,25 @@ func (d *Request) Dig() {
 
 // RunDig looks up name server
 func (d *Request) RunDig() {
-	var (
-		r   *dns.Msg
-		err error
-		rtt time.Duration
-	)
-
-	c := new(dns.Client)
-	m := new(dns.Msg)
-	m.SetQuestion(dns.Fqdn(d.Target), d.Type)
-	m.RecursionDesired = true
-	m.RecursionAvailable = true
-	c.Net = "udp"
-
-	for i := 0; i < 3; i++ {
-		fmt.Printf("Trying to query server (%s): %s %s %s\n", c.Net, d.Host, d.Country, d.City)
-		r, rtt, err = c.Exchange(m, net.JoinHostPort(d.Host, "53"))
-
-		// fall back to tcp
-		if err == dns.ErrTruncated && i == 0 {
-			c.Net = "tcp"
-		}
-		// last chance: udp + A records instead of any records
-		if err != nil && i == 1 {
-			c.Net = "udp"
-			d.Type = dns.TypeA
-			m.SetQuestion(dns.Fqdn(d.Target), d.Type)
-		}
-
-		if err != nil {
-			println(err.Error())
-			continue
-		} else {
-			break
-		}
-	}
-
+	m := d.message()
+	r, rtt, server, err := d.exchange(m)
 	if err != nil {
 		return
 	}
+	viaDoH := d.DoH && server == d.DoHURL
 
 	// Answer
 	println(r.MsgHdr.String())
 	for _, a := range r.Answer {
 		fmt.Println(a)
 	}
+	// Authority
+	if len(r.Ns) > 0 {
+		println("\n;; AUTHORITY SECTION:")
+		for _, a := range r.Ns {
+			fmt.Println(a)
+		}
+	}
 	// Extra info
 	if len(r.Extra) > 0 {
 		println("\n;; ADDITIONAL SECTION:")
@@ -221,14 +252,25 @@ func (d *Request) RunDig() {
 			fmt.Println(a)
 		}
 	}
+	if d.DNSSEC {
+		if r.AuthenticatedData {
+			println("\n;; DNSSEC: authenticated data (ad) flag is set")
+		} else {
+			println("\n;; DNSSEC: authenticated data (ad) flag isn't set")
+		}
+	}
 	fmt.Printf(";; Query time: %d ms\n", rtt/1e6)
 
 	// CHAOS
+	if viaDoH {
+		return
+	}
+	c := new(dns.Client)
 	c.Timeout = ((rtt / 1e6) + 100) * time.Millisecond
 	fmt.Printf("\n;; CHAOS CLASS BIND\n")
 	for _, q := range []string{"version.bind.", "hostname.bind."} {
 		m.Question[0] = dns.Question{q, dns.TypeTXT, dns.ClassCHAOS}
-		r, _, err = c.Exchange(m, d.Host+":53")
+		r, _, err = c.Exchange(m, d.server())
 		if err != nil {
 			continue
 		}
@@ -394,11 +436,23 @@ func help() {
           dig [@local-server] host [options]
     options:
           +trace
+          +dnssec requests the DNSSEC records (RRSIG) and shows the ad flag
+          +compare queries the public resolvers and marks the mismatched answers
+          +watch  queries the record every interval (-i, default 5s) and shows the ttl,
+                  the changed and the refreshed (ttl reset) answers, -c stops after count queries
+          -json   prints the answer, authority and additional sections in json format
+          -doh [url] query through DNS over HTTPS (default ` + DefaultDoHURL + `)
     Example:
           dig google.com
           dig @8.8.8.8 yahoo.com
           dig google.com +trace
           dig google.com MX
+          dig google.com AAAA -doh
+          dig google.com AAAA -doh https://dns.google/dns-query
+          dig @8.8.8.8 cloudflare.com A +dnssec
+          dig google.com MX -json
+          dig google.com A +compare
+          dig @8.8.8.8 google.com A +watch -i 2s -c 30
 	`)
 
 }
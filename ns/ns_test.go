@@ -1,7 +1,18 @@
 package ns_test
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/miekg/dns"
 
 	"github.com/mehrdadrad/mylg/ns"
 )
@@ -40,3 +51,188 @@ func TestChkNode(t *testing.T) {
 		t.Error("ChkNode didn't return expected value")
 	}
 }
+
+func TestExchangeDoH(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/dns-message" {
+			t.Error("unexpected content type", r.Header.Get("Content-Type"))
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		q := new(dns.Msg)
+		if err := q.Unpack(b); err != nil {
+			t.Error(err)
+			return
+		}
+		m := new(dns.Msg)
+		m.SetReply(q)
+		rr, _ := dns.NewRR("example.com. 300 IN A 192.0.2.1")
+		m.Answer = append(m.Answer, rr)
+		b, _ = m.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(b)
+	}))
+	defer ts.Close()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+	r, _, err := ns.ExchangeDoH(ts.URL, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Id != m.Id || len(r.Answer) != 1 || r.Answer[0].(*dns.A).A.String() != "192.0.2.1" {
+		t.Error("unexpected DoH response", r)
+	}
+
+	ts.Close()
+	if _, _, err := ns.ExchangeDoH(ts.URL, m); err == nil {
+		t.Error("expected error for unreachable DoH endpoint")
+	}
+}
+
+func TestQueryDNSSEC(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		q := new(dns.Msg)
+		if err := q.Unpack(b); err != nil {
+			t.Error(err)
+			return
+		}
+		m := new(dns.Msg)
+		m.SetReply(q)
+		if opt := q.IsEdns0(); opt != nil && opt.Do() {
+			m.AuthenticatedData = true
+			sig, _ := dns.NewRR("example.com. 300 IN RRSIG MX 13 2 300 20300101000000 20200101000000 34505 example.com. dGVzdA==")
+			m.Answer = append(m.Answer, sig)
+		}
+		mx, _ := dns.NewRR("example.com. 300 IN MX 10 mail.example.com.")
+		soa, _ := dns.NewRR("example.com. 3600 IN NS ns1.example.com.")
+		glue, _ := dns.NewRR("ns1.example.com. 60 IN A 192.0.2.53")
+		m.Answer = append([]dns.RR{mx}, m.Answer...)
+		m.Ns = append(m.Ns, soa)
+		m.Extra = append(m.Extra, glue)
+		b, _ = m.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(b)
+	}))
+	defer ts.Close()
+
+	req := ns.NewRequest()
+	if ok, err := req.SetOptions("example.com MX +dnssec -doh "+ts.URL+" -json", "local"); !ok || err != nil {
+		t.Fatal("SetOptions failed", err)
+	}
+	if _, err := ns.NewRequest().SetOptions("example.com +watch -i 2x", "local"); err == nil {
+		t.Error("expected the invalid interval error")
+	}
+	if !req.DNSSEC || !req.JSON || !req.DoH || req.DoHURL != ts.URL || req.Type != dns.TypeMX {
+		t.Fatalf("unexpected options %+v", req)
+	}
+	res, err := req.Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.AD || len(res.Answer) != 2 || res.Answer[1].Type != "RRSIG" {
+		t.Errorf("expected the ad flag and the rrsig record but got %+v", res)
+	}
+	if res.Answer[0].TTL != 300 || res.Answer[0].Data != "10 mail.example.com." {
+		t.Errorf("unexpected answer %+v", res.Answer[0])
+	}
+	if len(res.Authority) != 1 || len(res.Additional) != 1 || res.Additional[0].TTL != 60 {
+		t.Errorf("unexpected authority / additional sections %+v", res)
+	}
+}
+
+// dnsServer runs a udp name server which answers w/ the A record
+func dnsServer(t *testing.T, ip string) (string, func()) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, q *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(q)
+		rr, _ := dns.NewRR(q.Question[0].Name + " 300 IN A " + ip)
+		m.Answer = append(m.Answer, rr)
+		w.WriteMsg(m)
+	})}
+	go s.ActivateAndServe()
+	return pc.LocalAddr().String(), func() { s.Shutdown() }
+}
+
+func TestDigAll(t *testing.T) {
+	a, closeA := dnsServer(t, "192.0.2.1")
+	defer closeA()
+	b, closeB := dnsServer(t, "192.0.2.1")
+	defer closeB()
+	c, closeC := dnsServer(t, "198.51.100.1")
+	defer closeC()
+	// the slow server never answers
+	slow, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	defer slow.Close()
+
+	ns.DigTimeout = 200 * time.Millisecond
+	start := time.Now()
+	r, err := ns.DigAll("example.com", "A", []string{a, b, c, slow.LocalAddr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("expected the slow server doesn't stall the comparison")
+	}
+	if !reflect.DeepEqual(r[a], []string{"192.0.2.1"}) || !reflect.DeepEqual(r[c], []string{"198.51.100.1"}) {
+		t.Errorf("unexpected answers %q", r)
+	}
+	m := ns.Mismatches(r)
+	if _, ok := m[c]; !ok || len(m) != 2 {
+		t.Errorf("expected %s and the slow server mismatches but got %q", c, m)
+	}
+	if _, err := ns.DigAll("example.com", "AAAAA", []string{a}); err == nil {
+		t.Error("expected unknown query type error")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		ttl = []uint32{300, 298, 600}
+		n   int
+	)
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, q *dns.Msg) {
+		mu.Lock()
+		rr, _ := dns.NewRR(fmt.Sprintf("%s %d IN A 192.0.2.%d", q.Question[0].Name, ttl[n%3], 1+n/2))
+		n++
+		mu.Unlock()
+		m := new(dns.Msg)
+		m.SetReply(q)
+		m.Answer = append(m.Answer, rr)
+		w.WriteMsg(m)
+	})}
+	go s.ActivateAndServe()
+	defer s.Shutdown()
+
+	d := &ns.Request{Target: "example.com", Type: dns.TypeA, Host: pc.LocalAddr().String(), Interval: 10 * time.Millisecond, Count: 3}
+	var obs []ns.Observation
+	for o := range d.Watch(context.Background()) {
+		obs = append(obs, o)
+	}
+	if len(obs) != 3 {
+		t.Fatal("expected 3 observations but got", obs)
+	}
+	if obs[0].TTL != 300 || obs[1].TTL != 298 || obs[1].Refreshed || obs[1].Changed {
+		t.Errorf("unexpected observations %+v", obs[:2])
+	}
+	if !obs[2].Refreshed || !obs[2].Changed || obs[2].Answers[0] != "192.0.2.2" {
+		t.Errorf("expected the refreshed and changed answer but got %+v", obs[2])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.Count = 0
+	c := d.Watch(ctx)
+	<-c
+	cancel()
+	for range c {
+	}
+}
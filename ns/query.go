@@ -0,0 +1,168 @@
+package ns
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// A Record represents a resource record
+type Record struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Class string `json:"class"`
+	TTL   uint32 `json:"ttl"`
+	Data  string `json:"data"`
+}
+
+// A Result represents a dig query result
+type Result struct {
+	Question   string   `json:"question"`
+	Type       string   `json:"type"`
+	Server     string   `json:"server"`
+	Rcode      string   `json:"rcode"`
+	AD         bool     `json:"ad"`
+	RTT        float64  `json:"rtt_ms"`
+	Answer     []Record `json:"answer"`
+	Authority  []Record `json:"authority"`
+	Additional []Record `json:"additional"`
+}
+
+// Query looks up the target and returns the result w/ all of the sections
+func (d *Request) Query() (Result, error) {
+	r, rtt, server, err := d.exchange(d.message())
+	if err != nil {
+		return Result{}, err
+	}
+	return newResult(d.Target, d.Type, server, r, rtt), nil
+}
+
+// message returns the query message, the DO bit is set if DNSSEC is enabled
+func (d *Request) message() *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(d.Target), d.Type)
+	m.RecursionDesired = true
+	m.RecursionAvailable = true
+	if d.DNSSEC {
+		m.SetEdns0(4096, true)
+		m.AuthenticatedData = true
+	}
+	return m
+}
+
+// server returns the name server address, the default port is 53
+func (d *Request) server() string {
+	return serverAddr(d.Host)
+}
+
+// serverAddr appends the default port to the host if it doesn't have
+func serverAddr(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, "53")
+}
+
+// exchange sends the message through DoH (if it's enabled) or udp and
+// falls back to tcp / A records, it returns the answered server as well
+func (d *Request) exchange(m *dns.Msg) (*dns.Msg, time.Duration, string, error) {
+	var (
+		r   *dns.Msg
+		err error
+		rtt time.Duration
+	)
+
+	if d.DoH {
+		d.logf("Trying to query server (doh): %s\n", d.DoHURL)
+		r, rtt, err = ExchangeDoH(d.DoHURL, m)
+		if err == nil {
+			return r, rtt, d.DoHURL, nil
+		}
+		d.logf("%s - falling back to udp\n", err)
+	}
+
+	c := new(dns.Client)
+	c.Net = "udp"
+	for i := 0; i < 3; i++ {
+		d.logf("Trying to query server (%s): %s %s %s\n", c.Net, d.Host, d.Country, d.City)
+		r, rtt, err = c.Exchange(m, d.server())
+
+		// fall back to tcp
+		if err == dns.ErrTruncated && i == 0 {
+			c.Net = "tcp"
+		}
+		// last chance: udp + A records instead of any records
+		if err != nil && i == 1 {
+			c.Net = "udp"
+			d.Type = dns.TypeA
+			m.SetQuestion(dns.Fqdn(d.Target), d.Type)
+		}
+
+		if err != nil {
+			d.logf("%s\n", err)
+			continue
+		}
+		break
+	}
+	return r, rtt, d.server(), err
+}
+
+// logf prints the query progress unless the json output is enabled
+func (d *Request) logf(format string, a ...interface{}) {
+	if !d.JSON {
+		fmt.Printf(format, a...)
+	}
+}
+
+// printJSON prints the query result in json format
+func (d *Request) printJSON() {
+	r, err := d.Query()
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	fmt.Println(string(b))
+}
+
+// newResult returns the result of the response message
+func newResult(target string, qtype uint16, server string, r *dns.Msg, rtt time.Duration) Result {
+	return Result{
+		Question:   dns.Fqdn(target),
+		Type:       dns.TypeToString[qtype],
+		Server:     server,
+		Rcode:      dns.RcodeToString[r.Rcode],
+		AD:         r.AuthenticatedData,
+		RTT:        float64(rtt) / float64(time.Millisecond),
+		Answer:     records(r.Answer),
+		Authority:  records(r.Ns),
+		Additional: records(r.Extra),
+	}
+}
+
+// records converts the resource records, the OPT pseudo records are skipped
+func records(rrs []dns.RR) []Record {
+	r := []Record{}
+	for _, rr := range rrs {
+		h := rr.Header()
+		if h.Rrtype == dns.TypeOPT {
+			continue
+		}
+		r = append(r, Record{
+			Name:  h.Name,
+			Type:  dns.TypeToString[h.Rrtype],
+			Class: dns.ClassToString[h.Class],
+			TTL:   h.Ttl,
+			Data:  strings.TrimPrefix(rr.String(), h.String()),
+		})
+	}
+	return r
+}
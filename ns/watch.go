@@ -0,0 +1,141 @@
+package ns
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// DefaultWatchInterval is the watch queries interval if it's not set
+const DefaultWatchInterval = 5 * time.Second
+
+// An Observation represents a lookup of the watched record, TTL is the
+// lowest answer ttl. Changed is set once the answers differ from the last
+// observation and Refreshed once the ttl is reset (a resolver cache miss)
+type Observation struct {
+	Time      time.Time
+	TTL       uint32
+	Answers   []string
+	Changed   bool
+	Refreshed bool
+	Err       error
+}
+
+// String returns the observation in one line
+func (o Observation) String() string {
+	t := o.Time.Format("15:04:05")
+	if o.Err != nil {
+		return fmt.Sprintf("%s error: %s", t, o.Err)
+	}
+	s := fmt.Sprintf("%s ttl %-6d %s", t, o.TTL, strings.Join(o.Answers, ", "))
+	switch {
+	case o.Changed:
+		s += " [changed]"
+	case o.Refreshed:
+		s += " [refreshed]"
+	}
+	return s
+}
+
+// Watch queries the record every Interval and streams the observations,
+// it stops after Count observations (zero is unlimited) or once ctx is
+// canceled, the channel is closed then
+func (d *Request) Watch(ctx context.Context) <-chan Observation {
+	var (
+		c        = make(chan Observation)
+		interval = d.Interval
+	)
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	go func() {
+		defer close(c)
+		var (
+			ticker = time.NewTicker(interval)
+			last   *Observation
+		)
+		defer ticker.Stop()
+		for n := 0; d.Count == 0 || n < d.Count; n++ {
+			o := d.observe()
+			if last != nil && o.Err == nil && last.Err == nil {
+				o.Changed = strings.Join(o.Answers, " ") != strings.Join(last.Answers, " ")
+				o.Refreshed = o.TTL > last.TTL
+			}
+			if o.Err == nil {
+				last = &o
+			}
+			select {
+			case c <- o:
+			case <-ctx.Done():
+				return
+			}
+			if d.Count != 0 && n+1 == d.Count {
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// observe looks up the record once
+func (d *Request) observe() Observation {
+	var (
+		o   = Observation{Time: time.Now()}
+		r   *dns.Msg
+		err error
+	)
+	if d.DoH {
+		r, _, err = ExchangeDoH(d.DoHURL, d.message())
+	} else {
+		c := &dns.Client{Timeout: DigTimeout}
+		r, _, err = c.Exchange(d.message(), d.server())
+	}
+	if err != nil {
+		o.Err = err
+		return o
+	}
+	for i, rr := range r.Answer {
+		h := rr.Header()
+		if i == 0 || h.Ttl < o.TTL {
+			o.TTL = h.Ttl
+		}
+		o.Answers = append(o.Answers, strings.TrimSpace(strings.TrimPrefix(rr.String(), h.String())))
+	}
+	if len(o.Answers) == 0 {
+		o.Err = fmt.Errorf("no answer (%s)", dns.RcodeToString[r.Rcode])
+	}
+	sort.Strings(o.Answers)
+	return o
+}
+
+// RunWatch prints the observations until it's interrupted
+func (d *Request) RunWatch() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+	go func() {
+		select {
+		case <-sig:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	fmt.Printf("watching %s %s @%s\n", dns.Fqdn(d.Target), dns.TypeToString[d.Type], d.Host)
+	for o := range d.Watch(ctx) {
+		fmt.Println(o)
+	}
+}
@@ -0,0 +1,284 @@
+// Package report provides the quick connectivity overview of a host
+package report
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/icmp"
+	"github.com/mehrdadrad/mylg/ripe"
+)
+
+// the local ping and trace limits, the overview should be quick
+const (
+	pingCount   = 3
+	traceMaxTTL = 15
+)
+
+// PingSummary represents the ping statistics, the rtts are in milliseconds
+type PingSummary struct {
+	Sent     int     `json:"sent"`
+	Received int     `json:"received"`
+	Loss     float64 `json:"loss_percent"`
+	Min      float64 `json:"min_ms"`
+	Avg      float64 `json:"avg_ms"`
+	Max      float64 `json:"max_ms"`
+
+	// the percentiles are nil w/o any reply and the jitter needs two replies
+	P50    *float64 `json:"p50_ms,omitempty"`
+	P90    *float64 `json:"p90_ms,omitempty"`
+	P99    *float64 `json:"p99_ms,omitempty"`
+	Jitter *float64 `json:"jitter_ms,omitempty"`
+}
+
+// Hop represents a trace hop, IP is empty once the hop is timed out
+type Hop struct {
+	Index int     `json:"index"`
+	IP    string  `json:"ip,omitempty"`
+	Host  string  `json:"host,omitempty"`
+	RTT   float64 `json:"rtt_ms"`
+}
+
+// HostReport represents the consolidated results of the checks, a
+// failed check leaves its result empty and its error is in Errors
+type HostReport struct {
+	Target string            `json:"target"`
+	IP     string            `json:"ip,omitempty"`
+	Ping   *PingSummary      `json:"ping,omitempty"`
+	Trace  []Hop             `json:"trace,omitempty"`
+	PTR    string            `json:"ptr,omitempty"`
+	Origin *ripe.RIPEPrefix  `json:"origin,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// Checks are the lookups of the report by the target address,
+// the nil ones are skipped
+type Checks struct {
+	Ping   func(ip string) (PingSummary, error)
+	Trace  func(ip string) ([]Hop, error)
+	PTR    func(ip string) (string, error)
+	Origin func(ip string) (ripe.RIPEPrefix, error)
+}
+
+// DefaultChecks returns the local ping and trace, the reverse
+// dns and the RIPEstat prefix origin lookups
+func DefaultChecks(cfg cli.Config) Checks {
+	return Checks{
+		Ping:   func(ip string) (PingSummary, error) { return ping(ip, cfg) },
+		Trace:  func(ip string) ([]Hop, error) { return trace(ip, cfg) },
+		PTR:    ptr,
+		Origin: ripe.LookupPrefix,
+	}
+}
+
+// Run resolves the target and runs the checks concurrently
+func Run(target string, c Checks) HostReport {
+	r := HostReport{Target: target}
+	ip, err := resolve(target)
+	if err != nil {
+		r.Errors = map[string]string{"resolve": err.Error()}
+		return r
+	}
+	r.IP = ip
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	check := func(name string, f func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f(); err != nil {
+				mu.Lock()
+				if r.Errors == nil {
+					r.Errors = map[string]string{}
+				}
+				r.Errors[name] = err.Error()
+				mu.Unlock()
+			}
+		}()
+	}
+	if c.Ping != nil {
+		check("ping", func() error {
+			s, err := c.Ping(ip)
+			if err == nil {
+				r.Ping = &s
+			}
+			return err
+		})
+	}
+	if c.Trace != nil {
+		check("trace", func() (err error) {
+			r.Trace, err = c.Trace(ip)
+			return err
+		})
+	}
+	if c.PTR != nil {
+		check("ptr", func() (err error) {
+			r.PTR, err = c.PTR(ip)
+			return err
+		})
+	}
+	if c.Origin != nil {
+		check("origin", func() error {
+			o, err := c.Origin(ip)
+			if err == nil {
+				r.Origin = &o
+			}
+			return err
+		})
+	}
+	wg.Wait()
+	return r
+}
+
+// resolve returns the target address, IPv4 is preferred
+func resolve(target string) (string, error) {
+	if ip := net.ParseIP(target); ip != nil {
+		return ip.String(), nil
+	}
+	ips, err := net.LookupIP(target)
+	if err != nil {
+		return "", err
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip.String(), nil
+		}
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("%s has no address", target)
+	}
+	return ips[0].String(), nil
+}
+
+// ping pings the address locally
+func ping(ip string, cfg cli.Config) (PingSummary, error) {
+	var s PingSummary
+	p, err := icmp.NewPing(fmt.Sprintf("%s -c %d", ip, pingCount), cfg)
+	if err != nil {
+		return s, err
+	}
+	var (
+		lastErr error
+		rtts    []float64
+	)
+	for resp := range p.Run() {
+		s.Sent++
+		if resp.Error != nil {
+			lastErr = resp.Error
+			continue
+		}
+		if s.Received == 0 || resp.RTT < s.Min {
+			s.Min = resp.RTT
+		}
+		if resp.RTT > s.Max {
+			s.Max = resp.RTT
+		}
+		s.Avg += resp.RTT
+		s.Received++
+		rtts = append(rtts, resp.RTT)
+	}
+	if s.Received == 0 && lastErr != nil {
+		return s, lastErr
+	}
+	if s.Received > 0 {
+		s.Avg /= float64(s.Received)
+		p50, p90, p99 := icmp.Percentile(rtts, 50), icmp.Percentile(rtts, 90), icmp.Percentile(rtts, 99)
+		s.P50, s.P90, s.P99 = &p50, &p90, &p99
+	}
+	if s.Received > 1 {
+		j := icmp.Jitter(rtts)
+		s.Jitter = &j
+	}
+	if s.Sent > 0 {
+		s.Loss = float64(s.Sent-s.Received) * 100 / float64(s.Sent)
+	}
+	return s, nil
+}
+
+// trace traces the address locally up to traceMaxTTL hops
+func trace(ip string, cfg cli.Config) ([]Hop, error) {
+	t, err := icmp.NewTrace(fmt.Sprintf("%s -m %d", ip, traceMaxTTL), cfg)
+	if err != nil {
+		return nil, err
+	}
+	c, err := t.Run(1)
+	if err != nil {
+		return nil, err
+	}
+	var hops []Hop
+	for resp := range c {
+		for _, h := range resp {
+			if h.Err() != nil {
+				return hops, h.Err()
+			}
+			hops = append(hops, Hop{Index: h.Num(), IP: h.IP(), Host: h.Host(), RTT: h.Elapsed()})
+		}
+	}
+	return hops, nil
+}
+
+// ptr returns the reverse dns name of the address
+func ptr(ip string) (string, error) {
+	names, err := net.LookupAddr(ip)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(names[0], "."), nil
+}
+
+// String returns the summary of the report
+func (r HostReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s", r.Target)
+	if r.IP != "" && r.IP != r.Target {
+		fmt.Fprintf(&b, " (%s)", r.IP)
+	}
+	b.WriteString("\n")
+	line := func(name, v string) {
+		if err, ok := r.Errors[name]; ok {
+			v = "error: " + err
+		}
+		if v != "" {
+			fmt.Fprintf(&b, "  %-7s %s\n", name, v)
+		}
+	}
+	var pingLine, originLine, traceLine string
+	if p := r.Ping; p != nil {
+		pingLine = fmt.Sprintf("%d/%d received, %.0f%% loss, min/avg/max %.3f/%.3f/%.3f ms",
+			p.Received, p.Sent, p.Loss, p.Min, p.Avg, p.Max)
+		if p.P50 != nil {
+			pingLine += fmt.Sprintf(", p50/p90/p99 %.3f/%.3f/%.3f ms", *p.P50, *p.P90, *p.P99)
+		}
+		if p.Jitter != nil {
+			pingLine += fmt.Sprintf(", jitter %.3f ms", *p.Jitter)
+		}
+	}
+	if r.Origin != nil {
+		originLine = r.Origin.String()
+	}
+	if len(r.Trace) > 0 {
+		traceLine = fmt.Sprintf("%d hops", len(r.Trace))
+	}
+	line("resolve", "")
+	line("ping", pingLine)
+	line("ptr", r.PTR)
+	line("origin", originLine)
+	line("trace", traceLine)
+	for _, h := range r.Trace {
+		switch {
+		case h.IP == "":
+			fmt.Fprintf(&b, "  %7d *\n", h.Index)
+		case h.Host != "" && h.Host != h.IP:
+			fmt.Fprintf(&b, "  %7d %s (%s) %.3f ms\n", h.Index, h.Host, h.IP, h.RTT)
+		default:
+			fmt.Fprintf(&b, "  %7d %s %.3f ms\n", h.Index, h.IP, h.RTT)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,58 @@
+package report_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/report"
+	"github.com/mehrdadrad/mylg/ripe"
+)
+
+func TestRun(t *testing.T) {
+	var targets []string
+	c := report.Checks{
+		Ping: func(ip string) (report.PingSummary, error) {
+			p50, p99, jitter := 2.0, 3.0, 1.0
+			return report.PingSummary{Sent: 3, Received: 3, Min: 1, Avg: 2, Max: 3, P50: &p50, P90: &p99, P99: &p99, Jitter: &jitter}, nil
+		},
+		Trace: func(ip string) ([]report.Hop, error) {
+			return nil, errors.New("raw socket isn't permitted")
+		},
+		PTR: func(ip string) (string, error) {
+			targets = append(targets, ip)
+			return "dns.google", nil
+		},
+		Origin: func(ip string) (ripe.RIPEPrefix, error) {
+			return ripe.RIPEPrefix{Prefix: "8.8.8.0/24", Announced: true, Origins: []ripe.Holder{{ASN: 15169, Holder: "GOOGLE"}}}, nil
+		},
+	}
+	r := report.Run("8.8.8.8", c)
+	if r.IP != "8.8.8.8" || r.Ping == nil || r.Ping.Received != 3 || r.PTR != "dns.google" || r.Origin == nil {
+		t.Errorf("unexpected report %+v", r)
+	}
+	if len(r.Errors) != 1 || r.Errors["trace"] != "raw socket isn't permitted" {
+		t.Error("expected the trace error only but got", r.Errors)
+	}
+	s := r.String()
+	for _, want := range []string{"ping    3/3 received, 0% loss", "p50/p90/p99 2.000/3.000/3.000 ms, jitter 1.000 ms", "trace   error: raw socket", "origin  8.8.8.0/24 originated by AS15169 GOOGLE"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("expected %q in %s", want, s)
+		}
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"errors":{"trace":"raw socket isn't permitted"}`) || !strings.Contains(string(b), `"p50_ms":2`) || strings.Contains(string(b), `"trace":[`) {
+		t.Error("unexpected json", string(b))
+	}
+}
+
+func TestRunResolveError(t *testing.T) {
+	r := report.Run("invalid..host", report.Checks{})
+	if r.Errors["resolve"] == "" || !strings.Contains(r.String(), "resolve error:") {
+		t.Errorf("expected resolve error but got %+v", r)
+	}
+}
@@ -0,0 +1,240 @@
+package ripe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RIPEAnnouncedURL holds RIPE announced prefixes path
+const RIPEAnnouncedURL = "/data/announced-prefixes/data.json?resource=AS"
+
+// ErrRateLimited is returned once RIPEstat keeps rejecting
+// the requests due to the rate limit
+var ErrRateLimited = errors.New("ripestat rate limit exceeded, try again later")
+
+// CacheTTL is the lifetime of the cached RIPEstat responses
+var CacheTTL = 5 * time.Minute
+
+// rateLimitRetries is the max retries once RIPEstat returns 429
+const rateLimitRetries = 2
+
+// maxRetryAfter caps the wait time which is requested by RIPEstat
+const maxRetryAfter = 5 * time.Second
+
+// lookupClient is used for the RIPEstat lookups, the default
+// transport is resolved per request so it can be replaced
+var lookupClient = &http.Client{Timeout: 10 * time.Second}
+
+// Holder represents an origin AS and its holder name
+type Holder struct {
+	ASN    int    `json:"asn"`
+	Holder string `json:"holder"`
+}
+
+// RIPEPrefix represents the routing information of a prefix, the
+// prefix isn't in the routing table if it's not announced
+type RIPEPrefix struct {
+	Prefix    string   `json:"prefix"`
+	Announced bool     `json:"announced"`
+	Origins   []Holder `json:"origins"`
+}
+
+// RIPEASN represents the holder and the announced prefixes of an AS
+type RIPEASN struct {
+	ASN       int      `json:"asn"`
+	Holder    string   `json:"holder"`
+	Announced bool     `json:"announced"`
+	Prefixes  []string `json:"prefixes"`
+}
+
+// String returns the prefix information in human readable form
+func (p RIPEPrefix) String() string {
+	if !p.Announced {
+		return fmt.Sprintf("%s is not announced in the routing table", p.Prefix)
+	}
+	var origins []string
+	for _, o := range p.Origins {
+		origins = append(origins, fmt.Sprintf("AS%d %s", o.ASN, o.Holder))
+	}
+	return fmt.Sprintf("%s originated by %s", p.Prefix, strings.Join(origins, ", "))
+}
+
+// String returns the AS information in human readable form
+func (a RIPEASN) String() string {
+	if !a.Announced {
+		return fmt.Sprintf("AS%d %s doesn't announce any prefix", a.ASN, a.Holder)
+	}
+	return fmt.Sprintf("AS%d %s announces %d prefix(es)", a.ASN, a.Holder, len(a.Prefixes))
+}
+
+// cacheEntry represents a cached RIPEstat response
+type cacheEntry struct {
+	expire time.Time
+	body   []byte
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]cacheEntry{}
+)
+
+// LookupPrefix returns the origin ASNs and their holders of the prefix,
+// it's not named Prefix since that's the type of the ripe command
+func LookupPrefix(prefix string) (RIPEPrefix, error) {
+	return LookupPrefixContext(context.Background(), prefix)
+}
+
+// LookupPrefixContext is like LookupPrefix but the requests are bound to ctx
+func LookupPrefixContext(ctx context.Context, prefix string) (RIPEPrefix, error) {
+	var (
+		r RIPEPrefix
+		d struct {
+			Data struct {
+				Resource  string
+				Announced bool
+				ASNs      []Holder
+			}
+		}
+	)
+	if !IsIP(prefix) && !IsPrefix(prefix) {
+		return r, fmt.Errorf("invalid prefix: %s", prefix)
+	}
+	if err := getJSON(ctx, RIPEAPI+RIPEPrefixURL+prefix, &d); err != nil {
+		return r, err
+	}
+	r.Prefix = prefix
+	if d.Data.Resource != "" {
+		r.Prefix = d.Data.Resource
+	}
+	r.Announced = d.Data.Announced && len(d.Data.ASNs) > 0
+	r.Origins = d.Data.ASNs
+	return r, nil
+}
+
+// LookupASN returns the holder and the announced prefixes of the AS,
+// it's not named ASN since that's the type of the ripe command
+func LookupASN(asn string) (RIPEASN, error) {
+	return LookupASNContext(context.Background(), asn)
+}
+
+// LookupASNContext is like LookupASN but the requests are bound to ctx
+func LookupASNContext(ctx context.Context, asn string) (RIPEASN, error) {
+	var (
+		r  RIPEASN
+		ov struct {
+			Data struct {
+				Holder    string
+				Announced bool
+			}
+		}
+		ap struct {
+			Data struct {
+				Prefixes []struct {
+					Prefix string
+				}
+			}
+		}
+	)
+	asn = strings.TrimPrefix(strings.ToUpper(asn), "AS")
+	if !IsASN(asn) {
+		return r, fmt.Errorf("invalid AS number: %s", asn)
+	}
+	r.ASN, _ = strconv.Atoi(asn)
+	if err := getJSON(ctx, RIPEAPI+RIPEASNURL+asn, &ov); err != nil {
+		return r, err
+	}
+	if err := getJSON(ctx, RIPEAPI+RIPEAnnouncedURL+asn, &ap); err != nil {
+		return r, err
+	}
+	r.Holder = ov.Data.Holder
+	for _, p := range ap.Data.Prefixes {
+		r.Prefixes = append(r.Prefixes, p.Prefix)
+	}
+	r.Announced = len(r.Prefixes) > 0
+	return r, nil
+}
+
+// getJSON decodes the RIPEstat response of the url to v, the responses
+// are cached and the request is retried if it's rate limited
+func getJSON(ctx context.Context, url string, v interface{}) error {
+	b, err := fetch(ctx, url)
+	if err != nil {
+		return err
+	}
+	var status struct {
+		Status  string
+		Message string
+	}
+	if err = json.Unmarshal(b, &status); err != nil {
+		return fmt.Errorf("ripestat: %v", err)
+	}
+	if status.Status != "" && status.Status != "ok" {
+		return fmt.Errorf("ripestat: %s %s", status.Status, status.Message)
+	}
+	return json.Unmarshal(b, v)
+}
+
+// fetch returns the response body from the cache or RIPEstat
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	cacheMu.Lock()
+	e, ok := cache[url]
+	cacheMu.Unlock()
+	if ok && time.Now().Before(e.expire) {
+		return e.body, nil
+	}
+
+	for i := 0; ; i++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := lookupClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			resp.Body.Close()
+			if i == rateLimitRetries {
+				return nil, ErrRateLimited
+			}
+			select {
+			case <-time.After(retryAfter(resp.Header.Get("Retry-After"), i)):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("ripestat: HTTP code: %d returned", resp.StatusCode)
+		}
+		cacheMu.Lock()
+		cache[url] = cacheEntry{time.Now().Add(CacheTTL), body}
+		cacheMu.Unlock()
+		return body, nil
+	}
+}
+
+// retryAfter returns the wait time before the next try
+func retryAfter(header string, try int) time.Duration {
+	d := time.Duration(try+1) * time.Second
+	if s, err := strconv.Atoi(header); err == nil && s >= 0 {
+		d = time.Duration(s) * time.Second
+	}
+	if d > maxRetryAfter {
+		d = maxRetryAfter
+	}
+	return d
+}
@@ -1,9 +1,11 @@
 package ripe_test
 
 import (
+	"context"
 	"github.com/mehrdadrad/mylg/ripe"
 	"gopkg.in/h2non/gock.v0"
 	"testing"
+	"time"
 )
 
 func TestRipePrefixAPISCode(t *testing.T) {
@@ -65,3 +67,81 @@ func TestRipeGeoAPISCode(t *testing.T) {
 		t.Error("failed on none http 200")
 	}
 }
+
+func TestLookupPrefix(t *testing.T) {
+	gock.New(ripe.RIPEAPI).
+		Get("/data/prefix-overview/").
+		Reply(200).
+		JSON(`{"status": "ok", "data": {"resource": "8.8.8.0/24", "announced": true,
+			"asns": [{"asn": 15169, "holder": "GOOGLE - Google LLC"}]}}`)
+
+	p, err := ripe.LookupPrefix("8.8.8.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Announced || len(p.Origins) != 1 || p.Origins[0].ASN != 15169 {
+		t.Errorf("unexpected prefix %+v", p)
+	}
+	// cached
+	if _, err = ripe.LookupPrefix("8.8.8.0/24"); err != nil {
+		t.Error("expected the cached response but got", err)
+	}
+
+	gock.New(ripe.RIPEAPI).
+		Get("/data/prefix-overview/").
+		Reply(200).
+		JSON(`{"status": "ok", "data": {"resource": "192.0.2.0/24", "announced": false, "asns": []}}`)
+
+	p, err = ripe.LookupPrefix("192.0.2.0/24")
+	if err != nil || p.Announced {
+		t.Errorf("expected not announced prefix but got %+v, %v", p, err)
+	}
+}
+
+func TestLookupASN(t *testing.T) {
+	gock.New(ripe.RIPEAPI).
+		Get("/data/as-overview/").
+		Reply(200).
+		JSON(`{"status": "ok", "data": {"holder": "BACOM - Bell Canada", "announced": true}}`)
+	gock.New(ripe.RIPEAPI).
+		Get("/data/announced-prefixes/").
+		Reply(200).
+		JSON(`{"status": "ok", "data": {"prefixes": [{"prefix": "64.230.0.0/16"}]}}`)
+
+	a, err := ripe.LookupASN("AS577")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ASN != 577 || a.Holder != "BACOM - Bell Canada" || len(a.Prefixes) != 1 {
+		t.Errorf("unexpected asn %+v", a)
+	}
+}
+
+func TestLookupRateLimited(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		gock.New(ripe.RIPEAPI).
+			Get("/data/prefix-overview/").
+			Reply(429).
+			SetHeader("Retry-After", "0")
+	}
+	if _, err := ripe.LookupPrefix("10.0.0.0/8"); err != ripe.ErrRateLimited {
+		t.Error("expected rate limited error but got", err)
+	}
+}
+
+func TestLookupPrefixContext(t *testing.T) {
+	gock.New(ripe.RIPEAPI).
+		Get("/data/prefix-overview/").
+		Reply(429).
+		SetHeader("Retry-After", "5")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if _, err := ripe.LookupPrefixContext(ctx, "10.1.0.0/16"); err == nil {
+		t.Error("expected the canceled lookup error")
+	}
+	if time.Since(start) > time.Second {
+		t.Error("expected the canceled lookup doesn't wait for the retry")
+	}
+}
@@ -0,0 +1,289 @@
+package scan
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mehrdadrad/mylg/cli"
+)
+
+// the banner grab defaults
+const (
+	defaultBannerSize    = 512
+	defaultBannerTimeout = 3 * time.Second
+)
+
+// probes are the requests which are sent once connected, the
+// services like http don't send anything until they're asked
+var probes = map[string]string{
+	"http":  "HEAD / HTTP/1.0\r\nHost: %s\r\nUser-Agent: myLG\r\n\r\n",
+	"smtp":  "EHLO mylg.io\r\n",
+	"redis": "PING\r\n",
+}
+
+// tlsPorts are the well-known ports which talk TLS from the start
+var tlsPorts = map[int]bool{
+	443: true, 465: true, 636: true, 993: true, 995: true, 8443: true,
+}
+
+// BannerOptions represents the banner grab options, the empty Probe
+// just reads what the service sends once it's connected
+type BannerOptions struct {
+	Probe   string
+	Size    int
+	Timeout time.Duration
+	TLS     bool
+}
+
+// TLSInfo represents the TLS connection state and the leaf certificate
+type TLSInfo struct {
+	Version   string
+	Cipher    string
+	Subject   string
+	Issuer    string
+	DNSNames  []string
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// BannerResult represents the first bytes which the service sent back
+type BannerResult struct {
+	Host    string
+	Port    int
+	Service string
+	Data    []byte
+	Latency time.Duration
+	TLS     *TLSInfo
+}
+
+// Banner represents a banner grab request
+type Banner struct {
+	host string
+	port int
+	hex  bool
+	opts BannerOptions
+}
+
+// NewBanner parses the banner arguments, nil is returned if the help is requested
+func NewBanner(args string) (*Banner, error) {
+	args, flag := cli.Flag(args)
+	if _, ok := flag["help"]; ok || args == "" {
+		bannerHelp()
+		return nil, nil
+	}
+	b := &Banner{
+		host: args,
+		hex:  cli.SetFlag(flag, "hex", false).(bool),
+		opts: BannerOptions{
+			Probe: fmt.Sprint(cli.SetFlag(flag, "probe", "")),
+			TLS:   cli.SetFlag(flag, "tls", false).(bool),
+		},
+	}
+	defaultPort := ""
+	if h, p, err := net.SplitHostPort(args); err == nil {
+		b.host, defaultPort = h, p
+	}
+	port, err := strconv.Atoi(fmt.Sprint(cli.SetFlag(flag, "p", defaultPort)))
+	if err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("port is not valid, please try banner help")
+	}
+	b.port = port
+	if _, ok := probes[b.opts.Probe]; !ok && b.opts.Probe != "" {
+		return nil, fmt.Errorf("unknown probe %s, the probes are http, smtp and redis", b.opts.Probe)
+	}
+	switch v := cli.SetFlag(flag, "n", defaultBannerSize).(type) {
+	case int:
+		b.opts.Size = v
+	default:
+		return nil, fmt.Errorf("size is not valid")
+	}
+	switch v := cli.SetFlag(flag, "t", "3s").(type) {
+	case int:
+		b.opts.Timeout = time.Duration(v) * time.Second
+	default:
+		if b.opts.Timeout, err = time.ParseDuration(fmt.Sprint(v)); err != nil {
+			return nil, fmt.Errorf("timeout is not valid")
+		}
+	}
+	return b, nil
+}
+
+// Run grabs the banner
+func (b *Banner) Run(ctx context.Context) (BannerResult, error) {
+	return GrabBanner(ctx, b.host, b.port, b.opts)
+}
+
+// Hex returns true if the banner should be printed as hex dump
+func (b *Banner) Hex() bool {
+	return b.hex
+}
+
+// GrabBanner connects to the TCP port, sends the probe if there is any and
+// reads the first bytes of the response until the size or the timeout is
+// reached. the TLS is negotiated if it's asked or the port is a well-known
+// TLS port, the certificate isn't verified as it's only reported
+func GrabBanner(ctx context.Context, host string, port int, o BannerOptions) (BannerResult, error) {
+	r := BannerResult{Host: host, Port: port, Service: Service(port)}
+	if o.Size < 1 {
+		o.Size = defaultBannerSize
+	}
+	if o.Timeout == 0 {
+		o.Timeout = defaultBannerTimeout
+	}
+	ts := time.Now()
+	conn, err := dial(ctx, host, port, o.Timeout)
+	if err != nil {
+		return r, err
+	}
+	r.Latency = time.Since(ts)
+	defer conn.Close()
+
+	deadline := time.Now().Add(o.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	if o.TLS || tlsPorts[port] {
+		tc := tls.Client(conn, &tls.Config{ServerName: serverName(host), InsecureSkipVerify: true})
+		if err := tc.Handshake(); err != nil {
+			return r, fmt.Errorf("tls handshake: %w", err)
+		}
+		r.TLS = tlsInfo(tc.ConnectionState())
+		conn = tc
+	}
+	if probe, ok := probes[o.Probe]; ok {
+		if strings.Contains(probe, "%s") {
+			probe = fmt.Sprintf(probe, host)
+		}
+		if _, err := io.WriteString(conn, probe); err != nil {
+			return r, err
+		}
+	}
+
+	buf := make([]byte, o.Size)
+	for len(r.Data) < o.Size {
+		n, err := conn.Read(buf[len(r.Data):])
+		r.Data = buf[:len(r.Data)+n]
+		if err != nil {
+			// the silent service is timed out w/o any banner
+			var ne net.Error
+			if err == io.EOF || (errors.As(err, &ne) && ne.Timeout()) {
+				break
+			}
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// serverName returns the TLS server name, it's empty for the ip addresses
+func serverName(host string) string {
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+	return host
+}
+
+// tlsInfo returns the TLS details of the connection
+func tlsInfo(s tls.ConnectionState) *TLSInfo {
+	info := &TLSInfo{
+		Version: tlsVersion(s.Version),
+		Cipher:  tls.CipherSuiteName(s.CipherSuite),
+	}
+	if len(s.PeerCertificates) > 0 {
+		c := s.PeerCertificates[0]
+		info.Subject = c.Subject.String()
+		info.Issuer = c.Issuer.String()
+		info.DNSNames = c.DNSNames
+		info.NotBefore, info.NotAfter = c.NotBefore, c.NotAfter
+	}
+	return info
+}
+
+// tlsVersion returns the TLS version name
+func tlsVersion(v uint16) string {
+	switch v {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	}
+	return fmt.Sprintf("0x%04x", v)
+}
+
+// Text returns the banner w/ the non-printable bytes replaced by dots
+func (r BannerResult) Text() string {
+	b := make([]byte, len(r.Data))
+	for i, c := range r.Data {
+		switch {
+		case c == '\r' || c == '\n' || c == '\t':
+			b[i] = c
+		case c < 0x20 || c > 0x7e:
+			b[i] = '.'
+		default:
+			b[i] = c
+		}
+	}
+	return string(b)
+}
+
+// PrintPretty prints the TLS details and the banner as text or hex dump
+func (r BannerResult) PrintPretty(hexDump bool) {
+	fmt.Printf("connected to %s port %d", r.Host, r.Port)
+	if r.Service != "" {
+		fmt.Printf(" (%s)", r.Service)
+	}
+	fmt.Printf(" in %.3f ms\n", float64(r.Latency)/float64(time.Millisecond))
+	if t := r.TLS; t != nil {
+		fmt.Printf("tls:       %s %s\n", t.Version, t.Cipher)
+		if t.Subject != "" {
+			fmt.Printf("subject:   %s\n", t.Subject)
+			fmt.Printf("issuer:    %s\n", t.Issuer)
+			if len(t.DNSNames) > 0 {
+				fmt.Printf("dns names: %s\n", strings.Join(t.DNSNames, ", "))
+			}
+			fmt.Printf("valid:     %s - %s\n", t.NotBefore.Format("2006-01-02"), t.NotAfter.Format("2006-01-02"))
+		}
+	}
+	if len(r.Data) == 0 {
+		fmt.Println("no banner")
+		return
+	}
+	fmt.Printf("%d bytes:\n", len(r.Data))
+	if hexDump {
+		fmt.Print(hex.Dump(r.Data))
+		return
+	}
+	fmt.Println(strings.TrimRight(r.Text(), "\r\n"))
+}
+
+func bannerHelp() {
+	fmt.Println(`
+    usage:
+          banner ip/host [option]
+    options:
+          -p port          The TCP port (or host:port)
+          -probe name      Sends the probe once connected: http, smtp or redis (default: just reads)
+          -n bytes         The max banner size in bytes (default: 512)
+          -t timeout       Connect and read timeout e.g. 500ms (default: 3s)
+          -tls             Negotiates TLS (it's on for 443, 465, 636, 993, 995 and 8443)
+          -hex             Prints the banner as hex dump
+    example:
+          banner mail.google.com -p 25
+          banner google.com -p 443 -probe http
+          banner 10.0.0.1:6379 -probe redis -hex
+	`)
+}
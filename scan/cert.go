@@ -0,0 +1,252 @@
+package scan
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mehrdadrad/mylg/cli"
+)
+
+// defaultCertDays is the days before the expiry which the certificate
+// is flagged as soon-to-expire
+const defaultCertDays = 30
+
+// CertInfo represents a certificate of the chain
+type CertInfo struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	Serial             string    `json:"serial"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	DaysLeft           int       `json:"days_left"`
+	DNSNames           []string  `json:"dns_names,omitempty"`
+	IPAddresses        []string  `json:"ip_addresses,omitempty"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	IsCA               bool      `json:"is_ca"`
+	Expired            bool      `json:"expired"`
+	ExpiresSoon        bool      `json:"expires_soon"`
+	NotYetValid        bool      `json:"not_yet_valid,omitempty"`
+}
+
+// CertChain represents the certificate chain which the server sent, the
+// chain isn't rejected by the handshake so VerifyError is the reason why
+// the system roots don't trust it
+type CertChain struct {
+	Host         string     `json:"host"`
+	Port         int        `json:"port"`
+	ServerName   string     `json:"server_name,omitempty"`
+	Version      string     `json:"tls_version"`
+	Cipher       string     `json:"cipher"`
+	Verified     bool       `json:"verified"`
+	VerifyError  string     `json:"verify_error,omitempty"`
+	Certificates []CertInfo `json:"certificates"`
+}
+
+// CertOptions represents the certificate inspection options, the empty
+// ServerName is the host unless it's an ip address
+type CertOptions struct {
+	ServerName string
+	Timeout    time.Duration
+	Days       int
+}
+
+// Cert represents a certificate inspection request
+type Cert struct {
+	host string
+	port int
+	json bool
+	opts CertOptions
+}
+
+// NewCert parses the cert arguments, nil is returned if the help is requested
+func NewCert(args string) (*Cert, error) {
+	args, flag := cli.Flag(args)
+	if _, ok := flag["help"]; ok || args == "" {
+		certHelp()
+		return nil, nil
+	}
+	c := &Cert{
+		host: args,
+		json: cli.SetFlag(flag, "json", false).(bool),
+		opts: CertOptions{ServerName: fmt.Sprint(cli.SetFlag(flag, "sni", ""))},
+	}
+	defaultPort := "443"
+	if h, p, err := net.SplitHostPort(args); err == nil {
+		c.host, defaultPort = h, p
+	}
+	port, err := strconv.Atoi(fmt.Sprint(cli.SetFlag(flag, "p", defaultPort)))
+	if err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("port is not valid, please try cert help")
+	}
+	c.port = port
+	switch v := cli.SetFlag(flag, "days", defaultCertDays).(type) {
+	case int:
+		c.opts.Days = v
+	default:
+		return nil, fmt.Errorf("days is not valid")
+	}
+	switch v := cli.SetFlag(flag, "t", "3s").(type) {
+	case int:
+		c.opts.Timeout = time.Duration(v) * time.Second
+	default:
+		if c.opts.Timeout, err = time.ParseDuration(fmt.Sprint(v)); err != nil {
+			return nil, fmt.Errorf("timeout is not valid")
+		}
+	}
+	return c, nil
+}
+
+// Run inspects the certificate chain
+func (c *Cert) Run(ctx context.Context) (CertChain, error) {
+	return InspectCert(ctx, c.host, c.port, c.opts)
+}
+
+// JSON returns true if the chain should be printed as JSON
+func (c *Cert) JSON() bool {
+	return c.json
+}
+
+// InspectCert dials the TLS port and returns the certificate chain w/ the
+// validity of each certificate, the handshake doesn't verify the chain so
+// the expired or the self-signed certificates can be inspected too. the
+// server name is sent as SNI so the virtual hosts return their own chain
+func InspectCert(ctx context.Context, host string, port int, o CertOptions) (CertChain, error) {
+	if o.ServerName == "" {
+		o.ServerName = serverName(host)
+	}
+	if o.Timeout == 0 {
+		o.Timeout = defaultBannerTimeout
+	}
+	if o.Days == 0 {
+		o.Days = defaultCertDays
+	}
+	chain := CertChain{Host: host, Port: port, ServerName: o.ServerName}
+	conn, err := dial(ctx, host, port, o.Timeout)
+	if err != nil {
+		return chain, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(o.Timeout))
+
+	tc := tls.Client(conn, &tls.Config{ServerName: o.ServerName, InsecureSkipVerify: true})
+	if err := tc.Handshake(); err != nil {
+		return chain, fmt.Errorf("tls handshake: %w", err)
+	}
+	s := tc.ConnectionState()
+	chain.Version = tlsVersion(s.Version)
+	chain.Cipher = tls.CipherSuiteName(s.CipherSuite)
+	if len(s.PeerCertificates) == 0 {
+		return chain, fmt.Errorf("%s sent no certificate", host)
+	}
+
+	now := time.Now()
+	for _, c := range s.PeerCertificates {
+		chain.Certificates = append(chain.Certificates, certInfo(c, now, o.Days))
+	}
+	if err := verifyChain(s.PeerCertificates, o.ServerName, now); err != nil {
+		chain.VerifyError = err.Error()
+	} else {
+		chain.Verified = true
+	}
+	return chain, nil
+}
+
+// certInfo returns the certificate details and its validity at now
+func certInfo(c *x509.Certificate, now time.Time, days int) CertInfo {
+	info := CertInfo{
+		Subject:            c.Subject.String(),
+		Issuer:             c.Issuer.String(),
+		Serial:             c.SerialNumber.Text(16),
+		NotBefore:          c.NotBefore,
+		NotAfter:           c.NotAfter,
+		DaysLeft:           int(c.NotAfter.Sub(now).Hours() / 24),
+		DNSNames:           c.DNSNames,
+		SignatureAlgorithm: c.SignatureAlgorithm.String(),
+		IsCA:               c.IsCA,
+		Expired:            now.After(c.NotAfter),
+		NotYetValid:        now.Before(c.NotBefore),
+	}
+	info.ExpiresSoon = !info.Expired && c.NotAfter.Before(now.AddDate(0, 0, days))
+	for _, ip := range c.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	return info
+}
+
+// verifyChain verifies the leaf certificate by the system roots and the
+// intermediates which the server sent
+func verifyChain(certs []*x509.Certificate, name string, now time.Time) error {
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       name,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	return err
+}
+
+// status returns the validity flags of the certificate
+func (c CertInfo) status() string {
+	switch {
+	case c.Expired:
+		return fmt.Sprintf("EXPIRED %d days ago", -c.DaysLeft)
+	case c.NotYetValid:
+		return "NOT YET VALID"
+	case c.ExpiresSoon:
+		return fmt.Sprintf("EXPIRES SOON in %d days", c.DaysLeft)
+	}
+	return fmt.Sprintf("valid, %d days left", c.DaysLeft)
+}
+
+// String returns the certificate chain summary
+func (c CertChain) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s port %d", c.Host, c.Port)
+	if c.ServerName != "" && c.ServerName != c.Host {
+		fmt.Fprintf(&b, " (sni %s)", c.ServerName)
+	}
+	fmt.Fprintf(&b, ": %s %s\n", c.Version, c.Cipher)
+	if c.Verified {
+		b.WriteString("chain is trusted\n")
+	} else {
+		fmt.Fprintf(&b, "chain is NOT trusted: %s\n", c.VerifyError)
+	}
+	for i, cert := range c.Certificates {
+		fmt.Fprintf(&b, "\n%d %s\n", i, cert.Subject)
+		fmt.Fprintf(&b, "  issuer:    %s\n", cert.Issuer)
+		fmt.Fprintf(&b, "  validity:  %s - %s (%s)\n", cert.NotBefore.Format("2006-01-02"),
+			cert.NotAfter.Format("2006-01-02"), cert.status())
+		if names := append(append([]string{}, cert.DNSNames...), cert.IPAddresses...); len(names) > 0 {
+			fmt.Fprintf(&b, "  sans:      %s\n", strings.Join(names, ", "))
+		}
+		fmt.Fprintf(&b, "  signature: %s\n", cert.SignatureAlgorithm)
+		fmt.Fprintf(&b, "  serial:    %s\n", cert.Serial)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func certHelp() {
+	fmt.Println(`
+    usage:
+          cert ip/host [option]
+    options:
+          -p port          The TLS port (default: 443, or host:port)
+          -sni name        The server name (default: the host)
+          -days days       Flags the certificates which expire within the days (default: 30)
+          -t timeout       Connect and handshake timeout e.g. 500ms (default: 3s)
+          -json            Prints the chain in JSON format
+    example:
+          cert google.com
+          cert 10.0.0.1 -sni www.example.com
+          cert mail.google.com -p 993 -json
+	`)
+}
@@ -0,0 +1,145 @@
+package scan
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PortResult represents a TCP port scan result
+type PortResult struct {
+	Port    int
+	Open    bool
+	Service string
+	Latency time.Duration
+}
+
+// CSVHeader returns the PortResult CSV columns
+func (r PortResult) CSVHeader() []string {
+	return []string{"port", "open", "service", "latency_ms"}
+}
+
+// CSVRecord returns the PortResult CSV row
+func (r PortResult) CSVRecord() []string {
+	return []string{
+		strconv.Itoa(r.Port),
+		strconv.FormatBool(r.Open),
+		r.Service,
+		strconv.FormatFloat(float64(r.Latency)/float64(time.Millisecond), 'f', -1, 64),
+	}
+}
+
+// WriteCSV writes the results as CSV w/ the header
+func WriteCSV(w io.Writer, results []PortResult) error {
+	cw := csv.NewWriter(w)
+	cw.Write(PortResult{}.CSVHeader())
+	for _, r := range results {
+		cw.Write(r.CSVRecord())
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// defaultConcurrency is the max concurrent connections,
+// it keeps the scan far from the open files limit
+const defaultConcurrency = 100
+
+// services maps the well-known ports to the service names
+var services = map[int]string{
+	21: "ftp", 22: "ssh", 23: "telnet", 25: "smtp", 53: "domain",
+	80: "http", 110: "pop3", 111: "rpcbind", 123: "ntp", 135: "msrpc",
+	139: "netbios-ssn", 143: "imap", 179: "bgp", 389: "ldap", 443: "https",
+	445: "microsoft-ds", 465: "smtps", 514: "shell", 587: "submission",
+	636: "ldaps", 873: "rsync", 993: "imaps", 995: "pop3s", 1433: "ms-sql",
+	1521: "oracle", 2049: "nfs", 3306: "mysql", 3389: "rdp", 5432: "postgresql",
+	5900: "vnc", 6379: "redis", 8080: "http-alt", 8443: "https-alt",
+	9200: "elasticsearch", 11211: "memcache", 27017: "mongodb",
+}
+
+// Service returns the well-known service name of the port
+func Service(port int) string {
+	return services[port]
+}
+
+// ConnScan dials the TCP ports through a bounded worker pool and
+// streams the results, the channel is closed once the scan is done
+// or ctx is canceled
+func ConnScan(ctx context.Context, host string, ports []int, concurrency int, timeout time.Duration) chan PortResult {
+	var (
+		c    = make(chan PortResult)
+		jobs = make(chan int)
+		wg   sync.WaitGroup
+	)
+	if concurrency < 1 {
+		concurrency = defaultConcurrency
+	}
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for port := range jobs {
+				r := dialPort(ctx, host, port, timeout)
+				select {
+				case c <- r:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	go func() {
+	LOOP:
+		for _, port := range ports {
+			select {
+			case jobs <- port:
+			case <-ctx.Done():
+				break LOOP
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(c)
+	}()
+	return c
+}
+
+// dialPort tries to connect to the TCP port
+func dialPort(ctx context.Context, host string, port int, timeout time.Duration) PortResult {
+	r := PortResult{Port: port, Service: Service(port)}
+	ts := time.Now()
+	conn, err := dial(ctx, host, port, timeout)
+	if err != nil {
+		return r
+	}
+	r.Latency = time.Since(ts)
+	r.Open = true
+	conn.Close()
+	return r
+}
+
+// dial connects to the TCP port, it backs off and retries
+// once the open files limit is reached
+func dial(ctx context.Context, host string, port int, timeout time.Duration) (net.Conn, error) {
+	var (
+		d    = net.Dialer{Timeout: timeout}
+		addr = net.JoinHostPort(host, fmt.Sprintf("%d", port))
+	)
+	for {
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil && strings.Contains(err.Error(), "too many open files") && ctx.Err() == nil {
+			// random back-off
+			time.Sleep(time.Duration(10+rand.Int31n(30)) * time.Millisecond)
+			continue
+		}
+		return conn, err
+	}
+}
@@ -4,14 +4,12 @@ package scan
 import (
 	"context"
 	"fmt"
-	"math/rand"
 	"net"
 	"os"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/google/gopacket"
@@ -36,6 +34,7 @@ type Scan struct {
 	forceV4  bool
 	forceV6  bool
 	connScan bool
+	csv      bool
 	ip       gopacket.NetworkLayer
 }
 
@@ -57,6 +56,7 @@ func NewScan(args string, cfg cli.Config) (Scan, error) {
 	scan.forceV4 = cli.SetFlag(flag, "4", false).(bool)
 	scan.forceV6 = cli.SetFlag(flag, "6", false).(bool)
 	scan.connScan = cli.SetFlag(flag, "c", false).(bool)
+	scan.csv = cli.SetFlag(flag, "csv", false).(bool)
 
 	pRange := cli.SetFlag(flag, "p", cfg.Scan.Port).(string)
 
@@ -131,24 +131,30 @@ func (s *Scan) setIP() error {
 // Run tries to scan wide range ports (TCP)
 func (s *Scan) Run() {
 	var (
-		openPorts []int
-		err       error
+		results []PortResult
+		err     error
 	)
 
-	if s.minPort != s.maxPort {
-		fmt.Printf("Scan %s (%s) TCP ports %d-%d\n", s.target, s.raddr, s.minPort, s.maxPort)
-	} else {
-		fmt.Printf("Scan %s (%s) TCP port %d\n", s.target, s.raddr, s.minPort)
+	if !s.csv {
+		if s.minPort != s.maxPort {
+			fmt.Printf("Scan %s (%s) TCP ports %d-%d\n", s.target, s.raddr, s.minPort, s.maxPort)
+		} else {
+			fmt.Printf("Scan %s (%s) TCP port %d\n", s.target, s.raddr, s.minPort)
+		}
 	}
 
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Protocol", "Port", "Status"})
+	table.SetHeader([]string{"Protocol", "Port", "Service", "Status"})
 
 	tStart := time.Now()
 	if s.connScan {
-		openPorts = s.tcpConnScan()
+		results = s.tcpConnScan()
 	} else {
+		var openPorts []int
 		openPorts, err = s.tcpSYNScan()
+		for _, p := range openPorts {
+			results = append(results, PortResult{Port: p, Open: true, Service: Service(p)})
+		}
 	}
 
 	if err != nil {
@@ -156,17 +162,24 @@ func (s *Scan) Run() {
 		return
 	}
 
-	for _, p := range openPorts {
-		table.Append([]string{"TCP", fmt.Sprintf("%d", p), "Open"})
+	if s.csv {
+		if err = WriteCSV(os.Stdout, results); err != nil {
+			println(err.Error())
+		}
+		return
+	}
+
+	for _, r := range results {
+		table.Append([]string{"TCP", fmt.Sprintf("%d", r.Port), r.Service, "Open"})
 	}
 
-	if len(openPorts) == 0 {
+	if len(results) == 0 {
 		println("there isn't any opened port")
 	} else {
 		println("")
 		table.Render()
 		elapsed := fmt.Sprintf("%.3f seconds", time.Since(tStart).Seconds())
-		println("Scan done:", len(openPorts), "opened port(s) found in", elapsed)
+		println("Scan done:", len(results), "opened port(s) found in", elapsed)
 	}
 
 }
@@ -349,37 +362,22 @@ func (s *Scan) sendTCPSYN() error {
 	return nil
 }
 
-// tcpConnScan tries to scan a single host
-func (s *Scan) tcpConnScan() []int {
-	var wg sync.WaitGroup
-
-	var ports []int
+// tcpConnScan tries to scan a single host, it returns the open ports
+func (s *Scan) tcpConnScan() []PortResult {
+	var (
+		ports []int
+		open  []PortResult
+	)
 	for i := s.minPort; i <= s.maxPort; i++ {
-		wg.Add(1)
-		go func(i int) {
-			for {
-				host := net.JoinHostPort(s.raddr.String(), fmt.Sprintf("%d", i))
-				conn, err := net.DialTimeout("tcp", host, 2*time.Second)
-				if err != nil {
-					if strings.Contains(err.Error(), "too many open files") {
-						// random back-off
-						time.Sleep(time.Duration(10+rand.Int31n(30)) * time.Millisecond)
-						continue
-					}
-					wg.Done()
-					return
-				}
-				conn.Close()
-				break
-			}
-			ports = append(ports, i)
-			wg.Done()
-		}(i)
+		ports = append(ports, i)
 	}
-
-	wg.Wait()
-	sort.Ints(ports)
-	return ports
+	for r := range ConnScan(context.Background(), s.raddr.String(), ports, 0, 2*time.Second) {
+		if r.Open {
+			open = append(open, r)
+		}
+	}
+	sort.Slice(open, func(i, j int) bool { return open[i].Port < open[j].Port })
+	return open
 }
 
 func uniqSlice(s []int) []int {
@@ -404,6 +402,7 @@ func help(cfg cli.Config) {
           -c                                TCP connect scan (default is TCP SYN scan)
           -4                                Force IPv4
           -6                                Force IPv6
+          -csv                              Prints the open ports in CSV format
     example:
           scan 8.8.8.8 -p 53
           scan www.google.com -p 1-500
@@ -1,9 +1,27 @@
 package scan_test
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
 	"github.com/mehrdadrad/mylg/cli"
 	"github.com/mehrdadrad/mylg/scan"
-	"testing"
 )
 
 var (
@@ -28,3 +46,245 @@ func TestIsCIDR(t *testing.T) {
 		t.Error("IsCIDR failed")
 	}
 }
+
+func TestConnScan(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	open := ln.Addr().(*net.TCPAddr).Port
+
+	// a closed port
+	cl, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed := cl.Addr().(*net.TCPAddr).Port
+	cl.Close()
+
+	results := map[int]scan.PortResult{}
+	for r := range scan.ConnScan(context.Background(), "127.0.0.1", []int{open, closed}, 2, time.Second) {
+		results[r.Port] = r
+	}
+	if len(results) != 2 {
+		t.Fatal("expected 2 results but got", results)
+	}
+	if !results[open].Open || results[closed].Open {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestConnScanCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for range scan.ConnScan(ctx, "127.0.0.1", []int{1, 2, 3}, 1, time.Second) {
+	}
+}
+
+func TestService(t *testing.T) {
+	if s := scan.Service(443); s != "https" {
+		t.Error("expected https but got", s)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	results := []scan.PortResult{
+		{Port: 22, Open: true, Service: "ssh", Latency: 1500 * time.Microsecond},
+		{Port: 8081, Open: false},
+	}
+	if err := scan.WriteCSV(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile("testdata/ports.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != string(want) {
+		t.Errorf("expected\n%s\nbut got\n%s", want, buf.String())
+	}
+}
+
+func TestGrabBanner(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte("SSH-2.0-OpenSSH_8.2\x00\r\n"))
+		conn.Close()
+	}()
+	port := ln.Addr().(*net.TCPAddr).Port
+	r, err := scan.GrabBanner(context.Background(), "127.0.0.1", port, scan.BannerOptions{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Text() != "SSH-2.0-OpenSSH_8.2.\r\n" {
+		t.Errorf("unexpected banner %q", r.Text())
+	}
+	if r.TLS != nil {
+		t.Error("expected no tls but got", r.TLS)
+	}
+}
+
+func TestGrabBannerSize(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("220 mail.example.com ESMTP\r\n"))
+		time.Sleep(2 * time.Second)
+	}()
+	port := ln.Addr().(*net.TCPAddr).Port
+	r, err := scan.GrabBanner(context.Background(), "127.0.0.1", port, scan.BannerOptions{Size: 3, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(r.Data) != "220" {
+		t.Errorf("expected 220 but got %q", r.Data)
+	}
+}
+
+func TestGrabBannerTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "mylg-test")
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	b, err := scan.NewBanner(u.Host + " -probe http -tls -t 2s")
+	if err != nil || b == nil {
+		t.Fatal("NewBanner failed", err)
+	}
+	r, err := b.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Port != port {
+		t.Error("expected port", port, "but got", r.Port)
+	}
+	if !strings.HasPrefix(r.Text(), "HTTP/1.0 200 OK") || !strings.Contains(r.Text(), "Server: mylg-test") {
+		t.Errorf("unexpected banner %q", r.Text())
+	}
+	if r.TLS == nil || r.TLS.Version == "" || !strings.HasPrefix(r.TLS.Cipher, "TLS_") || len(r.TLS.DNSNames) == 0 {
+		t.Errorf("unexpected tls details %+v", r.TLS)
+	}
+}
+
+func TestNewBanner(t *testing.T) {
+	for _, args := range []string{"127.0.0.1", "127.0.0.1 -p 70000", "127.0.0.1 -p 22 -probe gopher"} {
+		if _, err := scan.NewBanner(args); err == nil {
+			t.Error("expected error for", args)
+		}
+	}
+}
+
+// expiredCert returns a self-signed certificate which expired yesterday
+func expiredCert(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "www.example.com"},
+		DNSNames:     []string{"www.example.com"},
+		NotBefore:    time.Now().AddDate(-1, 0, 0),
+		NotAfter:     time.Now().AddDate(0, 0, -1),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestInspectCert(t *testing.T) {
+	var (
+		cert = expiredCert(t)
+		sni  = make(chan string, 1)
+	)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			sni <- hello.ServerName
+			return &cert, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		conn.(*tls.Conn).Handshake()
+		conn.Close()
+	}()
+
+	c, err := scan.NewCert(ln.Addr().String() + " -sni www.example.com")
+	if err != nil || c == nil {
+		t.Fatal("NewCert failed", err)
+	}
+	chain, err := c.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := <-sni; name != "www.example.com" {
+		t.Error("expected sni www.example.com but got", name)
+	}
+	if chain.Verified || chain.VerifyError == "" {
+		t.Error("expected the self-signed chain not to be verified")
+	}
+	if len(chain.Certificates) != 1 {
+		t.Fatal("expected 1 certificate but got", len(chain.Certificates))
+	}
+	got := chain.Certificates[0]
+	if !got.Expired || got.ExpiresSoon || got.Serial != "2a" || got.Subject != "CN=www.example.com" ||
+		got.SignatureAlgorithm != "ECDSA-SHA256" || len(got.DNSNames) != 1 {
+		t.Errorf("unexpected certificate %+v", got)
+	}
+	if !strings.Contains(chain.String(), "EXPIRED 1 days ago") {
+		t.Errorf("expected the expired flag but got\n%s", chain)
+	}
+}
+
+func TestInspectCertExpiresSoon(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	// the httptest certificate is valid until 2084
+	chain, err := scan.InspectCert(context.Background(), "127.0.0.1", port, scan.CertOptions{Days: 365 * 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chain.ServerName != "" {
+		t.Error("expected no sni for the ip address but got", chain.ServerName)
+	}
+	if c := chain.Certificates[0]; c.Expired || !c.ExpiresSoon || len(c.IPAddresses) == 0 {
+		t.Errorf("unexpected certificate %+v", c)
+	}
+}
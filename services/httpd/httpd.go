@@ -2,12 +2,15 @@
 package httpd
 
 import (
+	"context"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/rakyll/statik/fs"
 	"net/http"
+	"time"
 
 	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/lg"
 	// statik is single binary including all web stuff
 	_ "github.com/mehrdadrad/mylg/services/dashboard/statik"
 )
@@ -30,6 +33,9 @@ type APIHandler func(w http.ResponseWriter, r *http.Request, cfg cli.Config)
 
 var ttracker = make(map[int]TTracker)
 
+// shutdownTimeout is the time to finish the in-flight requests
+const shutdownTimeout = 10 * time.Second
+
 // APIWrapper wraps API func including cli arg
 func APIWrapper(handler APIHandler, cfg cli.Config) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -59,9 +65,50 @@ func API(w http.ResponseWriter, r *http.Request, cfg cli.Config) {
 
 // Run starts web service
 func Run(cfg cli.Config) {
+	addr := fmt.Sprintf("%s:%d", cfg.Web.Address, cfg.Web.Port)
+	if err := listen(context.Background(), addr, Handler(cfg)); err != nil {
+		println(err.Error())
+	}
+}
+
+// Serve starts web service w/ the metrics endpoint on the addr,
+// it shuts down gracefully once the ctx is canceled
+func Serve(ctx context.Context, addr string, cfg cli.Config) error {
+	m := NewMetrics()
+	lg.SetMetrics(m)
+	defer lg.SetMetrics(nil)
+	return listen(ctx, addr, Handler(cfg, Route{"Metrics", "GET", "/metrics", m.ServeHTTP}))
+}
+
+// listen serves the handler until the ctx is canceled
+func listen(ctx context.Context, addr string, h http.Handler) error {
+	srv := &http.Server{Addr: addr, Handler: h}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return srv.Shutdown(sctx)
+	}
+}
+
+// Handler returns the API, the extra and the dashboard routes
+func Handler(cfg cli.Config, extra ...Route) http.Handler {
 	statikFS, _ := fs.New()
 	router := mux.NewRouter().StrictSlash(true)
 	routes := []Route{
+		{
+			"LookingGlass",
+			"GET",
+			"/api/lg/{provider}/{cmd}",
+			lgAPI,
+		},
 		{
 			"API",
 			"GET",
@@ -69,6 +116,7 @@ func Run(cfg cli.Config) {
 			APIWrapper(API, cfg),
 		},
 	}
+	routes = append(routes, extra...)
 
 	for _, route := range routes {
 		router.
@@ -78,8 +126,5 @@ func Run(cfg cli.Config) {
 			Handler(route.HandlerFunc)
 	}
 	router.PathPrefix("/").Handler(http.FileServer(statikFS))
-	err := http.ListenAndServe(fmt.Sprintf("%s:%d", cfg.Web.Address, cfg.Web.Port), router)
-	if err != nil {
-		println(err.Error())
-	}
+	return router
 }
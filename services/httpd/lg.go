@@ -0,0 +1,191 @@
+package httpd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+// lgHostRe validates the looking glass host (including IDN), ip address or prefix
+var lgHostRe = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N}.:/_-]{0,252}$`)
+
+// lgAPI handles the looking glass routes, the provider is created per
+// request since the providers aren't safe for concurrent use
+func lgAPI(w http.ResponseWriter, r *http.Request) {
+	var (
+		v    = mux.Vars(r)
+		host = r.FormValue("host")
+		node = r.FormValue("node")
+	)
+
+	p, err := lg.New(v["provider"])
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if !lgHostRe.MatchString(host) {
+		writeError(w, http.StatusBadRequest, "host is invalid")
+		return
+	}
+	if node != "" {
+		p.GetNodes()
+		if !p.ChangeNode(node) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("node %q doesn't exist", node))
+			return
+		}
+	}
+	p.Set(host, r.FormValue("ipv"))
+
+	switch v["cmd"] {
+	case "ping":
+		lgPing(w, p)
+	case "trace":
+		lgTrace(w, r, p)
+	case "bgp":
+		lgBGP(w, r, p)
+	default:
+		writeError(w, http.StatusNotFound, "command doesn't support")
+	}
+}
+
+// lgPing responds the ping statistics
+func lgPing(w http.ResponseWriter, p lg.LookingGlass) {
+	m, err := p.Ping()
+	if err != nil {
+		writeLGError(w, err)
+		return
+	}
+	stats, err := lg.ParsePing(m)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// lgTrace streams the trace hops (or the lines if the
+// provider can't parse the hops) as server-sent events
+func lgTrace(w http.ResponseWriter, r *http.Request, p lg.LookingGlass) {
+	if t, ok := p.(lg.StructuredTracer); ok {
+		hops, err := t.TraceStructured()
+		if err != nil {
+			writeLGError(w, err)
+			return
+		}
+		sse := newEventStream(w)
+		for hop := range hops {
+			if !sse.send(r, "hop", hop) {
+				go func() {
+					for range hops {
+					}
+				}()
+				return
+			}
+		}
+		sse.send(r, "end", nil)
+		return
+	}
+	lines, err := p.Trace()
+	if err != nil {
+		writeLGError(w, err)
+		return
+	}
+	streamLines(w, r, lines)
+}
+
+// lgBGP streams the bgp routes (or the lines if the
+// provider can't parse the routes) as server-sent events
+func lgBGP(w http.ResponseWriter, r *http.Request, p lg.LookingGlass) {
+	if b, ok := p.(lg.BGPRouter); ok {
+		routes, err := b.BGPRoutes()
+		if err != nil {
+			writeLGError(w, err)
+			return
+		}
+		sse := newEventStream(w)
+		for _, route := range routes {
+			if !sse.send(r, "route", route) {
+				return
+			}
+		}
+		sse.send(r, "end", nil)
+		return
+	}
+	streamLines(w, r, p.BGP())
+}
+
+// streamLines streams the raw lines as server-sent events
+func streamLines(w http.ResponseWriter, r *http.Request, lines chan string) {
+	sse := newEventStream(w)
+	for l := range lines {
+		if !sse.send(r, "line", l) {
+			go func() {
+				for range lines {
+				}
+			}()
+			return
+		}
+	}
+	sse.send(r, "end", nil)
+}
+
+// eventStream writes the server-sent events
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newEventStream(w http.ResponseWriter) *eventStream {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	f, _ := w.(http.Flusher)
+	return &eventStream{w: w, flusher: f}
+}
+
+// send writes the JSON encoded event, it returns false
+// once the client is gone or the write failed
+func (s *eventStream) send(r *http.Request, event string, v interface{}) bool {
+	select {
+	case <-r.Context().Done():
+		return false
+	default:
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+	if _, err = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, b); err != nil {
+		return false
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	return true
+}
+
+// writeLGError responds the looking glass error, the rate limited
+// requests get 429 w/ the suggested Retry-After
+func writeLGError(w http.ResponseWriter, err error) {
+	var rl *lg.RateLimitError
+	if errors.As(err, &rl) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(rl.RetryAfter.Seconds())))
+		writeError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	writeError(w, http.StatusBadGateway, err.Error())
+}
+
+// writeError responds the error in JSON format
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,127 @@
+package httpd_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/lg"
+	"github.com/mehrdadrad/mylg/services/httpd"
+)
+
+const fakePingOutput = `PING 192.0.2.1 (192.0.2.1): 56 data bytes
+
+--- 192.0.2.1 ping statistics ---
+4 packets transmitted, 3 packets received, 25.0% packet loss
+round-trip min/avg/max/stddev = 1.100/1.200/1.400/0.100 ms
+`
+
+// fakeLG is a looking glass w/ a single node
+type fakeLG struct {
+	host string
+}
+
+func (f *fakeLG) Set(host, version string)    { f.host = host }
+func (f *fakeLG) Get() (string, string)       { return f.host, "" }
+func (f *fakeLG) GetDefaultNode() string      { return "paris" }
+func (f *fakeLG) GetNodes() []string          { return []string{"paris"} }
+func (f *fakeLG) GetNode() string             { return "paris" }
+func (f *fakeLG) ChangeNode(node string) bool { return node == "paris" }
+func (f *fakeLG) Ping() (string, error)       { return fakePingOutput, nil }
+func (f *fakeLG) BGP() chan string            { return lines("route " + f.host) }
+
+func (f *fakeLG) SupportedCommands() []lg.Command {
+	return []lg.Command{lg.CmdPing, lg.CmdTrace, lg.CmdBGP}
+}
+
+func (f *fakeLG) Trace() (chan string, error) {
+	return lines("1 192.0.2.1", "2 192.0.2.2"), nil
+}
+
+func lines(l ...string) chan string {
+	c := make(chan string, len(l))
+	for _, s := range l {
+		c <- s
+	}
+	close(c)
+	return c
+}
+
+func init() {
+	lg.Register("fake", func() lg.LookingGlass { return &fakeLG{} })
+}
+
+func TestLookingGlassAPI(t *testing.T) {
+	ts := httptest.NewServer(httpd.Handler(cli.Config{}))
+	defer ts.Close()
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/api/lg/fake/ping?host=192.0.2.1&node=paris", http.StatusOK},
+		{"/api/lg/fake/ping?host=192.0.2.1&node=london", http.StatusBadRequest},
+		{"/api/lg/fake/ping?host=;reboot", http.StatusBadRequest},
+		{"/api/lg/fake/ping", http.StatusBadRequest},
+		{"/api/lg/unknown/ping?host=192.0.2.1", http.StatusNotFound},
+		{"/api/lg/fake/dig?host=192.0.2.1", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		resp, err := http.Get(ts.URL + test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.code {
+			t.Errorf("%s expected status %d but got %d", test.path, test.code, resp.StatusCode)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/api/lg/fake/ping?host=192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var stats map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats["packets_received"] != 3.0 || stats["avg_ms"] != 1.2 {
+		t.Error("unexpected ping stats", stats)
+	}
+}
+
+func TestLookingGlassAPIStream(t *testing.T) {
+	ts := httptest.NewServer(httpd.Handler(cli.Config{}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/lg/fake/trace?host=192.0.2.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Error("expected event stream but got", ct)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	expected := "event: line\ndata: \"1 192.0.2.1\"\n\n" +
+		"event: line\ndata: \"2 192.0.2.2\"\n\n" +
+		"event: end\ndata: null\n\n"
+	if string(b) != expected {
+		t.Errorf("unexpected events %q", b)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/lg/fake/bgp?host=192.0.2.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ = ioutil.ReadAll(resp.Body)
+	if !strings.Contains(string(b), `data: "route 192.0.2.0/24"`) {
+		t.Errorf("unexpected events %q", b)
+	}
+}
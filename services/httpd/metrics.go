@@ -0,0 +1,141 @@
+package httpd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the histograms buckets in seconds
+var (
+	durationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+	rttBuckets      = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+)
+
+// Metrics collects the looking glass metrics (see lg.SetMetrics)
+// and exposes them in the prometheus text format
+type Metrics struct {
+	mu        sync.Mutex
+	queries   map[string]float64
+	errors    map[string]float64
+	durations map[string]*histogram
+	rtts      map[string]*histogram
+}
+
+// histogram represents a prometheus histogram
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+// NewMetrics returns an empty metrics collector
+func NewMetrics() *Metrics {
+	return &Metrics{
+		queries:   map[string]float64{},
+		errors:    map[string]float64{},
+		durations: map[string]*histogram{},
+		rtts:      map[string]*histogram{},
+	}
+}
+
+// ObserveRequest counts the query, its error and its duration
+func (m *Metrics) ObserveRequest(provider, node, cmd string, d time.Duration, errType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l := labels("provider", provider, "node", node, "cmd", cmd)
+	m.queries[l]++
+	if errType != "" {
+		m.errors[labels("provider", provider, "node", node, "cmd", cmd, "type", errType)]++
+	}
+	observe(m.durations, l, durationBuckets, d)
+}
+
+// ObservePing adds the ping rtt to the histogram
+func (m *Metrics) ObservePing(provider, node string, rtt time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	observe(m.rtts, labels("provider", provider, "node", node), rttBuckets, rtt)
+}
+
+// ServeHTTP writes the metrics in the prometheus text format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+
+	m.mu.Lock()
+	writeCounter(&buf, "mylg_lg_queries_total", "Total looking glass queries.", m.queries)
+	writeCounter(&buf, "mylg_lg_errors_total", "Total looking glass errors by type.", m.errors)
+	writeHistogram(&buf, "mylg_lg_request_duration_seconds", "Looking glass request durations.", m.durations)
+	writeHistogram(&buf, "mylg_lg_ping_rtt_seconds", "Looking glass ping average rtt.", m.rtts)
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(buf.Bytes())
+}
+
+func observe(h map[string]*histogram, l string, buckets []float64, d time.Duration) {
+	if _, ok := h[l]; !ok {
+		h[l] = &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+	}
+	s := d.Seconds()
+	for i, b := range buckets {
+		if s <= b {
+			h[l].counts[i]++
+		}
+	}
+	h[l].count++
+	h[l].sum += s
+}
+
+func writeCounter(buf *bytes.Buffer, name, help string, c map[string]float64) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	for _, l := range sortedKeys(c) {
+		fmt.Fprintf(buf, "%s{%s} %s\n", name, l, formatValue(c[l]))
+	}
+}
+
+func writeHistogram(buf *bytes.Buffer, name, help string, h map[string]*histogram) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	var keys []string
+	for l := range h {
+		keys = append(keys, l)
+	}
+	sort.Strings(keys)
+	for _, l := range keys {
+		for i, b := range h[l].buckets {
+			fmt.Fprintf(buf, "%s_bucket{%s,le=\"%s\"} %d\n", name, l, formatValue(b), h[l].counts[i])
+		}
+		fmt.Fprintf(buf, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, l, h[l].count)
+		fmt.Fprintf(buf, "%s_sum{%s} %s\n", name, l, formatValue(h[l].sum))
+		fmt.Fprintf(buf, "%s_count{%s} %d\n", name, l, h[l].count)
+	}
+}
+
+// labels returns the escaped label pairs, kv is name, value, ...
+func labels(kv ...string) string {
+	var pairs []string
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	for i := 0; i+1 < len(kv); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, kv[i], r.Replace(kv[i+1])))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func sortedKeys(m map[string]float64) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatValue(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,36 @@
+package httpd_test
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mehrdadrad/mylg/services/httpd"
+)
+
+func TestMetrics(t *testing.T) {
+	m := httpd.NewMetrics()
+	m.ObserveRequest("cogent", "US - Los Angeles", "ping", 300*time.Millisecond, "")
+	m.ObserveRequest("cogent", "US - Los Angeles", "ping", 2*time.Second, "timeout")
+	m.ObservePing("cogent", `JP "Tokyo"`, 20*time.Millisecond)
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	b, _ := ioutil.ReadAll(w.Body)
+
+	for _, line := range []string{
+		`mylg_lg_queries_total{provider="cogent",node="US - Los Angeles",cmd="ping"} 2`,
+		`mylg_lg_errors_total{provider="cogent",node="US - Los Angeles",cmd="ping",type="timeout"} 1`,
+		`mylg_lg_request_duration_seconds_bucket{provider="cogent",node="US - Los Angeles",cmd="ping",le="0.5"} 1`,
+		`mylg_lg_request_duration_seconds_bucket{provider="cogent",node="US - Los Angeles",cmd="ping",le="+Inf"} 2`,
+		`mylg_lg_request_duration_seconds_sum{provider="cogent",node="US - Los Angeles",cmd="ping"} 2.3`,
+		`mylg_lg_ping_rtt_seconds_bucket{provider="cogent",node="JP \"Tokyo\"",le="0.025"} 1`,
+		`mylg_lg_ping_rtt_seconds_count{provider="cogent",node="JP \"Tokyo\""} 1`,
+	} {
+		if !strings.Contains(string(b), line+"\n") {
+			t.Errorf("expected %s in\n%s", line, b)
+		}
+	}
+}
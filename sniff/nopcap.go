@@ -0,0 +1,9 @@
+//go:build !cgo || nopcap
+// +build !cgo nopcap
+
+package sniff
+
+// openLive isn't available w/o libpcap
+func openLive(device, filter string) (packetSource, func(), error) {
+	return nil, nil, ErrNoPcap
+}
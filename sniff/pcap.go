@@ -0,0 +1,25 @@
+//go:build cgo && !nopcap
+// +build cgo,!nopcap
+
+package sniff
+
+import (
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/pcap"
+)
+
+// snapLen is the max captured bytes of each packet
+const snapLen = 6 * 1024
+
+// openLive opens the device through libpcap and applies the filter
+func openLive(device, filter string) (packetSource, func(), error) {
+	handle, err := pcap.OpenLive(device, snapLen, false, pcap.BlockForever)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := handle.SetBPFFilter(filter); err != nil {
+		handle.Close()
+		return nil, nil, err
+	}
+	return gopacket.NewPacketSource(handle, handle.LinkType()), handle.Close, nil
+}
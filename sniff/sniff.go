@@ -0,0 +1,168 @@
+// Package sniff captures the live packets and prints a one line summary
+// per packet or the per conversation packets and bytes counters
+package sniff
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/google/gopacket"
+
+	"github.com/mehrdadrad/mylg/cli"
+)
+
+// Sniffer represents a packet capture request
+type Sniffer struct {
+	// Device is the capture interface, the first
+	// non-loopback interface is used if it's empty
+	Device string
+	// Filter is the BPF filter expression
+	Filter string
+	// Count stops the capture after count packets, zero is unlimited
+	Count int
+	// Duration stops the capture after the duration, zero is unlimited
+	Duration time.Duration
+	// Conversations prints the per conversation counters
+	// instead of the packets
+	Conversations bool
+}
+
+// packetSource returns the captured packets
+type packetSource interface {
+	Packets() chan gopacket.Packet
+}
+
+// ErrNoPcap is returned once mylg is built w/o libpcap
+var ErrNoPcap = errors.New("packet capture isn't available: mylg is built w/o libpcap (cgo)")
+
+// New parses the sniff arguments, nil is returned if the help is requested
+func New(args string) (*Sniffer, error) {
+	filter, flag := cli.Flag(args)
+	if _, ok := flag["help"]; ok {
+		help()
+		return nil, nil
+	}
+	d, err := parseDuration(cli.SetFlag(flag, "t", "0s").(string))
+	if err != nil {
+		return nil, err
+	}
+	s := &Sniffer{
+		Device:        cli.SetFlag(flag, "i", "").(string),
+		Filter:        filter,
+		Count:         cli.SetFlag(flag, "c", 0).(int),
+		Duration:      d,
+		Conversations: cli.SetFlag(flag, "conv", false).(bool),
+	}
+	if s.Count < 0 {
+		return nil, fmt.Errorf("invalid count %d", s.Count)
+	}
+	if s.Device == "" {
+		s.Device = defaultDevice()
+	}
+	return s, nil
+}
+
+// Banner returns the capture info
+func (s *Sniffer) Banner() string {
+	return fmt.Sprintf("Interface: %s, filter: %q", s.Device, s.Filter)
+}
+
+// Run captures the packets until the count or the duration is reached
+// or it's interrupted, the summaries (or the conversations at the end)
+// are printed to the stdout
+func (s *Sniffer) Run() error {
+	src, closeSrc, err := openLive(s.Device, s.Filter)
+	if err != nil {
+		return err
+	}
+	defer closeSrc()
+
+	var (
+		sig     = make(chan os.Signal, 1)
+		timeout <-chan time.Time
+		conv    = NewConversations()
+		packets = src.Packets()
+		count   int
+	)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+	if s.Duration > 0 {
+		timeout = time.After(s.Duration)
+	}
+
+LOOP:
+	for s.Count == 0 || count < s.Count {
+		select {
+		case p, ok := <-packets:
+			if !ok {
+				break LOOP
+			}
+			count++
+			sum := Summarize(p)
+			if s.Conversations {
+				conv.Add(sum)
+				continue
+			}
+			fmt.Println(sum)
+		case <-timeout:
+			break LOOP
+		case <-sig:
+			break LOOP
+		}
+	}
+
+	if s.Conversations {
+		conv.Print(os.Stdout)
+	}
+	fmt.Printf("%d packets captured\n", count)
+	return nil
+}
+
+// parseDuration parses the duration e.g. 1m30s, the
+// number w/o unit is in seconds
+func parseDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		n, nErr := strconv.Atoi(s)
+		if nErr != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d = time.Duration(n) * time.Second
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// defaultDevice returns the first up and non-loopback interface
+func defaultDevice() string {
+	ifs, _ := net.Interfaces()
+	for _, i := range ifs {
+		if i.Flags&net.FlagUp != 0 && i.Flags&net.FlagLoopback == 0 {
+			return i.Name
+		}
+	}
+	return ""
+}
+
+func help() {
+	fmt.Println(`
+    usage:
+          sniff [filter expression] [options]
+          * The expression consists of one or more primitives (Berkeley Packet Filter (BPF) syntax)
+    options:
+          -i interface   Listen on specified interface (default: first non-loopback)
+          -c count       Stop after receiving count packets
+          -t duration    Stop after the duration e.g. 30s
+          -conv          Print the per conversation packets and bytes instead of the packets
+    Example:
+          sniff tcp and port 443 -c 100
+          sniff -i eth0 -t 10s -conv
+  `)
+}
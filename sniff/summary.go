@@ -0,0 +1,169 @@
+package sniff
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Summary represents the one line view of a packet
+type Summary struct {
+	Time   time.Time
+	Src    string
+	Dst    string
+	Proto  string
+	Length int
+	Flags  string
+}
+
+// Summarize returns the packet summary, the src and dst have the
+// ports for TCP and UDP and the mac addresses w/o the ip layer
+func Summarize(p gopacket.Packet) Summary {
+	s := Summary{
+		Time:   p.Metadata().Timestamp,
+		Length: p.Metadata().Length,
+		Proto:  "unknown",
+	}
+	if s.Length == 0 {
+		s.Length = len(p.Data())
+	}
+
+	var srcIP, dstIP net.IP
+	if l, ok := p.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); ok {
+		s.Src, s.Dst, s.Proto = l.SrcMAC.String(), l.DstMAC.String(), l.EthernetType.String()
+	}
+	if l, ok := p.Layer(layers.LayerTypeARP).(*layers.ARP); ok {
+		s.Src, s.Dst, s.Proto = net.IP(l.SourceProtAddress).String(), net.IP(l.DstProtAddress).String(), "ARP"
+		s.Flags = "request"
+		if l.Operation == layers.ARPReply {
+			s.Flags = "reply"
+		}
+	}
+	if l, ok := p.Layer(layers.LayerTypeIPv4).(*layers.IPv4); ok {
+		srcIP, dstIP, s.Proto = l.SrcIP, l.DstIP, l.Protocol.String()
+	}
+	if l, ok := p.Layer(layers.LayerTypeIPv6).(*layers.IPv6); ok {
+		srcIP, dstIP, s.Proto = l.SrcIP, l.DstIP, l.NextHeader.String()
+	}
+	if srcIP != nil {
+		s.Src, s.Dst = srcIP.String(), dstIP.String()
+	}
+
+	switch {
+	case p.Layer(layers.LayerTypeTCP) != nil:
+		l := p.Layer(layers.LayerTypeTCP).(*layers.TCP)
+		s.Src = hostPort(srcIP, s.Src, int(l.SrcPort))
+		s.Dst = hostPort(dstIP, s.Dst, int(l.DstPort))
+		s.Proto, s.Flags = "TCP", tcpFlags(l)
+	case p.Layer(layers.LayerTypeUDP) != nil:
+		l := p.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		s.Src = hostPort(srcIP, s.Src, int(l.SrcPort))
+		s.Dst = hostPort(dstIP, s.Dst, int(l.DstPort))
+		s.Proto = "UDP"
+	case p.Layer(layers.LayerTypeICMPv4) != nil:
+		s.Proto, s.Flags = "ICMP", p.Layer(layers.LayerTypeICMPv4).(*layers.ICMPv4).TypeCode.String()
+	case p.Layer(layers.LayerTypeICMPv6) != nil:
+		s.Proto, s.Flags = "ICMPv6", p.Layer(layers.LayerTypeICMPv6).(*layers.ICMPv6).TypeCode.String()
+	}
+	return s
+}
+
+// String returns the summary in one line
+func (s Summary) String() string {
+	l := fmt.Sprintf("%s %s > %s %s %d", s.Time.Format("15:04:05.000000"), s.Src, s.Dst, s.Proto, s.Length)
+	if s.Flags != "" {
+		l += " [" + s.Flags + "]"
+	}
+	return l
+}
+
+func hostPort(ip net.IP, host string, port int) string {
+	if ip == nil {
+		return host
+	}
+	return net.JoinHostPort(ip.String(), strconv.Itoa(port))
+}
+
+// tcpFlags returns the set tcp flags e.g. SYN,ACK
+func tcpFlags(l *layers.TCP) string {
+	var flags []string
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{
+		{l.SYN, "SYN"}, {l.FIN, "FIN"}, {l.RST, "RST"}, {l.PSH, "PSH"},
+		{l.ACK, "ACK"}, {l.URG, "URG"}, {l.ECE, "ECE"}, {l.CWR, "CWR"},
+	} {
+		if f.set {
+			flags = append(flags, f.name)
+		}
+	}
+	return strings.Join(flags, ",")
+}
+
+// Conversation represents the packets and bytes counters between
+// two endpoints in both directions
+type Conversation struct {
+	A       string
+	B       string
+	Proto   string
+	Packets int
+	Bytes   int
+}
+
+// Conversations aggregates the packets by conversation
+type Conversations struct {
+	m map[[3]string]*Conversation
+}
+
+// NewConversations returns an empty conversations table
+func NewConversations() *Conversations {
+	return &Conversations{m: make(map[[3]string]*Conversation)}
+}
+
+// Add counts the packet, A is the lower endpoint so
+// the both directions are in the same conversation
+func (c *Conversations) Add(s Summary) {
+	a, b := s.Src, s.Dst
+	if b < a {
+		a, b = b, a
+	}
+	key := [3]string{a, b, s.Proto}
+	conv, ok := c.m[key]
+	if !ok {
+		conv = &Conversation{A: a, B: b, Proto: s.Proto}
+		c.m[key] = conv
+	}
+	conv.Packets++
+	conv.Bytes += s.Length
+}
+
+// List returns the conversations sorted by the bytes
+func (c *Conversations) List() []Conversation {
+	var list []Conversation
+	for _, conv := range c.m {
+		list = append(list, *conv)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Bytes != list[j].Bytes {
+			return list[i].Bytes > list[j].Bytes
+		}
+		return list[i].A+list[i].B < list[j].A+list[j].B
+	})
+	return list
+}
+
+// Print writes the conversations table to w
+func (c *Conversations) Print(w io.Writer) {
+	fmt.Fprintf(w, "%-45s %-45s %-8s %8s %10s\n", "A", "B", "Proto", "Packets", "Bytes")
+	for _, conv := range c.List() {
+		fmt.Fprintf(w, "%-45s %-45s %-8s %8d %10d\n", conv.A, conv.B, conv.Proto, conv.Packets, conv.Bytes)
+	}
+}
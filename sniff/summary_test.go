@@ -0,0 +1,55 @@
+package sniff_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/mehrdadrad/mylg/sniff"
+)
+
+func tcpPacket(t *testing.T, src, dst string, sport, dport int, payload int) gopacket.Packet {
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0, 1, 2, 3, 4, 5},
+		DstMAC:       net.HardwareAddr{0, 1, 2, 3, 4, 6},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolTCP, SrcIP: net.ParseIP(src), DstIP: net.ParseIP(dst)}
+	tcp := &layers.TCP{SrcPort: layers.TCPPort(sport), DstPort: layers.TCPPort(dport), SYN: true, ACK: true}
+	tcp.SetNetworkLayerForChecksum(ip)
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, eth, ip, tcp, gopacket.Payload(make([]byte, payload))); err != nil {
+		t.Fatal(err)
+	}
+	p := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+	p.Metadata().Timestamp = time.Date(2020, 1, 1, 10, 20, 30, 0, time.UTC)
+	p.Metadata().Length = len(buf.Bytes())
+	return p
+}
+
+func TestSummarize(t *testing.T) {
+	s := sniff.Summarize(tcpPacket(t, "192.0.2.1", "192.0.2.2", 443, 50000, 10))
+	want := "10:20:30.000000 192.0.2.1:443 > 192.0.2.2:50000 TCP 64 [SYN,ACK]"
+	if s.String() != want {
+		t.Errorf("expected %q but got %q", want, s)
+	}
+}
+
+func TestConversations(t *testing.T) {
+	c := sniff.NewConversations()
+	c.Add(sniff.Summarize(tcpPacket(t, "192.0.2.1", "192.0.2.2", 443, 50000, 10)))
+	c.Add(sniff.Summarize(tcpPacket(t, "192.0.2.2", "192.0.2.1", 50000, 443, 100)))
+	c.Add(sniff.Summarize(tcpPacket(t, "192.0.2.3", "192.0.2.1", 22, 40000, 0)))
+
+	list := c.List()
+	if len(list) != 2 {
+		t.Fatal("expected two conversations but got", list)
+	}
+	if list[0].A != "192.0.2.1:443" || list[0].B != "192.0.2.2:50000" || list[0].Packets != 2 || list[0].Bytes != 218 {
+		t.Errorf("unexpected conversation %+v", list[0])
+	}
+}
@@ -0,0 +1,37 @@
+// Package stats provides the rtt statistics which are shared by
+// the local probes and the looking glasses
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Percentile returns the nearest-rank percentile (0-100) of the rtts
+func Percentile(rtts []float64, p float64) float64 {
+	if len(rtts) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), rtts...)
+	sort.Float64s(sorted)
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// Jitter returns the mean deviation of the consecutive rtts
+func Jitter(rtts []float64) float64 {
+	var sum float64
+	if len(rtts) < 2 {
+		return 0
+	}
+	for i := 1; i < len(rtts); i++ {
+		sum += math.Abs(rtts[i] - rtts[i-1])
+	}
+	return sum / float64(len(rtts)-1)
+}
@@ -0,0 +1,31 @@
+package stats_test
+
+import (
+	"testing"
+
+	"github.com/mehrdadrad/mylg/stats"
+)
+
+func TestPercentile(t *testing.T) {
+	rtts := []float64{7, 1, 10, 3, 5, 2, 9, 4, 8, 6}
+	for p, want := range map[float64]float64{50: 5, 90: 9, 99: 10, 0: 1} {
+		if got := stats.Percentile(rtts, p); got != want {
+			t.Errorf("expected p%v %v but got %v", p, want, got)
+		}
+	}
+	if rtts[0] != 7 {
+		t.Error("expected the rtts not to be sorted in place")
+	}
+	if p := stats.Percentile(nil, 50); p != 0 {
+		t.Error("expected 0 w/o rtts but got", p)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	if j := stats.Jitter([]float64{2, 4, 4, 4, 5, 5, 7, 9}); j != 1 {
+		t.Error("expected jitter 1 but got", j)
+	}
+	if j := stats.Jitter([]float64{2}); j != 0 {
+		t.Error("expected jitter 0 w/ one rtt but got", j)
+	}
+}
@@ -0,0 +1,252 @@
+package whois
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mehrdadrad/mylg/ripe"
+)
+
+// ErrRateLimited is returned if the whois server refuses the
+// query because of the rate limit
+var ErrRateLimited = errors.New("whois: the server rate limit exceeded, please try again later")
+
+// A Client represents a whois client
+type Client struct {
+	// Server is the first server to query, it's selected
+	// based on the target if it's empty
+	Server string
+	// Timeout is each server query timeout (default 10s)
+	Timeout time.Duration
+	// MaxReferrals is the max followed referrals (default 3)
+	MaxReferrals int
+	// CacheDir keeps the responses, the caching is disabled if it's empty
+	CacheDir string
+	// CacheTTL is the cached response lifetime (default 24h)
+	CacheTTL time.Duration
+}
+
+// WhoisRecord represents the common whois fields
+type WhoisRecord struct {
+	Org       string `json:"org,omitempty"`
+	NetRange  string `json:"netrange,omitempty"`
+	ASN       string `json:"asn,omitempty"`
+	Registrar string `json:"registrar,omitempty"`
+}
+
+var (
+	referRe     = regexp.MustCompile(`(?im)^\s*(?:refer|whois|ReferralServer|Registrar WHOIS Server):\s*(?:r?whois://)?(\S+)\s*$`)
+	rateLimitRe = regexp.MustCompile(`(?i)(limit exceeded|query rate|too many (queries|requests)|access denied)`)
+
+	recordKeys = map[string][]string{
+		"Org":       {"OrgName", "org-name", "Organization", "organisation", "Registrant Organization", "owner"},
+		"NetRange":  {"NetRange", "inetnum", "inet6num", "CIDR"},
+		"ASN":       {"OriginAS", "origin", "aut-num", "ASNumber"},
+		"Registrar": {"Registrar", "registrar"},
+	}
+)
+
+// defaultClient caches the responses at the user cache dir
+var defaultClient = &Client{CacheDir: defaultCacheDir()}
+
+// Query returns the whois response of the IP address, ASN or domain
+func Query(target string) (string, error) {
+	return defaultClient.Query(target)
+}
+
+// Query returns the whois response, it follows the referrals
+// and returns the last server response
+func (c *Client) Query(target string) (string, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return "", errors.New("whois: empty target")
+	}
+	if r, ok := c.readCache(target); ok {
+		return r, nil
+	}
+	var (
+		server = c.Server
+		resp   string
+		seen   = map[string]bool{}
+	)
+	if server == "" {
+		server = selectServer(target)
+	}
+	for i := 0; i <= c.maxReferrals(); i++ {
+		r, err := c.query(server, target)
+		if err != nil {
+			if resp != "" {
+				// the referral is broken, keep the last response
+				break
+			}
+			return "", err
+		}
+		if rateLimitRe.MatchString(r) && len(r) < 1024 {
+			return "", ErrRateLimited
+		}
+		resp = r
+		seen[server] = true
+		next := referral(r)
+		if next == "" || seen[next] {
+			break
+		}
+		server = next
+	}
+	c.writeCache(target, resp)
+	return resp, nil
+}
+
+// query sends the target to the whois server
+func (c *Client) query(server, target string) (string, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "43")
+	}
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	conn, err := net.DialTimeout("tcp", server, timeout)
+	if err != nil {
+		return "", fmt.Errorf("whois: %s", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := fmt.Fprintf(conn, "%s\r\n", queryString(server, target)); err != nil {
+		return "", fmt.Errorf("whois: %s", err)
+	}
+	b, err := ioutil.ReadAll(conn)
+	if err != nil && len(b) == 0 {
+		return "", fmt.Errorf("whois: %s", err)
+	}
+	return string(b), nil
+}
+
+// queryString returns the server specific query
+func queryString(server, target string) string {
+	if strings.HasPrefix(server, "whois.arin.net") {
+		if ripe.IsASN(target) {
+			return "a " + target
+		}
+		return "n + " + target
+	}
+	return target
+}
+
+// selectServer returns the first whois server, the RIRs refer to
+// each other for the IPs and ASNs, IANA refers to the TLD registries
+func selectServer(target string) string {
+	if ripe.IsASN(target) || ripe.IsIP(target) || ripe.IsPrefix(target) {
+		return "whois.arin.net"
+	}
+	return "whois.iana.org"
+}
+
+// referral returns the referred whois server
+func referral(r string) string {
+	m := referRe.FindAllStringSubmatch(r, -1)
+	for i := len(m) - 1; i >= 0; i-- {
+		if s := strings.TrimSuffix(m[i][1], "/"); s != "" {
+			return strings.ToLower(s)
+		}
+	}
+	return ""
+}
+
+// Parse returns the common fields of the whois response
+func Parse(r string) WhoisRecord {
+	var (
+		rec    WhoisRecord
+		fields = map[string]*string{
+			"Org":       &rec.Org,
+			"NetRange":  &rec.NetRange,
+			"ASN":       &rec.ASN,
+			"Registrar": &rec.Registrar,
+		}
+	)
+	scanner := bufio.NewScanner(strings.NewReader(r))
+	for scanner.Scan() {
+		l := strings.TrimSpace(scanner.Text())
+		i := strings.Index(l, ":")
+		if i < 1 || strings.HasPrefix(l, "%") || strings.HasPrefix(l, "#") {
+			continue
+		}
+		key, value := strings.TrimSpace(l[:i]), strings.TrimSpace(l[i+1:])
+		if value == "" {
+			continue
+		}
+		for field, keys := range recordKeys {
+			if *fields[field] != "" {
+				continue
+			}
+			for _, k := range keys {
+				if strings.EqualFold(k, key) {
+					*fields[field] = value
+				}
+			}
+		}
+	}
+	return rec
+}
+
+// maxReferrals returns the max followed referrals
+func (c *Client) maxReferrals() int {
+	if c.MaxReferrals > 0 {
+		return c.MaxReferrals
+	}
+	return 3
+}
+
+// cacheFile returns the target cache file
+func (c *Client) cacheFile(target string) string {
+	return filepath.Join(c.CacheDir, fmt.Sprintf("%x.txt", sha1.Sum([]byte(strings.ToLower(target)))))
+}
+
+// readCache returns the cached response if it's not expired
+func (c *Client) readCache(target string) (string, bool) {
+	if c.CacheDir == "" {
+		return "", false
+	}
+	ttl := c.CacheTTL
+	if ttl == 0 {
+		ttl = 24 * time.Hour
+	}
+	file := c.cacheFile(target)
+	info, err := os.Stat(file)
+	if err != nil || time.Since(info.ModTime()) > ttl {
+		return "", false
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// writeCache keeps the response on disk
+func (c *Client) writeCache(target, r string) {
+	if c.CacheDir == "" || r == "" {
+		return
+	}
+	if err := os.MkdirAll(c.CacheDir, 0700); err != nil {
+		return
+	}
+	ioutil.WriteFile(c.cacheFile(target), []byte(r), 0600)
+}
+
+// defaultCacheDir returns the whois cache dir
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "mylg", "whois")
+}
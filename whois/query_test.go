@@ -0,0 +1,92 @@
+package whois_test
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/whois"
+)
+
+// whoisServer returns a fake whois server address
+func whoisServer(t *testing.T, resp func(q string) string) (string, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			q, _ := bufio.NewReader(conn).ReadString('\n')
+			fmt.Fprint(conn, resp(strings.TrimSpace(q)))
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String(), func() { ln.Close() }
+}
+
+func TestQueryReferral(t *testing.T) {
+	var hits int
+	registrar, closeRegistrar := whoisServer(t, func(q string) string {
+		hits++
+		return "Domain Name: EXAMPLE.COM\nRegistrar: Example Registrar, Inc.\nRegistrant Organization: Example Org\n"
+	})
+	defer closeRegistrar()
+	registry, closeRegistry := whoisServer(t, func(q string) string {
+		hits++
+		return "Domain Name: EXAMPLE.COM\nRegistrar WHOIS Server: " + registrar + "\n"
+	})
+	defer closeRegistry()
+
+	dir, _ := ioutil.TempDir("", "mylg")
+	defer os.RemoveAll(dir)
+
+	c := &whois.Client{Server: registry, CacheDir: dir}
+	r, err := c.Query("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := whois.Parse(r)
+	if rec.Registrar != "Example Registrar, Inc." || rec.Org != "Example Org" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+	// cached
+	if _, err := c.Query("example.com"); err != nil || hits != 2 {
+		t.Error("expected the cached response but hits", hits, err)
+	}
+}
+
+func TestQueryRateLimited(t *testing.T) {
+	addr, closeServer := whoisServer(t, func(q string) string {
+		return "%ERROR:201: access denied\n% Query rate limit exceeded\n"
+	})
+	defer closeServer()
+
+	c := &whois.Client{Server: addr}
+	if _, err := c.Query("192.0.2.1"); err != whois.ErrRateLimited {
+		t.Error("expected ErrRateLimited but got", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	r := `% RIPE whois
+inetnum:        193.0.0.0 - 193.0.23.255
+org-name:       Reseaux IP Europeens Network Coordination Centre
+origin:         AS3333
+`
+	want := whois.WhoisRecord{
+		Org:      "Reseaux IP Europeens Network Coordination Centre",
+		NetRange: "193.0.0.0 - 193.0.23.255",
+		ASN:      "AS3333",
+	}
+	if rec := whois.Parse(r); rec != want {
+		t.Errorf("expected %+v but got %+v", want, rec)
+	}
+}
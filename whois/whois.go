@@ -1,8 +1,10 @@
 // Package whois tries to get information about
-// IP address / Prefix / Domain (todo)
+// IP address / Prefix / Domain
 package whois
 
 import (
+	"strings"
+
 	"github.com/mehrdadrad/mylg/ripe"
 )
 
@@ -28,20 +30,34 @@ func Lookup(args string) {
 		w["prefix"].Set(args)
 		w["prefix"].GetData()
 		w["prefix"].PrettyPrint()
+	} else if isDomain(args) {
+		r, err := Query(args)
+		if err != nil {
+			println(err.Error())
+			return
+		}
+		println(r)
 	} else {
 		help()
 	}
 }
 
+// isDomain returns true if the args looks like a domain name
+func isDomain(args string) bool {
+	return strings.Contains(args, ".") && !strings.ContainsAny(args, " /")
+}
+
 // help represents whois help
 func help() {
 	println(`
     usage:
-          whois ASN/CIDR/IPAddress
+          whois ASN/CIDR/IPAddress/Domain [-json]
 
     Example:
           whois 8.8.8.8
           whois 8.0.0.0/8
           whois 577
+          whois google.com
+          whois 8.8.8.0/24 -json
 	`)
 }